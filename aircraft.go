@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadTypeAliases reads a CSV file in which each record maps an aircraft type
+// code to the canonical name to use in its place, for example:
+//
+//	B38M,B737 MAX
+//	B39M,B737 MAX
+func loadTypeAliases(path string) (map[string]string, error) {
+	return readTypeTable(path, "type aliases")
+}
+
+// loadTypeNames reads a CSV file in which each record maps an aircraft type
+// code to its full name, for example:
+//
+//	B738,Boeing 737-800
+//	A21N,Airbus A321neo
+func loadTypeNames(path string) (map[string]string, error) {
+	return readTypeTable(path, "type names")
+}
+
+// readTypeTable reads a two column CSV file keyed by aircraft type code.
+func readTypeTable(path, what string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", what, err)
+	}
+
+	table := make(map[string]string, len(records))
+	for _, record := range records {
+		table[strings.ToUpper(record[0])] = record[1]
+	}
+	return table, nil
+}
+
+// normalizeAircraftType returns the canonical name for an aircraft type code,
+// or the code itself if it has no alias.
+func (a *App) normalizeAircraftType(code string) string {
+	if alias, ok := a.TypeAliases[strings.ToUpper(code)]; ok {
+		return alias
+	}
+	return code
+}
+
+// aircraftTypeName returns the full name of an aircraft type code, preferring
+// any loaded from the type names file over the built-in table, or the code
+// itself, as reported, if it is unknown.
+func (a *App) aircraftTypeName(code string) string {
+	key := strings.ToUpper(code)
+	if name, ok := a.TypeNames[key]; ok {
+		return name
+	}
+	if name, ok := aircraftTypeNames[key]; ok {
+		return name
+	}
+	return code
+}
+
+// aircraftTypeNames are the full names of common aircraft types.
+var aircraftTypeNames = map[string]string{
+	"A19N": "Airbus A319neo",
+	"A20N": "Airbus A320neo",
+	"A21N": "Airbus A321neo",
+	"A318": "Airbus A318",
+	"A319": "Airbus A319",
+	"A320": "Airbus A320",
+	"A321": "Airbus A321",
+	"A332": "Airbus A330-200",
+	"A333": "Airbus A330-300",
+	"A339": "Airbus A330-900",
+	"A343": "Airbus A340-300",
+	"A359": "Airbus A350-900",
+	"A35K": "Airbus A350-1000",
+	"A388": "Airbus A380-800",
+	"AT75": "ATR 72-500",
+	"AT76": "ATR 72-600",
+	"B712": "Boeing 717-200",
+	"B737": "Boeing 737-700",
+	"B738": "Boeing 737-800",
+	"B739": "Boeing 737-900",
+	"B38M": "Boeing 737 MAX 8",
+	"B39M": "Boeing 737 MAX 9",
+	"B744": "Boeing 747-400",
+	"B748": "Boeing 747-8",
+	"B752": "Boeing 757-200",
+	"B753": "Boeing 757-300",
+	"B763": "Boeing 767-300",
+	"B764": "Boeing 767-400",
+	"B772": "Boeing 777-200",
+	"B77L": "Boeing 777-200LR",
+	"B77W": "Boeing 777-300ER",
+	"B788": "Boeing 787-8",
+	"B789": "Boeing 787-9",
+	"B78X": "Boeing 787-10",
+	"BCS1": "Airbus A220-100",
+	"BCS3": "Airbus A220-300",
+	"BE20": "Beechcraft King Air 200",
+	"BE36": "Beechcraft Bonanza",
+	"C172": "Cessna 172 Skyhawk",
+	"C182": "Cessna 182 Skylane",
+	"C208": "Cessna 208 Caravan",
+	"C68A": "Cessna Citation Latitude",
+	"CL30": "Bombardier Challenger 300",
+	"CL35": "Bombardier Challenger 350",
+	"CRJ2": "Bombardier CRJ200",
+	"CRJ7": "Bombardier CRJ700",
+	"CRJ9": "Bombardier CRJ900",
+	"DH8D": "De Havilland Dash 8-400",
+	"E135": "Embraer ERJ 135",
+	"E145": "Embraer ERJ 145",
+	"E170": "Embraer 170",
+	"E75L": "Embraer 175",
+	"E75S": "Embraer 175",
+	"E190": "Embraer 190",
+	"E195": "Embraer 195",
+	"E290": "Embraer E190-E2",
+	"GLF4": "Gulfstream IV",
+	"GLF5": "Gulfstream V",
+	"GLF6": "Gulfstream G650",
+	"MD11": "McDonnell Douglas MD-11",
+	"P28A": "Piper Cherokee",
+	"PC12": "Pilatus PC-12",
+	"SR22": "Cirrus SR22",
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTypeAliases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.csv")
+	data := "# 737 MAX family\nB37M,B737 MAX\nB38M, B737 MAX\nb39m,B737 MAX\nA20N,A320neo\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	aliases, err := loadTypeAliases(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &App{TypeAliases: aliases}
+
+	tests := []struct {
+		code string
+		exp  string
+	}{
+		{"B38M", "B737 MAX"},
+		{"B39M", "B737 MAX"},
+		{"a20n", "A320neo"},
+		{"B738", "B738"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			if actual := app.normalizeAircraftType(test.code); actual != test.exp {
+				t.Errorf("unexpected type: %s", actual)
+			}
+		})
+	}
+}
+
+func TestTypeAliasesMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.csv")
+	if err := os.WriteFile(path, []byte("B38M,B737 MAX,extra\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTypeAliases(path); err == nil {
+		t.Errorf("expected an error for a malformed record")
+	}
+}
+
+func TestAircraftTypeName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.csv")
+	if err := os.WriteFile(path, []byte("B738,Boeing 737 Next Generation\nZZZZ,Mystery Machine\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	names, err := loadTypeNames(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &App{TypeNames: names}
+
+	tests := []struct {
+		code string
+		exp  string
+	}{
+		{"A21N", "Airbus A321neo"},
+		{"a21n", "Airbus A321neo"},
+		{"B738", "Boeing 737 Next Generation"},
+		{"ZZZZ", "Mystery Machine"},
+		{"XX99", "XX99"},
+		{"xx99", "xx99"},
+	}
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			if actual := app.aircraftTypeName(test.code); actual != test.exp {
+				t.Errorf("unexpected name: %s", actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var airportCodeRegex = regexp.MustCompile("^[A-Z0-9]{3,4}$")
+
+// loadAirports reads the airport file, if one is configured. Each line holds
+// an ICAO or IATA airport code and the airport's name separated by a comma,
+// e.g.:
+//
+//	KBED,Hanscom Field
+//	BED,Hanscom Field
+//
+// Blank lines and lines starting with # are ignored. Malformed lines are logged
+// and skipped rather than failing the whole file.
+func (a *App) loadAirports() error {
+	if a.AirportFile == "" {
+		return nil
+	}
+	f, err := os.Open(a.AirportFile)
+	if err != nil {
+		return fmt.Errorf("could not open airport file: %w", err)
+	}
+	defer f.Close()
+
+	airports := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		code, name, _ := strings.Cut(line, ",")
+		code, name = strings.ToUpper(strings.TrimSpace(code)), strings.TrimSpace(name)
+		if !airportCodeRegex.MatchString(code) || name == "" {
+			slog.Warn("skipping malformed airport entry", "file", a.AirportFile, "line", n, "entry", line)
+			continue
+		}
+		airports[code] = name
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read airport file: %w", err)
+	}
+	a.airports = airports
+	return nil
+}
+
+// airportName returns the name of the airport with the given ICAO or IATA
+// code, preferring any loaded from the airport file over the built-in table,
+// or the code itself if it is unknown.
+func (a *App) airportName(code string) string {
+	key := strings.ToUpper(code)
+	if name, ok := a.airports[key]; ok {
+		return name
+	}
+	if name, ok := airportNames[key]; ok {
+		return name
+	}
+	return code
+}
+
+// airportNames are the names of major airports, by both ICAO and IATA code.
+var airportNames = map[string]string{}
+
+func init() {
+	for _, airport := range []struct{ icao, iata, name string }{
+		{"CYUL", "YUL", "Montreal Trudeau"},
+		{"CYVR", "YVR", "Vancouver"},
+		{"CYYZ", "YYZ", "Toronto Pearson"},
+		{"EDDF", "FRA", "Frankfurt"},
+		{"EDDM", "MUC", "Munich"},
+		{"EGKK", "LGW", "London Gatwick"},
+		{"EGLL", "LHR", "London Heathrow"},
+		{"EHAM", "AMS", "Amsterdam Schiphol"},
+		{"EIDW", "DUB", "Dublin"},
+		{"KATL", "ATL", "Atlanta Hartsfield-Jackson"},
+		{"KAUS", "AUS", "Austin-Bergstrom"},
+		{"KBOS", "BOS", "Boston Logan"},
+		{"KBWI", "BWI", "Baltimore/Washington"},
+		{"KCLT", "CLT", "Charlotte Douglas"},
+		{"KDCA", "DCA", "Washington National"},
+		{"KDEN", "DEN", "Denver"},
+		{"KDFW", "DFW", "Dallas/Fort Worth"},
+		{"KDTW", "DTW", "Detroit Metro"},
+		{"KEWR", "EWR", "Newark Liberty"},
+		{"KFLL", "FLL", "Fort Lauderdale"},
+		{"KIAD", "IAD", "Washington Dulles"},
+		{"KIAH", "IAH", "Houston Intercontinental"},
+		{"KJFK", "JFK", "New York JFK"},
+		{"KLAS", "LAS", "Las Vegas Harry Reid"},
+		{"KLAX", "LAX", "Los Angeles"},
+		{"KLGA", "LGA", "New York LaGuardia"},
+		{"KMCO", "MCO", "Orlando"},
+		{"KMIA", "MIA", "Miami"},
+		{"KMSP", "MSP", "Minneapolis-Saint Paul"},
+		{"KORD", "ORD", "Chicago O'Hare"},
+		{"KPHL", "PHL", "Philadelphia"},
+		{"KPHX", "PHX", "Phoenix Sky Harbor"},
+		{"KPVD", "PVD", "Providence"},
+		{"KSAN", "SAN", "San Diego"},
+		{"KSEA", "SEA", "Seattle-Tacoma"},
+		{"KSFO", "SFO", "San Francisco"},
+		{"KSLC", "SLC", "Salt Lake City"},
+		{"LEMD", "MAD", "Madrid Barajas"},
+		{"LFPG", "CDG", "Paris Charles de Gaulle"},
+		{"LIRF", "FCO", "Rome Fiumicino"},
+		{"LSZH", "ZRH", "Zurich"},
+		{"OMDB", "DXB", "Dubai"},
+		{"PANC", "ANC", "Anchorage"},
+		{"PHNL", "HNL", "Honolulu"},
+		{"RJTT", "HND", "Tokyo Haneda"},
+		{"VHHH", "HKG", "Hong Kong"},
+		{"WSSS", "SIN", "Singapore Changi"},
+		{"YSSY", "SYD", "Sydney"},
+	} {
+		airportNames[airport.icao] = airport.name
+		airportNames[airport.iata] = airport.name
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAirportName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "airports.csv")
+	data := `# local fields
+KBED, Hanscom Field
+bed,Hanscom Field
+KBOS,Boston
+KB,too short
+KOWD,
+nocomma
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	app := &App{AirportFile: path}
+	if err := app.loadAirports(); err != nil {
+		t.Fatal(err)
+	}
+	if len(app.airports) != 3 {
+		t.Errorf("expected malformed entries to be skipped, got %v", app.airports)
+	}
+
+	tests := []struct {
+		code string
+		exp  string
+	}{
+		{"KBED", "Hanscom Field"},
+		{"BED", "Hanscom Field"},
+		{"KBOS", "Boston"},
+		{"KJFK", "New York JFK"},
+		{"jfk", "New York JFK"},
+		{"KOWD", "KOWD"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			if actual := app.airportName(test.code); actual != test.exp {
+				t.Errorf("expected %q, got %q", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestLoadAirportsMissingFile(t *testing.T) {
+	app := &App{AirportFile: filepath.Join(t.TempDir(), "missing.csv")}
+	if err := app.loadAirports(); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// BeepTimeout bounds how long playing the alert sound may take.
+const BeepTimeout = 10 * time.Second
+
+// The tone played when no sound file is configured.
+const (
+	toneSampleRate = 8000
+	toneFrequency  = 880
+	toneDuration   = 200 * time.Millisecond
+)
+
+// defaultBeepCommand picks the sound player for the platform we are running on:
+// afplay ships with macOS, and aplay comes with ALSA on Linux.
+func defaultBeepCommand() string {
+	if runtime.GOOS == "darwin" {
+		return "afplay"
+	}
+	return "aplay"
+}
+
+// defaultBeepSound picks a sound file to play, since afplay can't play a tone
+// from its standard input like aplay can.
+func defaultBeepSound() string {
+	if runtime.GOOS == "darwin" {
+		return "/System/Library/Sounds/Ping.aiff"
+	}
+	return ""
+}
+
+// beepArgs builds the arguments with which to run the sound player to play the
+// sound file, or a tone from standard input if no file is given. Commands we
+// don't recognize are just given the file.
+func beepArgs(command, sound string) []string {
+	var args []string
+	switch filepath.Base(command) {
+	case "aplay":
+		args = []string{"-q"}
+	case "paplay", "afplay":
+	default:
+		if sound == "" {
+			return nil
+		}
+	}
+	if sound != "" {
+		args = append(args, sound)
+	}
+	return args
+}
+
+// canBeep reports whether the alert sound should be played for a flight which
+// alerted at time t.
+func (a *App) canBeep(t time.Time) bool {
+	return a.Beep && !a.DryRun && !a.QuietHours.Contains(t)
+}
+
+// beep plays the alert sound for a position, if enabled.
+func (a *App) beep(curr *Position) {
+	if !a.canBeep(curr.Timestamp) {
+		return
+	}
+	err := a.playBeep()
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		a.beepWarning.Do(func() {
+			slog.Warn("cannot play alert sound", "error", err)
+		})
+	case err != nil:
+		slog.Error("could not play alert sound", "error", err)
+	}
+}
+
+// playBeep runs the sound player, killing it if it runs for too long.
+func (a *App) playBeep() error {
+	command, err := exec.LookPath(a.BeepCommand)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), BeepTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, command, beepArgs(command, a.BeepSound)...)
+	if a.BeepSound == "" {
+		cmd.Stdin = bytes.NewReader(toneWAV())
+	}
+	if err := cmd.Run(); errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s timed out after %s", command, BeepTimeout)
+	} else if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+	return nil
+}
+
+// toneWAV generates a short sine wave tone as an 8-bit mono WAV file.
+func toneWAV() []byte {
+	samples := int(toneSampleRate * toneDuration.Seconds())
+	var b bytes.Buffer
+	for _, v := range []any{
+		[]byte("RIFF"),
+		uint32(36 + samples),
+		[]byte("WAVEfmt "),
+		uint32(16),             // format chunk size
+		uint16(1),              // PCM
+		uint16(1),              // channels
+		uint32(toneSampleRate), // sample rate
+		uint32(toneSampleRate), // byte rate
+		uint16(1),              // block align
+		uint16(8),              // bits per sample
+		[]byte("data"),
+		uint32(samples),
+	} {
+		binary.Write(&b, binary.LittleEndian, v)
+	}
+	for i := 0; i < samples; i++ {
+		v := math.Sin(2 * math.Pi * toneFrequency * float64(i) / toneSampleRate)
+		b.WriteByte(byte(128 + 100*v))
+	}
+	return b.Bytes()
+}
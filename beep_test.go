@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBeepArgs(t *testing.T) {
+	tests := []struct {
+		command string
+		sound   string
+		exp     []string
+	}{
+		{"afplay", "/System/Library/Sounds/Ping.aiff", []string{"/System/Library/Sounds/Ping.aiff"}},
+		{"/usr/bin/aplay", "alert.wav", []string{"-q", "alert.wav"}},
+		{"aplay", "", []string{"-q"}},
+		{"paplay", "", nil},
+		{"play", "alert.wav", []string{"alert.wav"}},
+		{"play", "", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.command+" "+test.sound, func(t *testing.T) {
+			if actual := beepArgs(test.command, test.sound); !slices.Equal(actual, test.exp) {
+				t.Errorf("expected %q, got %q", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestToneWAV(t *testing.T) {
+	wav := toneWAV()
+	if !bytes.HasPrefix(wav, []byte("RIFF")) || !bytes.Equal(wav[8:16], []byte("WAVEfmt ")) {
+		t.Fatalf("unexpected header: %q", wav[:16])
+	}
+	if size := binary.LittleEndian.Uint32(wav[4:8]); int(size) != len(wav)-8 {
+		t.Errorf("RIFF size %d doesn't match length %d", size, len(wav))
+	}
+	if size := binary.LittleEndian.Uint32(wav[40:44]); int(size) != len(wav)-44 {
+		t.Errorf("data size %d doesn't match length %d", size, len(wav))
+	}
+}
+
+func TestCanBeep(t *testing.T) {
+	q, err := parseQuietHours("22:00", "07:00", "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &App{Beep: true, QuietHours: q}
+	if app.canBeep(time.Date(2024, 7, 4, 3, 0, 0, 0, time.UTC)) {
+		t.Error("expected the alert sound to be suppressed during quiet hours")
+	}
+	if !app.canBeep(time.Date(2024, 7, 4, 12, 0, 0, 0, time.UTC)) {
+		t.Error("expected the alert sound outside quiet hours")
+	}
+	app.DryRun = true
+	if app.canBeep(time.Date(2024, 7, 4, 12, 0, 0, 0, time.UTC)) {
+		t.Error("expected the alert sound to be suppressed in a dry run")
+	}
+}
+
+func TestPlayBeep(t *testing.T) {
+	app := &App{BeepCommand: "true"}
+	if err := app.playBeep(); err != nil {
+		t.Errorf("expected the sound to play, got %v", err)
+	}
+	app.BeepCommand = "false"
+	if err := app.playBeep(); err == nil {
+		t.Error("expected a failing player to return an error")
+	}
+}
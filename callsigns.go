@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	icaoCodeRegex = regexp.MustCompile("^[A-Z]{3}$")
+	iataCodeRegex = regexp.MustCompile("^[A-Z0-9]{2}$")
+	// iataFlightRegex matches an ident made of an IATA airline code and a
+	// flight number, e.g. UA123 or B6512.
+	iataFlightRegex = regexp.MustCompile("^([A-Z0-9]{2})([0-9].*)$")
+)
+
+// loadCallsigns reads the callsign file, if one is configured. Each line holds
+// an ICAO or IATA airline code and its spoken callsign separated by a comma,
+// e.g.:
+//
+//	UAL,united
+//	BA,speed bird
+//
+// Blank lines and lines starting with # are ignored. Malformed lines are logged
+// and skipped rather than failing the whole file.
+func (a *App) loadCallsigns() error {
+	if a.CallsignFile == "" {
+		return nil
+	}
+	f, err := os.Open(a.CallsignFile)
+	if err != nil {
+		return fmt.Errorf("could not open callsign file: %w", err)
+	}
+	defer f.Close()
+
+	callsigns := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		code, name, err := parseCallsignLine(n, line)
+		if err != nil {
+			slog.Warn("skipping malformed callsign entry", "file", a.CallsignFile, "error", err)
+			continue
+		}
+		callsigns[code] = name
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read callsign file: %w", err)
+	}
+	a.callsigns = callsigns
+	return nil
+}
+
+// parseCallsignLine parses line n of the callsign file, which must have exactly
+// two fields: an ICAO or IATA airline code and a non-empty callsign.
+func parseCallsignLine(n int, line string) (code, name string, err error) {
+	fields := strings.Split(line, ",")
+	if len(fields) != 2 {
+		return "", "", fmt.Errorf("line %d: expected 2 fields, got %d in %q", n, len(fields), line)
+	}
+	code, name = strings.ToUpper(strings.TrimSpace(fields[0])), strings.TrimSpace(fields[1])
+	if !icaoCodeRegex.MatchString(code) && !iataCodeRegex.MatchString(code) {
+		return "", "", fmt.Errorf("line %d: %q is not an ICAO or IATA airline code", n, code)
+	}
+	if name == "" {
+		return "", "", fmt.Errorf("line %d: missing callsign for %s", n, code)
+	}
+	return code, name, nil
+}
+
+// allCallsigns merges the built-in callsigns with any loaded from a file.
+func (a *App) allCallsigns() map[string]string {
+	callsigns := maps.Clone(icaoCallsigns)
+	maps.Copy(callsigns, a.callsigns)
+	return callsigns
+}
+
+// airlineCallsign finds the spoken callsign of the airline an ident belongs
+// to, and the flight number following the airline code. The ident is tried as
+// starting with an ICAO code first, and then an IATA code. callsign is empty if
+// the airline isn't known.
+func (a *App) airlineCallsign(ident string) (callsign, suffix string) {
+	if len(ident) >= 3 && icaoCodeRegex.MatchString(ident[:3]) {
+		if callsign := a.icaoCallsign(ident[:3]); callsign != "" {
+			return callsign, ident[3:]
+		}
+	}
+	if m := iataFlightRegex.FindStringSubmatch(ident); m != nil {
+		if callsign := a.iataCallsign(m[1]); callsign != "" {
+			return callsign, m[2]
+		}
+	}
+	return "", ""
+}
+
+// iataCallsign looks up the callsign for an IATA airline code, preferring one
+// loaded from the callsign file and otherwise going by the airline's ICAO code.
+func (a *App) iataCallsign(iata string) string {
+	if callsign, ok := a.callsigns[iata]; ok {
+		return callsign
+	}
+	if icao, ok := iataAirlines[iata]; ok {
+		return a.icaoCallsign(icao)
+	}
+	return ""
+}
+
+// iataAirlines maps IATA airline codes to ICAO ones, for the airlines in the
+// built-in callsign table.
+var iataAirlines = map[string]string{
+	"UA": "UAL",
+	"FX": "FDX",
+	"DL": "DAL",
+	"9K": "KAP",
+	"NK": "NKS",
+	"YX": "RPA",
+	"AC": "ACA",
+	"PD": "POE",
+	"WN": "SWA",
+	"B6": "JBU",
+	"EI": "EIN",
+	"AA": "AAL",
+	"AS": "ASA",
+	"F9": "FFT",
+	"JL": "JAL",
+	"QK": "JZA",
+	"AF": "AFR",
+	"BA": "BAW",
+}
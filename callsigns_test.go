@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadCallsigns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "callsigns.csv")
+	data := `# overrides
+UAL, united airlines
+aca,air canada
+QXE,horizon
+X,too short
+b6,blue
+ABCD,too long
+EJA,
+nocomma
+SWA,southwest,extra
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	app := &App{CallsignFile: path}
+	if err := app.loadCallsigns(); err != nil {
+		t.Fatal(err)
+	}
+	if len(app.callsigns) != 4 {
+		t.Errorf("expected malformed entries to be skipped, got %v", app.callsigns)
+	}
+
+	tests := []struct {
+		ident string
+		exp   string
+	}{
+		{"UAL1234", "united airlines 12 34"},
+		{"QXE2345", "horizon 23 45"},
+		{"ACA12", "air canada 12"},
+		{"DAL123", "delta 1 23"},
+		{"EJA123", "echo juliet alpha one two three"},
+		{"B6123", "blue 1 23"},
+		{"UA123", "united airlines 1 23"},
+	}
+	for _, test := range tests {
+		t.Run(test.ident, func(t *testing.T) {
+			actual := strings.Join(app.identToWords(test.ident), " ")
+			if actual != test.exp {
+				t.Errorf("unexpected verbalization: %s", actual)
+			}
+		})
+	}
+}
+
+func TestParseCallsignLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		code  string
+		name  string
+		valid bool
+	}{
+		{"UAL,united", "UAL", "united", true},
+		{"baw, speed bird ", "BAW", "speed bird", true},
+		{"UAL,united,extra", "", "", false},
+		{"nocomma", "", "", false},
+		{"b6,jet blue", "B6", "jet blue", true},
+		{"X,too short", "", "", false},
+		{"EJA,", "", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			code, name, err := parseCallsignLine(7, test.line)
+			if (err == nil) != test.valid {
+				t.Fatalf("unexpected result: %v", err)
+			}
+			if err != nil && !strings.Contains(err.Error(), "line 7") {
+				t.Errorf("expected the line number in the error: %v", err)
+			}
+			if code != test.code || name != test.name {
+				t.Errorf("unexpected entry: %q, %q", code, name)
+			}
+		})
+	}
+}
+
+func TestIATACallsigns(t *testing.T) {
+	tests := []struct {
+		ident string
+		exp   string
+	}{
+		{"UA123", "united 1 23"},
+		{"UAL123", "united 1 23"},
+		{"DL12", "delta 12"},
+		{"B6512", "jet blue 5 12"},
+		{"AA1", "american one"},
+		{"DLH400", "delta lima hotel four zero zero"},
+		{"ZZ123", "zulu zulu one two three"},
+	}
+	for _, test := range tests {
+		t.Run(test.ident, func(t *testing.T) {
+			actual := strings.Join((&App{}).identToWords(test.ident), " ")
+			if actual != test.exp {
+				t.Errorf("unexpected verbalization: %s", actual)
+			}
+		})
+	}
+}
+
+func TestLoadCallsignsMissingFile(t *testing.T) {
+	app := &App{CallsignFile: filepath.Join(t.TempDir(), "missing.csv")}
+	if err := app.loadCallsigns(); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
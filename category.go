@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Categories of aircraft which can be filtered on.
+const (
+	HelicopterCategory = "helicopter"
+	FixedWingCategory  = "fixed-wing"
+	// UnknownCategory is for flights not reporting an aircraft type, or
+	// reporting one we don't recognize.
+	UnknownCategory = "unknown"
+)
+
+// Ways the aircraft filter can apply to its categories.
+const (
+	// AircraftFilterInclude limits interesting flights to those in one of the
+	// filter's categories.
+	AircraftFilterInclude = "include"
+	// AircraftFilterExclude ignores flights in any of the filter's categories.
+	AircraftFilterExclude = "exclude"
+)
+
+func validateAircraftFilter(categories []string, mode string) error {
+	for _, category := range categories {
+		switch strings.ToLower(category) {
+		case HelicopterCategory, FixedWingCategory:
+		default:
+			return fmt.Errorf("unknown aircraft category %q; must be %s or %s", category, HelicopterCategory, FixedWingCategory)
+		}
+	}
+	switch mode {
+	case AircraftFilterInclude, AircraftFilterExclude:
+		return nil
+	}
+	return fmt.Errorf("unknown aircraft-filter-mode %q", mode)
+}
+
+// aircraftCategory infers the category of an ICAO aircraft type designator
+// from tables of common types. Types in neither table are UnknownCategory.
+func aircraftCategory(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	switch {
+	case helicopterTypes[code]:
+		return HelicopterCategory
+	case fixedWingTypes[code]:
+		return FixedWingCategory
+	}
+	return UnknownCategory
+}
+
+// isInterestingCategory applies the aircraft filter to the position's type.
+// Flights of unknown type pass unless ExcludeUnknownCategory is set.
+func (a *App) isInterestingCategory(pos *Position) bool {
+	if len(a.AircraftFilter) == 0 {
+		return true
+	}
+	category := pos.Category
+	if category == "" || category == UnknownCategory {
+		return !a.ExcludeUnknownCategory
+	}
+	var listed bool
+	for _, c := range a.AircraftFilter {
+		if strings.EqualFold(c, category) {
+			listed = true
+			break
+		}
+	}
+	if a.AircraftFilterMode == AircraftFilterExclude {
+		return !listed
+	}
+	return listed
+}
+
+// helicopterTypes are the type designators of common helicopters.
+var helicopterTypes = map[string]bool{
+	"A109": true, // AgustaWestland AW109
+	"A119": true, // AgustaWestland AW119
+	"A139": true, // AgustaWestland AW139
+	"A169": true, // AgustaWestland AW169
+	"A189": true, // AgustaWestland AW189
+	"AS32": true, // Airbus Super Puma
+	"AS50": true, // Airbus AS350 Ecureuil
+	"AS55": true, // Airbus AS355 Ecureuil 2
+	"AS65": true, // Airbus AS365 Dauphin
+	"B06":  true, // Bell 206 JetRanger
+	"B06T": true, // Bell 206L TwinRanger
+	"B212": true, // Bell 212
+	"B222": true, // Bell 222
+	"B230": true, // Bell 230
+	"B407": true, // Bell 407
+	"B412": true, // Bell 412
+	"B427": true, // Bell 427
+	"B429": true, // Bell 429
+	"B430": true, // Bell 430
+	"B505": true, // Bell 505 Jet Ranger X
+	"BK17": true, // Kawasaki BK117
+	"EC20": true, // Airbus H120
+	"EC30": true, // Airbus H130
+	"EC35": true, // Airbus H135
+	"EC45": true, // Airbus H145
+	"EC55": true, // Airbus H155
+	"EC75": true, // Airbus H175
+	"EXPL": true, // MD Explorer
+	"H47":  true, // Boeing CH-47 Chinook
+	"H53":  true, // Sikorsky CH-53
+	"H60":  true, // Sikorsky UH-60 Black Hawk
+	"H64":  true, // Boeing AH-64 Apache
+	"MD52": true, // MD 520N
+	"MD60": true, // MD 600N
+	"R22":  true, // Robinson R22
+	"R44":  true, // Robinson R44
+	"R66":  true, // Robinson R66
+	"S61":  true, // Sikorsky S-61
+	"S76":  true, // Sikorsky S-76
+	"S92":  true, // Sikorsky S-92
+	"UH1":  true, // Bell UH-1 Iroquois
+}
+
+// fixedWingTypes are the type designators of common fixed-wing aircraft.
+var fixedWingTypes = map[string]bool{
+	"A19N": true, // Airbus A319neo
+	"A20N": true, // Airbus A320neo
+	"A21N": true, // Airbus A321neo
+	"A318": true, // Airbus A318
+	"A319": true, // Airbus A319
+	"A320": true, // Airbus A320
+	"A321": true, // Airbus A321
+	"A332": true, // Airbus A330-200
+	"A333": true, // Airbus A330-300
+	"A339": true, // Airbus A330-900
+	"A343": true, // Airbus A340-300
+	"A359": true, // Airbus A350-900
+	"A35K": true, // Airbus A350-1000
+	"A388": true, // Airbus A380-800
+	"AT43": true, // ATR 42-300
+	"AT45": true, // ATR 42-500
+	"AT75": true, // ATR 72-500
+	"AT76": true, // ATR 72-600
+	"B712": true, // Boeing 717-200
+	"B737": true, // Boeing 737-700
+	"B738": true, // Boeing 737-800
+	"B739": true, // Boeing 737-900
+	"B38M": true, // Boeing 737 MAX 8
+	"B39M": true, // Boeing 737 MAX 9
+	"B744": true, // Boeing 747-400
+	"B748": true, // Boeing 747-8
+	"B752": true, // Boeing 757-200
+	"B753": true, // Boeing 757-300
+	"B763": true, // Boeing 767-300
+	"B764": true, // Boeing 767-400
+	"B772": true, // Boeing 777-200
+	"B77L": true, // Boeing 777-200LR
+	"B77W": true, // Boeing 777-300ER
+	"B788": true, // Boeing 787-8
+	"B789": true, // Boeing 787-9
+	"B78X": true, // Boeing 787-10
+	"BCS1": true, // Airbus A220-100
+	"BCS3": true, // Airbus A220-300
+	"BE20": true, // Beechcraft King Air 200
+	"BE35": true, // Beechcraft Bonanza (V-tail)
+	"BE36": true, // Beechcraft Bonanza
+	"BE58": true, // Beechcraft Baron
+	"BE9L": true, // Beechcraft King Air 90
+	"C150": true, // Cessna 150
+	"C152": true, // Cessna 152
+	"C172": true, // Cessna 172 Skyhawk
+	"C182": true, // Cessna 182 Skylane
+	"C206": true, // Cessna 206 Stationair
+	"C208": true, // Cessna 208 Caravan
+	"C25A": true, // Cessna Citation CJ2
+	"C25B": true, // Cessna Citation CJ3
+	"C310": true, // Cessna 310
+	"C56X": true, // Cessna Citation Excel
+	"C68A": true, // Cessna Citation Latitude
+	"C700": true, // Cessna Citation Longitude
+	"CL30": true, // Bombardier Challenger 300
+	"CL35": true, // Bombardier Challenger 350
+	"CL60": true, // Bombardier Challenger 600
+	"CRJ2": true, // Bombardier CRJ200
+	"CRJ7": true, // Bombardier CRJ700
+	"CRJ9": true, // Bombardier CRJ900
+	"DA40": true, // Diamond DA40
+	"DA42": true, // Diamond DA42
+	"DH8A": true, // De Havilland Dash 8-100
+	"DH8D": true, // De Havilland Dash 8-400
+	"E135": true, // Embraer ERJ 135
+	"E145": true, // Embraer ERJ 145
+	"E170": true, // Embraer 170
+	"E190": true, // Embraer 190
+	"E195": true, // Embraer 195
+	"E290": true, // Embraer E190-E2
+	"E55P": true, // Embraer Phenom 300
+	"E75L": true, // Embraer 175
+	"E75S": true, // Embraer 175
+	"GLEX": true, // Bombardier Global Express
+	"GLF4": true, // Gulfstream IV
+	"GLF5": true, // Gulfstream V
+	"GLF6": true, // Gulfstream G650
+	"LJ45": true, // Learjet 45
+	"M20P": true, // Mooney M20
+	"MD11": true, // McDonnell Douglas MD-11
+	"P28A": true, // Piper Cherokee
+	"P28R": true, // Piper Arrow
+	"PA31": true, // Piper Navajo
+	"PA34": true, // Piper Seneca
+	"PA46": true, // Piper Malibu
+	"PC12": true, // Pilatus PC-12
+	"SR20": true, // Cirrus SR20
+	"SR22": true, // Cirrus SR22
+	"SW4":  true, // Fairchild Metro
+	"TBM7": true, // Socata TBM 700
+	"TBM9": true, // Socata TBM 900
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/benburwell/firehose"
+)
+
+func TestAircraftCategory(t *testing.T) {
+	tests := []struct {
+		code string
+		exp  string
+	}{
+		{"H60", HelicopterCategory},
+		{"R44", HelicopterCategory},
+		{"ec35", HelicopterCategory},
+		{"B738", FixedWingCategory},
+		{"C172", FixedWingCategory},
+		{"", UnknownCategory},
+		{"ZZZZ", UnknownCategory},
+		{"XYZ1", UnknownCategory},
+	}
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			if actual := aircraftCategory(test.code); actual != test.exp {
+				t.Errorf("expected %s but got %s", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestIsInterestingCategory(t *testing.T) {
+	tests := []struct {
+		name           string
+		filter         []string
+		mode           string
+		excludeUnknown bool
+		aircraftType   string
+		exp            bool
+	}{
+		{"no filter", nil, AircraftFilterInclude, false, "R44", true},
+		{"exclude helicopter", []string{HelicopterCategory}, AircraftFilterExclude, false, "R44", false},
+		{"exclude helicopter passes jet", []string{HelicopterCategory}, AircraftFilterExclude, false, "B738", true},
+		{"include helicopter", []string{HelicopterCategory}, AircraftFilterInclude, false, "H60", true},
+		{"include helicopter drops jet", []string{HelicopterCategory}, AircraftFilterInclude, false, "B738", false},
+		{"unknown passes", []string{FixedWingCategory}, AircraftFilterInclude, false, "", true},
+		{"unknown filtered", []string{FixedWingCategory}, AircraftFilterInclude, true, "", false},
+		{"unrecognized filtered", []string{HelicopterCategory}, AircraftFilterExclude, true, "XYZ1", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &App{
+				AircraftFilter:         test.filter,
+				AircraftFilterMode:     test.mode,
+				ExcludeUnknownCategory: test.excludeUnknown,
+			}
+			pos := &Position{AircraftType: test.aircraftType, Category: aircraftCategory(test.aircraftType)}
+			if actual := app.isInteresting(pos); actual != test.exp {
+				t.Errorf("expected %t but got %t", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestCategoryIgnoresAliases(t *testing.T) {
+	app := &App{
+		AircraftFilter:         []string{FixedWingCategory},
+		AircraftFilterMode:     AircraftFilterInclude,
+		ExcludeUnknownCategory: true,
+		TypeAliases:            map[string]string{"B38M": "B737 MAX"},
+	}
+	pos, err := app.newPosition(&firehose.PositionMessage{
+		ID:           "A",
+		Lat:          "42.0",
+		Lon:          "-71.0",
+		Clock:        "1000",
+		AircraftType: "B38M",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pos.AircraftType != "B737 MAX" || pos.Category != FixedWingCategory {
+		t.Errorf("expected aliased fixed-wing type, got %q in category %q", pos.AircraftType, pos.Category)
+	}
+	if !app.isInteresting(pos) {
+		t.Errorf("expected aliased fixed-wing type to pass the filter")
+	}
+}
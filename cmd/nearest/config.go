@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"overhead/internal/precision"
+	"overhead/internal/unit"
+	"overhead/internal/validate"
+)
+
+// validateConfig checks the display settings and the units they are shown in
+// before nearest connects to Firehose, so that a bad setting is reported up
+// front along with any others instead of surfacing later on the display.
+func validateConfig(a *App, units string, allowNullIsland bool) []error {
+	var problems []error
+	check := func(err error) {
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	check(validate.Location(a.Latitude, a.Longitude, allowNullIsland))
+	if a.Username == "" || a.Password == "" {
+		check(errors.New("username and password are required to connect to Firehose"))
+	}
+	if a.RadiusNM <= 0 {
+		check(errors.New("radius must be positive"))
+	}
+	if a.CeilingFt <= 0 {
+		check(errors.New("ceiling must be positive"))
+	}
+	check(unit.Validate(units))
+	check(precision.Validate(a.DistancePrecision))
+	if a.DisplayType != HD44780Display && a.DisplayType != SSD1306Display {
+		check(fmt.Errorf("unknown display-type %q; must be %s or %s", a.DisplayType, HD44780Display, SSD1306Display))
+	}
+	if a.DisplayType == SSD1306Display && a.DisplayHeight != 32 && a.DisplayHeight != 64 {
+		check(fmt.Errorf("display-height must be 32 or 64, not %d", a.DisplayHeight))
+	}
+	if a.LCDGeometry != LCD16x2 && a.LCDGeometry != LCD20x4 {
+		check(fmt.Errorf("unknown lcd-geometry %q; must be %s or %s", a.LCDGeometry, LCD16x2, LCD20x4))
+	}
+	if a.DisplayReplace.MinHold < 0 {
+		check(errors.New("display-min-hold must not be negative"))
+	}
+	if a.DisplayReplace.Margin < 0 || a.DisplayReplace.Margin >= 1 {
+		check(fmt.Errorf("display-replace-margin %v must be at least 0 and less than 1", a.DisplayReplace.Margin))
+	}
+	return problems
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateConfig(t *testing.T) {
+	valid := func() *App {
+		return &App{
+			Username:          "user",
+			Password:          "secret",
+			Latitude:          42.36,
+			Longitude:         -71.01,
+			RadiusNM:          10,
+			CeilingFt:         15000,
+			DisplayType:       HD44780Display,
+			DisplayHeight:     64,
+			LCDGeometry:       LCD16x2,
+			DistancePrecision: "1",
+		}
+	}
+	tests := []struct {
+		name     string
+		modify   func(*App)
+		problems []string
+	}{
+		{"valid", func(a *App) {}, nil},
+		{"ssd1306", func(a *App) { a.DisplayType = SSD1306Display }, nil},
+		{"short ssd1306", func(a *App) { a.DisplayType, a.DisplayHeight = SSD1306Display, 32 }, nil},
+		{"bad ssd1306 height", func(a *App) { a.DisplayType, a.DisplayHeight = SSD1306Display, 48 }, []string{"display-height must be 32 or 64"}},
+		{"height ignored for hd44780", func(a *App) { a.DisplayHeight = 48 }, nil},
+		{"unknown display type", func(a *App) { a.DisplayType = "oled" }, []string{"unknown display-type"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := valid()
+			test.modify(app)
+			problems := validateConfig(app, "imperial", false)
+			if len(problems) != len(test.problems) {
+				t.Fatalf("expected %d problems, got %v", len(test.problems), problems)
+			}
+			for i, problem := range problems {
+				if !strings.Contains(problem.Error(), test.problems[i]) {
+					t.Errorf("expected problem %d to mention %q, got %q", i, test.problems[i], problem)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	lcd "github.com/d2r2/go-hd44780"
+	"github.com/d2r2/go-i2c"
+)
+
+// Supported display types.
+const (
+	HD44780Display = "hd44780"
+	SSD1306Display = "ssd1306"
+)
+
+// Supported HD44780 LCD geometries, in columns by lines.
+const (
+	LCD16x2 = "16x2"
+	LCD20x4 = "20x4"
+)
+
+// A Display shows lines of text.
+type Display interface {
+	// Clear blanks the display.
+	Clear() error
+	// ShowLine replaces the text of a line, numbered from 0.
+	ShowLine(line int, text string) error
+	// Flush sends any changes which Clear and ShowLine have buffered to the
+	// display.
+	Flush() error
+	// Lines is how many lines of text the display can show.
+	Lines() int
+	// Width is how many characters fit on a line.
+	Width() int
+	// On and Off turn the display (or its backlight) on and off.
+	On() error
+	Off() error
+}
+
+func (a *App) setupDisplay() (Display, error) {
+	if a.DryRun {
+		if a.DisplayType == SSD1306Display {
+			return logDisplay{SSD1306Lines, SSD1306Width / ssd1306CharWidth}, nil
+		}
+		if a.LCDGeometry == LCD20x4 {
+			return logDisplay{4, 20}, nil
+		}
+		return logDisplay{2, 16}, nil
+	}
+	bus, err := i2c.NewI2C(a.I2CAddress, a.I2CBus)
+	if err != nil {
+		return nil, err
+	}
+	switch a.DisplayType {
+	case SSD1306Display:
+		return newSSD1306(bus, SSD1306Width, a.DisplayHeight)
+	case HD44780Display, "":
+		if a.LCDGeometry == LCD20x4 {
+			screen, err := lcd.NewLcd(bus, lcd.LCD_20x4)
+			if err != nil {
+				return nil, err
+			}
+			return hd44780{screen, hd44780Lines[:4], 20}, nil
+		}
+		screen, err := lcd.NewLcd(bus, lcd.LCD_16x2)
+		if err != nil {
+			return nil, err
+		}
+		return hd44780{screen, hd44780Lines[:2], 16}, nil
+	}
+	return nil, fmt.Errorf("unknown display-type %q", a.DisplayType)
+}
+
+// logDisplay logs what would be shown instead of driving any hardware.
+type logDisplay struct {
+	lines, width int
+}
+
+func (d logDisplay) Clear() error { return nil }
+func (d logDisplay) On() error    { return nil }
+func (d logDisplay) Flush() error { return nil }
+func (d logDisplay) Lines() int   { return d.lines }
+func (d logDisplay) Width() int   { return d.width }
+
+func (d logDisplay) Off() error {
+	log.Println("would turn display off")
+	return nil
+}
+
+func (d logDisplay) ShowLine(line int, text string) error {
+	log.Printf("would show line %d: %s", line+1, text)
+	return nil
+}
+
+// hd44780 is a 16x2 or 20x4 character LCD.
+type hd44780 struct {
+	screen *lcd.Lcd
+	// lines selects each line the screen has.
+	lines []lcd.ShowOptions
+	width int
+}
+
+var hd44780Lines = []lcd.ShowOptions{lcd.SHOW_LINE_1, lcd.SHOW_LINE_2, lcd.SHOW_LINE_3, lcd.SHOW_LINE_4}
+
+func (d hd44780) Clear() error { return d.screen.Clear() }
+func (d hd44780) On() error    { return d.screen.BacklightOn() }
+func (d hd44780) Off() error   { return d.screen.BacklightOff() }
+func (d hd44780) Flush() error { return nil }
+func (d hd44780) Lines() int   { return len(d.lines) }
+func (d hd44780) Width() int   { return d.width }
+
+func (d hd44780) ShowLine(line int, text string) error {
+	if line < 0 || line >= len(d.lines) {
+		return fmt.Errorf("no line %d", line)
+	}
+	return d.screen.ShowMessage(text, d.lines[line]|lcd.SHOW_BLANK_PADDING)
+}
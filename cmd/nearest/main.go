@@ -8,30 +8,67 @@ import (
 	"math"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/benburwell/firehose"
-	lcd "github.com/d2r2/go-hd44780"
-	"github.com/d2r2/go-i2c"
 	"github.com/skypies/geo"
 	"github.com/spf13/cast"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+
+	"overhead/internal/airport"
+	"overhead/internal/bbox"
+	"overhead/internal/credentials"
+	"overhead/internal/precision"
+	"overhead/internal/unit"
+	"overhead/internal/validate"
+)
+
+const (
+	// FlipInterval is the least time each of the flip and flop screens is
+	// shown for.
+	FlipInterval = 5 * time.Second
+	// ScrollInterval is how often lines too long for the screen scroll by a
+	// character.
+	ScrollInterval = 400 * time.Millisecond
+	// ScrollPause is how many scroll intervals long lines pause for at each
+	// end.
+	ScrollPause = 3
 )
 
 const (
 	FT_PER_NM = 6080.0
+	// EnvPrefix begins the names of environment variables settings can be
+	// given in. It is shared with overhead so that both can find the same
+	// Firehose credentials.
+	EnvPrefix = "OVERHEAD"
 )
 
 func main() {
 	pflag.String("username", "", "Username for Firehose authentication")
-	pflag.String("password", "", "Password for Firehose authentication")
+	pflag.String("password", "", "Password for Firehose authentication (or set OVERHEAD_PASSWORD)")
+	pflag.String("password-file", "", "File to read the Firehose password from")
 	pflag.Float64("ceiling", 10000, "Maximum altitude in feet at which to display flights")
 	pflag.Float64("radius", 3, "Radius in nautical miles around location within which to display flights")
 	pflag.Duration("persist", time.Minute, "Persist flight on display for at most this long")
-	pflag.Int("i2c-bus", 1, "I2C bus to use for LCD")
-	pflag.Uint8("i2c-address", 0x27, "I2C address for LCD")
+	pflag.String("units", unit.Imperial, "Units to display distances and altitudes in: imperial or metric")
+	pflag.String("distance-precision", "1", "Decimal places to display distances to, or adaptive for more when closer")
+	pflag.String("display-type", HD44780Display, "Type of display: hd44780 (16x2 LCD) or ssd1306 (OLED)")
+	pflag.String("lcd-geometry", LCD16x2, "Geometry of an HD44780 LCD: 16x2 or 20x4")
+	pflag.Int("display-height", 64, "Height in pixels of an SSD1306 display: 32 or 64")
+	pflag.Duration("display-min-hold", 0, "Minimum time to show a flight before switching to a slightly closer one")
+	pflag.Float64("display-replace-margin", 0.5, "Fraction by which a flight must be closer to replace one shown for less than display-min-hold")
+	pflag.String("status-file", "", "File to keep the nearest flight in as JSON")
+	pflag.Bool("dry-run", false, "Log what would be displayed instead of writing to the display")
+	pflag.Int("i2c-bus", 1, "I2C bus to use for the display")
+	pflag.Uint8("i2c-address", 0x27, "I2C address for the display (SSD1306 displays are usually 0x3c)")
+	pflag.Float64("max-radius", 100, "Maximum radius in nautical miles that may be configured")
+	pflag.Bool("clamp-radius", false, "Reduce a radius exceeding max-radius to the maximum instead of exiting")
+	pflag.Bool("allow-null-island", false, "Allow watching latitude 0, longitude 0")
+	selfTest := pflag.Bool("selftest", false, "Show a test message on the display and exit, to check the hardware")
 	configFile := pflag.StringP("config-file", "c", "", "Config file name")
 	showHelp := pflag.BoolP("help", "h", false, "Show help")
 	pflag.Parse()
@@ -57,16 +94,62 @@ func main() {
 	if err := viper.BindPFlags(pflag.CommandLine); err != nil {
 		log.Fatal(err.Error())
 	}
+	// Any setting can also come from an environment variable such as
+	// OVERHEAD_PASSWORD or OVERHEAD_RADIUS.
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
+
+	units := viper.GetString("units")
+
+	// Password and radius problems are reported along with the rest of the
+	// configuration's.
+	var problems []error
+	passwordInEnv := credentials.InEnv(EnvPrefix + "_PASSWORD")
+	password, err := credentials.ResolvePassword(viper.GetString("password"), pflag.CommandLine.Changed("password") || passwordInEnv, viper.GetString("password-file"))
+	if err != nil {
+		problems = append(problems, err)
+	}
+	radius, err := validate.Radius("radius", viper.GetFloat64("radius"), viper.GetFloat64("max-radius"), viper.GetBool("clamp-radius"))
+	if err != nil {
+		problems = append(problems, err)
+		radius = viper.GetFloat64("radius")
+	}
 
 	app := &App{
-		Username:   viper.GetString("username"),
-		Password:   viper.GetString("password"),
-		Latitude:   viper.GetFloat64("latitude"),
-		Longitude:  viper.GetFloat64("longitude"),
-		RadiusNM:   viper.GetFloat64("radius"),
-		CeilingFt:  viper.GetFloat64("ceiling"),
-		I2CBus:     viper.GetInt("i2c-bus"),
-		I2CAddress: cast.ToUint8(viper.Get("i2c-address")),
+		Username:          viper.GetString("username"),
+		Password:          password,
+		Latitude:          viper.GetFloat64("latitude"),
+		Longitude:         viper.GetFloat64("longitude"),
+		RadiusNM:          radius,
+		CeilingFt:         viper.GetFloat64("ceiling"),
+		DisplayType:       viper.GetString("display-type"),
+		DisplayHeight:     viper.GetInt("display-height"),
+		LCDGeometry:       viper.GetString("lcd-geometry"),
+		I2CBus:            viper.GetInt("i2c-bus"),
+		I2CAddress:        cast.ToUint8(viper.Get("i2c-address")),
+		Metric:            unit.IsMetric(units),
+		DistancePrecision: viper.GetString("distance-precision"),
+		DryRun:            viper.GetBool("dry-run"),
+		StatusFile:        viper.GetString("status-file"),
+		DisplayReplace: replacePolicy{
+			MinHold: viper.GetDuration("display-min-hold"),
+			Margin:  viper.GetFloat64("display-replace-margin"),
+		},
+	}
+	if *selfTest {
+		if err := app.selfTest(); err != nil {
+			log.Fatalf("self test failed: %v", err)
+		}
+		log.Println("self test passed; check the display shows the test message")
+		os.Exit(0)
+	}
+	problems = append(problems, validateConfig(app, units, viper.GetBool("allow-null-island"))...)
+	if len(problems) > 0 {
+		for _, err := range problems {
+			log.Printf("invalid configuration: %v", err)
+		}
+		os.Exit(1)
 	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
@@ -79,14 +162,33 @@ func main() {
 }
 
 type App struct {
-	Username   string
-	Password   string
-	Latitude   float64
-	Longitude  float64
-	RadiusNM   float64
-	CeilingFt  float64
-	I2CBus     int
-	I2CAddress uint8
+	Username  string
+	Password  string
+	Latitude  float64
+	Longitude float64
+	RadiusNM  float64
+	CeilingFt float64
+	// DisplayType is HD44780Display or SSD1306Display.
+	DisplayType string
+	// DisplayHeight is the height in pixels of an SSD1306 display.
+	DisplayHeight int
+	// LCDGeometry is LCD16x2 or LCD20x4.
+	LCDGeometry string
+	I2CBus      int
+	I2CAddress  uint8
+	// Metric displays distances in kilometers and altitudes in meters rather
+	// than nautical miles and flight levels.
+	Metric bool
+	// DistancePrecision is the number of decimal places to display distances
+	// to, or precision.Adaptive.
+	DistancePrecision string
+	// DryRun logs what would be displayed instead of using the display.
+	DryRun bool
+	// StatusFile is a file in which the nearest flight is kept as JSON for
+	// other tools to read, and emptied once there is none.
+	StatusFile string
+	// DisplayReplace decides when a closer flight takes over the display.
+	DisplayReplace replacePolicy
 }
 
 func (a *App) Run(ctx context.Context) error {
@@ -101,21 +203,22 @@ func (a *App) Run(ctx context.Context) error {
 		Username: a.Username,
 		Password: a.Password,
 		Events:   []firehose.Event{firehose.PositionEvent},
-		LatLong:  []firehose.Rectangle{a.flightObservationBox()},
+		LatLong:  a.flightObservationBox(),
 	}
 
 	if err := stream.Init(cmd.String()); err != nil {
 		return fmt.Errorf("could not initialize firehose: %w", err)
 	}
 
-	screen, err := a.setupLCD()
+	screen, err := a.setupDisplay()
 	if err != nil {
-		return err
+		return fmt.Errorf("could not set up display: %w", err)
 	}
 
 	positions := make(chan Position)
 	defer close(positions)
-	go renderPositions(positions, screen)
+	units := displayUnits{Metric: a.Metric, DistancePrecision: a.DistancePrecision}
+	go renderPositions(positions, screen, units, a.StatusFile, a.DisplayReplace)
 
 	for {
 		msg, err := stream.NextMessage(ctx)
@@ -138,26 +241,8 @@ func (a *App) Run(ctx context.Context) error {
 	}
 }
 
-func (a *App) setupLCD() (*lcd.Lcd, error) {
-	bus, err := i2c.NewI2C(a.I2CAddress, a.I2CBus)
-	if err != nil {
-		return nil, err
-	}
-	return lcd.NewLcd(bus, lcd.LCD_16x2)
-}
-
-func (a *App) flightObservationBox() firehose.Rectangle {
-	center := a.myLocation()
-	minLat := center.MoveNM(180, a.RadiusNM)
-	maxLat := center.MoveNM(0, a.RadiusNM)
-	minLon := center.MoveNM(270, a.RadiusNM)
-	maxLon := center.MoveNM(90, a.RadiusNM)
-	return firehose.Rectangle{
-		LowLat: minLat.Lat,
-		LowLon: minLon.Long,
-		HiLat:  maxLat.Lat,
-		HiLon:  maxLon.Long,
-	}
+func (a *App) flightObservationBox() []firehose.Rectangle {
+	return bbox.Around(a.myLocation(), a.RadiusNM)
 }
 
 type Position struct {
@@ -269,70 +354,98 @@ func cardinalDirection(bearing float64) string {
 	return ""
 }
 
-func renderPositions(positions <-chan Position, screen *lcd.Lcd) {
+func renderPositions(positions <-chan Position, screen Display, units displayUnits, statusFile string, policy replacePolicy) {
 	var position *Position
+	// shownSince is when the flight on the display was first shown.
+	var shownSince time.Time
 
-	refresh := time.NewTicker(5 * time.Second)
+	refresh := time.NewTicker(ScrollInterval)
 	defer refresh.Stop()
 
-	var flip bool
+	var scroll scrollState
+	// shown holds what is currently on the screen, so that we can avoid
+	// redrawing it with identical content.
+	var shown screenLines
 
 	for {
 		select {
 		case <-refresh.C:
-			flip = !flip
-
 			if position != nil {
 				// If our position is super old, turn the screen off.
 				if time.Now().Sub(position.Timestamp) > time.Minute {
 					position = nil
+					shown = nil
+					updateStatus(statusFile, nil)
 					screen.Clear()
-					screen.BacklightOff()
+					screen.Flush()
+					screen.Off()
 					continue
 				}
 
 				// Otherwise, show the appropriate display.
-				if flip {
-					renderFlip(*position, screen)
-				} else {
-					renderFlop(*position, screen)
+				lines := scroll.next(*position, screen.Lines(), screen.Width(), units, time.Now())
+				if !slices.Equal(lines, shown) {
+					renderLines(lines, screen)
+					shown = lines
 				}
 			}
 		case p := <-positions:
-			if shouldReplace(position, &p) {
+			if policy.shouldReplace(position, &p, shownSince) {
+				if position == nil || position.FlightID != p.FlightID {
+					shownSince = time.Now()
+				}
 				position = &p
+				updateStatus(statusFile, position)
 			}
 		}
 	}
 }
 
-func shouldReplace(prev, curr *Position) bool {
+// A replacePolicy decides when a new position should replace the one on the
+// display.
+type replacePolicy struct {
+	// MinHold is how long a flight is shown before a different flight that is
+	// only marginally closer may replace it.
+	MinHold time.Duration
+	// Margin is the fraction by which a different flight must be closer to
+	// replace one which has been shown for less than MinHold.
+	Margin float64
+}
+
+// shouldReplace decides whether curr should replace prev, which has been on the
+// display since shownSince.
+func (p replacePolicy) shouldReplace(prev, curr *Position, shownSince time.Time) bool {
 	// If we don't have a previous position at all, we should use the new one.
 	if prev == nil {
 		return true
 	}
 
-	// We (probably) have 3 sides of a right triangle. Convert down to consistent
-	// units (feet), and fill in a default altitude for positions that don't have
-	// one.
-	prevDistFt, currDistFt := prev.Distance*FT_PER_NM, curr.Distance*FT_PER_NM
-	prevAlt, currAlt := assumeAltitude(prev), assumeAltitude(curr)
-
-	// Figure the 3D distance for each position.
-	prevDist := math.Sqrt(prevDistFt*prevDistFt + prevAlt*prevAlt)
-	currDist := math.Sqrt(currDistFt*currDistFt + currAlt*currAlt)
-
-	if currDist < prevDist {
-		return true
-	}
-
 	// Check if the old position is super old; we should replace it even if the
 	// new one is further away.
 	if time.Now().Sub(prev.Timestamp) > time.Minute {
 		return true
 	}
 
-	return false
+	prevDist, currDist := distance3D(prev), distance3D(curr)
+	if currDist >= prevDist {
+		return false
+	}
+
+	// Don't flicker between flights at similar distances: a different flight
+	// only takes over early if it is dramatically closer.
+	if curr.FlightID == prev.FlightID || time.Since(shownSince) >= p.MinHold {
+		return true
+	}
+	return currDist < prevDist*(1-p.Margin)
+}
+
+// distance3D is the straight-line distance in feet to the position.
+func distance3D(p *Position) float64 {
+	// We (probably) have 3 sides of a right triangle. Convert down to consistent
+	// units (feet), and fill in a default altitude for positions that don't have
+	// one.
+	distFt, alt := p.Distance*FT_PER_NM, assumeAltitude(p)
+	return math.Sqrt(distFt*distFt + alt*alt)
 }
 
 func assumeAltitude(p *Position) float64 {
@@ -342,40 +455,176 @@ func assumeAltitude(p *Position) float64 {
 	return 5000.0
 }
 
-func renderFlip(p Position, screen *lcd.Lcd) {
-	screen.Clear()
-	screen.ShowMessage(fmt.Sprintf("%s %s", p.Ident, p.AircraftType), lcd.SHOW_LINE_1|lcd.SHOW_BLANK_PADDING)
+// screenLines holds the text for each line of the display.
+type screenLines []string
+
+// displayUnits is how to format distances and other quantities on the screen.
+type displayUnits struct {
+	Metric            bool
+	DistancePrecision string
+}
+
+// screenFor picks which lines to show for a position. A screen with room for
+// everything shows it all at once; a smaller one alternates between the flip
+// and flop screens. If the flop screen would just repeat the flip screen, it
+// stays on the flip screen.
+func screenFor(p Position, lines int, flip bool, units displayUnits) screenLines {
+	if lines >= len(fullLines(p, units)) {
+		return fullLines(p, units)
+	}
+	if !flip && hasRoute(p) {
+		return flopLines(p, units)
+	}
+	return flipLines(p, units)
+}
+
+// scrollState is which of the flip and flop screens is being shown for a
+// flight, and how far its long lines have scrolled.
+type scrollState struct {
+	flightID string
+	flip     bool
+	// flipped is when we last switched between the flip and flop screens, and
+	// offset is how far lines too long for the screen have scrolled since.
+	flipped time.Time
+	offset  int
+}
+
+// next returns the lines to show for a position on the next tick, switching
+// between the flip and flop screens only once any long lines have finished
+// scrolling so that they can be read in full. A different flight starts over
+// from the beginning of its first screen.
+func (s *scrollState) next(p Position, lines, width int, units displayUnits, now time.Time) screenLines {
+	if p.FlightID != s.flightID {
+		*s = scrollState{flightID: p.FlightID}
+	}
+	shown := screenFor(p, lines, s.flip, units)
+	if now.Sub(s.flipped) >= FlipInterval && scrolled(shown, width, s.offset) {
+		s.flip = !s.flip
+		s.flipped = now
+		s.offset = 0
+		shown = screenFor(p, lines, s.flip, units)
+	} else {
+		s.offset++
+	}
+	return scrollLines(shown, width, s.offset)
+}
+
+// scrollLines shows the part of each line visible after scrolling offset
+// characters. Lines short enough to fit are left alone, and long ones pause at
+// each end for ScrollPause ticks.
+func scrollLines(lines screenLines, width int, offset int) screenLines {
+	scrolled := make(screenLines, len(lines))
+	for i, line := range lines {
+		text := []rune(line)
+		if len(text) <= width {
+			scrolled[i] = line
+			continue
+		}
+		start := min(max(offset-ScrollPause, 0), len(text)-width)
+		scrolled[i] = string(text[start : start+width])
+	}
+	return scrolled
+}
+
+// scrolled reports whether every line has been scrolled through to its end
+// and held there for ScrollPause ticks.
+func scrolled(lines screenLines, width int, offset int) bool {
+	for _, line := range lines {
+		if n := len([]rune(line)); n > width && offset < n-width+2*ScrollPause {
+			return false
+		}
+	}
+	return true
+}
+
+// renderLines shows the lines on the screen. Each line is padded out to the
+// width of the screen, so there is no need to clear it first, which would
+// make scrolling flicker.
+func renderLines(lines screenLines, screen Display) {
+	for i, line := range lines {
+		screen.ShowLine(i, line)
+	}
+	screen.Flush()
+	screen.On()
+}
+
+// flipLines shows the flight's position relative to us.
+func flipLines(p Position, units displayUnits) screenLines {
+	return screenLines{
+		fmt.Sprintf("%s %s", p.Ident, p.AircraftType),
+		positionLine(p, units),
+	}
+}
+
+// positionLine formats the flight's distance, direction, and altitude.
+func positionLine(p Position, units displayUnits) string {
+	decimals := precision.Decimals(p.Distance, units.DistancePrecision)
+	dist := fmt.Sprintf("%1.*fnm", decimals, p.Distance)
+	if units.Metric {
+		dist = fmt.Sprintf("%1.*fkm", decimals, p.Distance*unit.KMPerNM)
+	}
 	var alt string
 	if p.Altitude != nil {
 		alt = fmt.Sprintf("%03.0f", *p.Altitude/100)
+		if units.Metric {
+			alt = fmt.Sprintf("%.0fm", *p.Altitude*unit.MetersPerFoot)
+		}
 	}
-	screen.ShowMessage(fmt.Sprintf("%1.1fnm %s %s", p.Distance, cardinalDirection(p.Bearing), alt), lcd.SHOW_LINE_2|lcd.SHOW_BLANK_PADDING)
-	screen.BacklightOn()
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s", dist, cardinalDirection(p.Bearing), alt))
 }
 
-func renderFlop(p Position, screen *lcd.Lcd) {
-	if !isAirport(p.Origin) && !isAirport(p.Destination) {
-		renderFlip(p, screen)
-		return
+// flopLines shows the flight's route, falling back to its position if we don't
+// know anything about the route.
+func flopLines(p Position, units displayUnits) screenLines {
+	if !hasRoute(p) {
+		return flipLines(p, units)
 	}
+	return screenLines{
+		fmt.Sprintf("%s %s", p.Ident, p.AircraftType),
+		routeLine(p),
+	}
+}
 
-	screen.Clear()
-	screen.ShowMessage(fmt.Sprintf("%s %s", p.Ident, p.AircraftType), lcd.SHOW_LINE_1|lcd.SHOW_BLANK_PADDING)
-
+// routeLine formats the flight's origin and destination.
+func routeLine(p Position) string {
 	orig, dest := p.Origin, p.Destination
-	if !isAirport(orig) {
+	if !airport.IsCode(orig) {
 		orig = "????"
 	}
-	if !isAirport(dest) {
+	if !airport.IsCode(dest) {
 		dest = "????"
 	}
+	return fmt.Sprintf("%s-%s", orig, dest)
+}
 
-	screen.ShowMessage(fmt.Sprintf("%s-%s", orig, dest), lcd.SHOW_LINE_2|lcd.SHOW_BLANK_PADDING)
-	screen.BacklightOn()
+// fullLines shows everything we know about the flight, for a screen large
+// enough to do so without alternating. Unknown routes are left blank.
+func fullLines(p Position, units displayUnits) screenLines {
+	var route string
+	if hasRoute(p) {
+		route = routeLine(p)
+	}
+	var speed string
+	if p.Speed != nil {
+		speed = fmt.Sprintf("%.0fkts", *p.Speed)
+		if units.Metric {
+			speed = fmt.Sprintf("%.0fkm/h", *p.Speed*unit.KPHPerKnot)
+		}
+	}
+	var heading string
+	if p.Heading != nil {
+		heading = unit.FormatBearing(*p.Heading)
+	}
+	return screenLines{
+		strings.TrimSpace(fmt.Sprintf("%s %s", p.Ident, p.AircraftType)),
+		positionLine(p, units),
+		route,
+		strings.TrimSpace(fmt.Sprintf("%s %s", speed, heading)),
+	}
 }
 
-// Check whether the given string is an airport. It needs to be non-blank and
-// at most 4 characters long (ICAO aerodrome).
-func isAirport(s string) bool {
-	return s != "" && len(s) <= 4
+// hasRoute reports whether we know either end of the flight's route, i.e.
+// whether the flop screen has anything different to show.
+func hasRoute(p Position) bool {
+	return airport.IsCode(p.Origin) || airport.IsCode(p.Destination)
 }
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFullLines(t *testing.T) {
+	alt, speed, heading := 2500.0, 140.0, 359.6
+	tests := []struct {
+		name  string
+		pos   Position
+		units displayUnits
+		exp   screenLines
+	}{
+		{
+			name: "everything",
+			pos:  Position{Ident: "N12345", AircraftType: "C172", Origin: "KBOS", Destination: "KJFK", Distance: 1.52, Bearing: 90, Altitude: &alt, Speed: &speed, Heading: &heading},
+			exp:  screenLines{"N12345 C172", "1.5nm E 025", "KBOS-KJFK", "140kts 000"},
+		},
+		{
+			name:  "metric",
+			pos:   Position{Ident: "N12345", AircraftType: "C172", Distance: 1, Bearing: 180, Altitude: &alt, Speed: &speed},
+			units: displayUnits{Metric: true},
+			exp:   screenLines{"N12345 C172", "1.9km S 762m", "", "259km/h"},
+		},
+		{
+			name:  "adaptive precision",
+			pos:   Position{Ident: "N12345", Distance: 0.456, Bearing: 90},
+			units: displayUnits{DistancePrecision: "adaptive"},
+			exp:   screenLines{"N12345", "0.46nm E", "", ""},
+		},
+		{
+			name: "missing fields",
+			pos:  Position{Ident: "N12345", Distance: 2, Bearing: 0, Heading: &heading},
+			exp:  screenLines{"N12345", "2.0nm N", "", "000"},
+		},
+		{
+			name: "unknown origin",
+			pos:  Position{Ident: "DAL1", AircraftType: "B738", Origin: "L 42.36 -71.01", Destination: "KATL", Distance: 0.5, Bearing: 45},
+			exp:  screenLines{"DAL1 B738", "0.5nm NE", "????-KATL", ""},
+		},
+		{
+			name: "exactly the width",
+			pos:  Position{Ident: "LONGCALLSIGN123", AircraftType: "B77W", Distance: 12.34, Bearing: 270, Altitude: &alt},
+			exp:  screenLines{"LONGCALLSIGN123 B77W", "12.3nm W 025", "", ""},
+		},
+		{
+			name: "past the width",
+			pos:  Position{Ident: "LONGCALLSIGN1234", AircraftType: "B77W", Distance: 12.34, Bearing: 270},
+			exp:  screenLines{"LONGCALLSIGN1234 B77W", "12.3nm W", "", ""},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := fullLines(test.pos, test.units); !slices.Equal(actual, test.exp) {
+				t.Errorf("expected %q, got %q", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestScrollLines(t *testing.T) {
+	// The long line is 4 characters wider than the screen, so it pauses for
+	// ScrollPause ticks, scrolls for 4, and holds at the end for ScrollPause
+	// more before it counts as scrolled.
+	lines := screenLines{"0123456789AB", "short"}
+	tests := []struct {
+		offset   int
+		exp      screenLines
+		scrolled bool
+	}{
+		{0, screenLines{"01234567", "short"}, false},
+		{ScrollPause, screenLines{"01234567", "short"}, false},
+		{ScrollPause + 1, screenLines{"12345678", "short"}, false},
+		{ScrollPause + 4, screenLines{"456789AB", "short"}, false},
+		{2*ScrollPause + 3, screenLines{"456789AB", "short"}, false},
+		{2*ScrollPause + 4, screenLines{"456789AB", "short"}, true},
+		{2*ScrollPause + 10, screenLines{"456789AB", "short"}, true},
+	}
+	for _, test := range tests {
+		t.Run(strconv.Itoa(test.offset), func(t *testing.T) {
+			if actual := scrollLines(lines, 8, test.offset); !slices.Equal(actual, test.exp) {
+				t.Errorf("expected %q, got %q", test.exp, actual)
+			}
+			if actual := scrolled(lines, 8, test.offset); actual != test.scrolled {
+				t.Errorf("expected scrolled to be %t, got %t", test.scrolled, actual)
+			}
+		})
+	}
+
+	// Lines which fit are never scrolled, so they are done straight away.
+	short := screenLines{"N12345", "C172"}
+	if actual := scrollLines(short, 8, 5); !slices.Equal(actual, short) {
+		t.Errorf("expected short lines to stay put, got %q", actual)
+	}
+	if !scrolled(short, 8, 0) {
+		t.Error("expected short lines to count as scrolled immediately")
+	}
+}
+
+func TestScrollStateNewFlight(t *testing.T) {
+	now := time.Now()
+	first := Position{FlightID: "A", Ident: "LONGCALLSIGN1234", AircraftType: "B77W", Origin: "KBOS", Destination: "KJFK"}
+	var scroll scrollState
+	for i := 0; i < ScrollPause+3; i++ {
+		scroll.next(first, 2, 16, displayUnits{}, now)
+	}
+	if lines := scroll.next(first, 2, 16, displayUnits{}, now); lines[0] != "CALLSIGN1234 B77" {
+		t.Fatalf("expected the first flight to be part way through scrolling, got %q", lines)
+	}
+
+	// A different flight starts from the beginning of its first screen
+	// rather than part way through scrolling the previous flight's.
+	second := Position{FlightID: "B", Ident: "SCROLLINGCALLSIGN", AircraftType: "A320", Origin: "KBOS", Destination: "KJFK"}
+	exp := screenLines{"SCROLLINGCALLSIG", "KBOS-KJFK"}
+	if lines := scroll.next(second, 2, 16, displayUnits{}, now); !slices.Equal(lines, exp) {
+		t.Errorf("expected %q, got %q", exp, lines)
+	}
+}
+
+func TestShouldReplace(t *testing.T) {
+	policy := replacePolicy{MinHold: 10 * time.Second, Margin: 0.25}
+	now := time.Now()
+	pos := func(id string, nm float64, age time.Duration) *Position {
+		var ground float64
+		return &Position{FlightID: id, Distance: nm, Altitude: &ground, Timestamp: now.Add(-age)}
+	}
+	tests := []struct {
+		name       string
+		prev, curr *Position
+		shownFor   time.Duration
+		exp        bool
+	}{
+		{"nothing shown", nil, pos("A", 5, 0), 0, true},
+		{"stale previous flight", pos("A", 1, 2*time.Minute), pos("B", 5, 0), 0, true},
+		{"same flight closer", pos("A", 2, 0), pos("A", 1.9, 0), 0, true},
+		{"same flight further", pos("A", 2, 0), pos("A", 2.1, 0), 0, false},
+		{"marginally closer within hold", pos("A", 2, 0), pos("B", 1.8, 0), time.Second, false},
+		{"dramatically closer within hold", pos("A", 2, 0), pos("B", 1, 0), time.Second, true},
+		{"marginally closer after hold", pos("A", 2, 0), pos("B", 1.9, 0), 20 * time.Second, true},
+		{"further after hold", pos("A", 2, 0), pos("B", 2.5, 0), 20 * time.Second, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := policy.shouldReplace(test.prev, test.curr, now.Add(-test.shownFor)); actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+		})
+	}
+}
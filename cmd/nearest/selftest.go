@@ -0,0 +1,35 @@
+package main
+
+import "fmt"
+
+// selfTest shows a known message on the display, so that the display can be
+// checked before connecting to Firehose.
+func (a *App) selfTest() error {
+	screen, err := a.setupDisplay()
+	if err != nil {
+		return fmt.Errorf("could not set up %s display on I2C bus %d at address %#02x: %w", a.DisplayType, a.I2CBus, a.I2CAddress, err)
+	}
+	return showSelfTest(screen)
+}
+
+// showSelfTest writes the self test message to the screen, returning the first
+// error the screen reports.
+func showSelfTest(screen Display) error {
+	if err := screen.On(); err != nil {
+		return fmt.Errorf("could not turn on display: %w", err)
+	}
+	if err := screen.Clear(); err != nil {
+		return fmt.Errorf("could not clear display: %w", err)
+	}
+	for i, text := range []string{"overhead", "self test OK"} {
+		if err := screen.ShowLine(i, text); err != nil {
+			return fmt.Errorf("could not write to display: %w", err)
+		}
+	}
+	// Buffered displays show nothing, and report no errors from the bus,
+	// until they are flushed.
+	if err := screen.Flush(); err != nil {
+		return fmt.Errorf("could not write to display: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// fakeDisplay records the lines shown on it, and which have been flushed.
+type fakeDisplay struct {
+	lines, flushed []string
+	flushErr       error
+}
+
+func (d *fakeDisplay) On() error  { return nil }
+func (d *fakeDisplay) Off() error { return nil }
+func (d *fakeDisplay) Lines() int { return 2 }
+func (d *fakeDisplay) Width() int { return 16 }
+
+func (d *fakeDisplay) Clear() error {
+	d.lines = make([]string, d.Lines())
+	return nil
+}
+
+func (d *fakeDisplay) ShowLine(line int, text string) error {
+	d.lines[line] = text
+	return nil
+}
+
+func (d *fakeDisplay) Flush() error {
+	if d.flushErr != nil {
+		return d.flushErr
+	}
+	d.flushed = slices.Clone(d.lines)
+	return nil
+}
+
+func TestShowSelfTest(t *testing.T) {
+	screen := &fakeDisplay{}
+	if err := showSelfTest(screen); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(screen.flushed, []string{"overhead", "self test OK"}) {
+		t.Errorf("expected the self test message to be flushed, got %q", screen.flushed)
+	}
+
+	errBus := errors.New("i2c write failed")
+	screen = &fakeDisplay{flushErr: errBus}
+	if err := showSelfTest(screen); !errors.Is(err, errBus) {
+		t.Errorf("expected the flush error, got %v", err)
+	}
+}
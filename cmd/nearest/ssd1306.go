@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+
+	"github.com/d2r2/go-i2c"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// SSD1306Width is the width in pixels of SSD1306 displays.
+	SSD1306Width = 128
+	// SSD1306Lines is how many lines of text we show, matching the LCD.
+	SSD1306Lines = 2
+)
+
+// ssd1306CharWidth is the width in pixels of each character of the font.
+const ssd1306CharWidth = 7
+
+// SSD1306 control bytes, which prefix each I2C write.
+const (
+	ssd1306Command = 0x00
+	ssd1306Data    = 0x40
+)
+
+// ssd1306 is a monochrome OLED display driven over I2C. Text is drawn into an
+// in-memory image which Flush then copies to the display in full.
+type ssd1306 struct {
+	bus *i2c.I2C
+	img *image.Gray
+}
+
+func newSSD1306(bus *i2c.I2C, width, height int) (*ssd1306, error) {
+	if height != 32 && height != 64 {
+		return nil, fmt.Errorf("SSD1306 height must be 32 or 64, not %d", height)
+	}
+	comPins := byte(0x12)
+	if height == 32 {
+		comPins = 0x02
+	}
+	d := &ssd1306{bus: bus, img: image.NewGray(image.Rect(0, 0, width, height))}
+	err := d.command(
+		0xAE,       // display off
+		0xD5, 0x80, // clock divide ratio and oscillator frequency
+		0xA8, byte(height-1), // multiplex ratio
+		0xD3, 0x00, // no display offset
+		0x40,       // start line 0
+		0x8D, 0x14, // enable charge pump
+		0x20, 0x00, // horizontal addressing mode
+		0xA1,          // map column 127 to SEG0
+		0xC8,          // scan COM outputs in reverse
+		0xDA, comPins, // COM pins hardware configuration
+		0x81, 0xCF, // contrast
+		0xD9, 0xF1, // pre-charge period
+		0xDB, 0x40, // VCOMH deselect level
+		0xA4, // display follows RAM
+		0xA6, // not inverted
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize SSD1306: %w", err)
+	}
+	d.Clear()
+	return d, d.Flush()
+}
+
+func (d *ssd1306) command(cmds ...byte) error {
+	_, err := d.bus.WriteBytes(append([]byte{ssd1306Command}, cmds...))
+	return err
+}
+
+func (d *ssd1306) On() error  { return d.command(0xAF) }
+func (d *ssd1306) Off() error { return d.command(0xAE) }
+func (d *ssd1306) Lines() int { return SSD1306Lines }
+func (d *ssd1306) Width() int { return d.img.Bounds().Dx() / ssd1306CharWidth }
+
+func (d *ssd1306) Clear() error {
+	draw.Draw(d.img, d.img.Bounds(), image.Black, image.Point{}, draw.Src)
+	return nil
+}
+
+// ShowLine draws the text vertically centered in its share of the display.
+func (d *ssd1306) ShowLine(line int, text string) error {
+	if line < 0 || line >= SSD1306Lines {
+		return fmt.Errorf("no line %d", line)
+	}
+	bounds := d.img.Bounds()
+	lineHeight := bounds.Dy() / SSD1306Lines
+	band := image.Rect(0, line*lineHeight, bounds.Dx(), (line+1)*lineHeight)
+	draw.Draw(d.img, band, image.Black, image.Point{}, draw.Src)
+
+	face := basicfont.Face7x13
+	baseline := band.Min.Y + (lineHeight+face.Ascent-face.Descent)/2
+	drawer := font.Drawer{
+		Dst:  d.img,
+		Src:  image.White,
+		Face: face,
+		Dot:  fixed.P(0, baseline),
+	}
+	drawer.DrawString(text)
+	return nil
+}
+
+// Flush copies the image to the display, one page at a time.
+func (d *ssd1306) Flush() error {
+	bounds := d.img.Bounds()
+	pages := packPages(d.img)
+	if err := d.command(0x21, 0, byte(bounds.Dx()-1), 0x22, 0, byte(len(pages)-1)); err != nil {
+		return err
+	}
+	for _, page := range pages {
+		if _, err := d.bus.WriteBytes(append([]byte{ssd1306Data}, page...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// packPages converts an image to the display's memory layout: a page for each
+// band of 8 rows, in which each byte covers a column of 8 vertical pixels with
+// the least significant bit at the top.
+func packPages(img *image.Gray) [][]byte {
+	bounds := img.Bounds()
+	pages := make([][]byte, bounds.Dy()/8)
+	for page := range pages {
+		pages[page] = make([]byte, bounds.Dx())
+		for x := range pages[page] {
+			for bit := 0; bit < 8; bit++ {
+				if img.GrayAt(x, page*8+bit).Y >= 0x80 {
+					pages[page][x] |= 1 << bit
+				}
+			}
+		}
+	}
+	return pages
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+)
+
+func TestPackPages(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 16))
+	lit := []image.Point{
+		{0, 0},  // page 0, top bit
+		{1, 7},  // page 0, bottom bit
+		{2, 0},  // page 0, both ends of the column
+		{2, 7},  //
+		{3, 8},  // page 1, top bit
+		{0, 12}, // page 1, middle
+		{1, 15}, // page 1, bottom bit
+	}
+	for _, p := range lit {
+		img.SetGray(p.X, p.Y, color.Gray{Y: 0xFF})
+	}
+	// Dim pixels are off.
+	img.SetGray(3, 0, color.Gray{Y: 0x7F})
+
+	want := [][]byte{
+		{0x01, 0x80, 0x81, 0x00},
+		{0x10, 0x80, 0x00, 0x01},
+	}
+	got := packPages(img)
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("expected pages %x, got %x", want, got)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"log"
+
+	"overhead/internal/statusfile"
+)
+
+// updateStatus writes the position to the status file, if there is one, or an
+// empty object if pos is nil.
+func updateStatus(path string, pos *Position) {
+	if path == "" {
+		return
+	}
+	var status any = struct{}{}
+	if pos != nil {
+		status = pos
+	}
+	if err := statusfile.Write(path, status); err != nil {
+		log.Printf("could not write status file: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Settings for coloring the terminal output.
+const (
+	// ColorAuto colors output when stdout is a terminal and NO_COLOR isn't
+	// set.
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// ANSI escape codes for the styles we use.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+)
+
+// emergencySquawks are the transponder codes for a hijacking, radio failure,
+// and a general emergency.
+var emergencySquawks = map[string]bool{"7500": true, "7600": true, "7700": true}
+
+func validateColor(setting string) error {
+	switch setting {
+	case ColorAuto, ColorAlways, ColorNever:
+		return nil
+	}
+	return fmt.Errorf("unknown color %q; must be %s, %s, or %s", setting, ColorAuto, ColorAlways, ColorNever)
+}
+
+// useColor decides whether to color output written to f.
+func useColor(setting string, f *os.File) bool {
+	switch setting {
+	case ColorAlways:
+		return true
+	case ColorAuto:
+		if _, ok := os.LookupEnv("NO_COLOR"); ok {
+			return false
+		}
+		info, err := f.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+	return false
+}
+
+// paint wraps s in the ANSI style if color is enabled, and otherwise leaves it
+// as it is.
+func (a *App) paint(s, style string) string {
+	if !a.color || style == "" {
+		return s
+	}
+	return style + s + ansiReset
+}
+
+// identStyle picks the style in which to show a flight's ident: red for an
+// emergency, and yellow for a flight heading toward us.
+func identStyle(pos *Position) string {
+	if emergencySquawks[pos.Squawk] {
+		return ansiBold + ansiRed
+	}
+	if state, ok := approachState(pos); ok && state == Approaching && !pos.Departed {
+		return ansiYellow
+	}
+	return ""
+}
+
+// distanceStyle highlights flights within a mile of us.
+func distanceStyle(nm float64) string {
+	if nm < 1 {
+		return ansiBold
+	}
+	return ""
+}
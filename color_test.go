@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPaint(t *testing.T) {
+	app := &App{}
+	if actual := app.paint("UAL641", ansiRed); actual != "UAL641" {
+		t.Errorf("expected no color when disabled, got %q", actual)
+	}
+	app.color = true
+	if actual := app.paint("UAL641", ansiRed); actual != "\x1b[31mUAL641\x1b[0m" {
+		t.Errorf("unexpected colored text %q", actual)
+	}
+	if actual := app.paint("UAL641", ""); actual != "UAL641" {
+		t.Errorf("expected no style to leave the text alone, got %q", actual)
+	}
+}
+
+func TestIdentStyle(t *testing.T) {
+	toward, away := 180.0, 0.0
+	tests := []struct {
+		name string
+		pos  Position
+		exp  string
+	}{
+		{"emergency", Position{Squawk: "7700", Heading: &toward}, ansiBold + ansiRed},
+		{"approaching", Position{Squawk: "1200", Heading: &toward}, ansiYellow},
+		{"departing", Position{Heading: &away}, ""},
+		{"no heading", Position{}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := identStyle(&test.pos); actual != test.exp {
+				t.Errorf("expected %q but got %q", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if useColor(ColorAuto, f) {
+		t.Error("expected no color for a file")
+	}
+	if !useColor(ColorAlways, f) {
+		t.Error("expected color when always enabled")
+	}
+	if useColor(ColorNever, f) {
+		t.Error("expected no color when disabled")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// compass16 lists the points of a 16-point compass clockwise from north. The
+// principal eight are spelled out as cardinalDirection does, and the points
+// between them are abbreviated.
+var compass16 = []string{
+	"north", "NNE", "northeast", "ENE",
+	"east", "ESE", "southeast", "SSE",
+	"south", "SSW", "southwest", "WSW",
+	"west", "WNW", "northwest", "NNW",
+}
+
+// compassWords are how the letters of an abbreviated compass point are spoken.
+var compassWords = map[rune]string{
+	'N': "north",
+	'E': "east",
+	'S': "south",
+	'W': "west",
+}
+
+func validateCompassPoints(points int) error {
+	switch points {
+	case 8, 16:
+		return nil
+	}
+	return fmt.Errorf("compass-points must be 8 or 16, not %d", points)
+}
+
+// direction describes the bearing for display using the configured number of
+// compass points, e.g. "northeast" or "NNE".
+func (a *App) direction(bearing float64) string {
+	if a.CompassPoints != 16 {
+		return cardinalDirection(bearing)
+	}
+	return sixteenPointDirection(bearing)
+}
+
+// spokenDirection describes the bearing as it should be announced, with
+// abbreviated points spelled out, e.g. "north north east".
+func (a *App) spokenDirection(bearing float64) string {
+	dir := a.direction(bearing)
+	if strings.ToUpper(dir) != dir {
+		return dir
+	}
+	var words []string
+	for _, r := range dir {
+		words = append(words, compassWords[r])
+	}
+	return strings.Join(words, " ")
+}
+
+// sixteenPointDirection returns the point of a 16-point compass nearest to the
+// bearing. Each point covers 11.25° either side of it, and like
+// cardinalDirection a bearing exactly on a boundary belongs to the point
+// counterclockwise of it.
+func sixteenPointDirection(bearing float64) string {
+	bearing = math.Mod(bearing, 360)
+	if bearing < 0 {
+		bearing += 360
+	}
+	i := int(math.Ceil((bearing-11.25)/22.5)) % len(compass16)
+	return compass16[i]
+}
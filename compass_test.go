@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestDirection(t *testing.T) {
+	tests := []struct {
+		bearing float64
+		eight   string
+		sixteen string
+		spoken  string
+	}{
+		{0, "north", "north", "north"},
+		{11.25, "north", "north", "north"},
+		{22.5, "north", "NNE", "north north east"},
+		{33.75, "northeast", "NNE", "north north east"},
+		{45, "northeast", "northeast", "northeast"},
+		{56.25, "northeast", "northeast", "northeast"},
+		{67.5, "northeast", "ENE", "east north east"},
+		{78.75, "east", "ENE", "east north east"},
+		{90, "east", "east", "east"},
+		{101.25, "east", "east", "east"},
+		{112.5, "east", "ESE", "east south east"},
+		{123.75, "southeast", "ESE", "east south east"},
+		{135, "southeast", "southeast", "southeast"},
+		{146.25, "southeast", "southeast", "southeast"},
+		{157.5, "southeast", "SSE", "south south east"},
+		{168.75, "south", "SSE", "south south east"},
+		{180, "south", "south", "south"},
+		{191.25, "south", "south", "south"},
+		{202.5, "south", "SSW", "south south west"},
+		{213.75, "southwest", "SSW", "south south west"},
+		{225, "southwest", "southwest", "southwest"},
+		{236.25, "southwest", "southwest", "southwest"},
+		{247.5, "southwest", "WSW", "west south west"},
+		{258.75, "west", "WSW", "west south west"},
+		{270, "west", "west", "west"},
+		{281.25, "west", "west", "west"},
+		{292.5, "west", "WNW", "west north west"},
+		{303.75, "northwest", "WNW", "west north west"},
+		{315, "northwest", "northwest", "northwest"},
+		{326.25, "northwest", "northwest", "northwest"},
+		{337.5, "northwest", "NNW", "north north west"},
+		{348.75, "north", "NNW", "north north west"},
+		{348.8, "north", "north", "north"},
+	}
+	eight := &App{}
+	sixteen := &App{CompassPoints: 16}
+	for _, test := range tests {
+		if actual := eight.direction(test.bearing); actual != test.eight {
+			t.Errorf("8 points at %.2f: expected %q, got %q", test.bearing, test.eight, actual)
+		}
+		if actual := sixteen.direction(test.bearing); actual != test.sixteen {
+			t.Errorf("16 points at %.2f: expected %q, got %q", test.bearing, test.sixteen, actual)
+		}
+		if actual := sixteen.spokenDirection(test.bearing); actual != test.spoken {
+			t.Errorf("spoken 16 points at %.2f: expected %q, got %q", test.bearing, test.spoken, actual)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"overhead/internal/precision"
+	"overhead/internal/unit"
+	"overhead/internal/validate"
+)
+
+// validateConfig checks the alerting, source, and sink settings of an App
+// before it runs, returning every problem found rather than just the first so
+// they can all be fixed at once.
+func validateConfig(a *App, allowNullIsland bool) []error {
+	var problems []error
+	check := func(err error) {
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	check(validateLocationSource(a))
+	if !a.isMoving() {
+		check(validate.Location(a.Latitude, a.Longitude, allowNullIsland))
+	}
+	check(validateSource(a.Source))
+	if a.Source == FirehoseSource && a.ReplayFile == "" && (a.Username == "" || a.Password == "") {
+		check(errors.New("username and password are required to connect to Firehose"))
+	}
+	if a.Source == Dump1090Source && a.Dump1090Interval <= 0 {
+		check(errors.New("dump1090-interval must be positive"))
+	}
+	if a.ObservationBox != nil {
+		if err := validateRectangle(*a.ObservationBox); err != nil {
+			check(fmt.Errorf("invalid observation-box: %w", err))
+		}
+	}
+	if len(a.Geofence) > 0 {
+		if err := a.Geofence.Validate(); err != nil {
+			check(fmt.Errorf("invalid geofence: %w", err))
+		}
+	}
+	if a.InterestingRadiusNM < 0 {
+		check(errors.New("interesting-radius must not be negative"))
+	} else if a.InterestingRadiusNM == 0 && a.ObservationBox == nil && len(a.Geofence) == 0 {
+		check(errors.New("an interesting-radius of 0 requires an observation-box or geofence to be configured"))
+	}
+	if a.InterestingRadiusNM > 0 && a.AlertRadiusNM > a.InterestingRadiusNM {
+		check(fmt.Errorf("alert-radius of %.1fnm exceeds interesting-radius of %.1fnm, so flights would be dropped before they could alert; raise interesting-radius or lower alert-radius", a.AlertRadiusNM, a.InterestingRadiusNM))
+	}
+	if a.InterestingFloorFt > a.InterestingCeilingFt {
+		check(errors.New("interesting-floor must not be above interesting-ceiling"))
+	}
+	if a.MaxSpeedKts > 0 && a.MinSpeedKts > a.MaxSpeedKts {
+		check(errors.New("min-speed must not exceed max-speed"))
+	}
+	if a.TTSTimeout <= 0 {
+		check(errors.New("tts-timeout must be positive"))
+	}
+	check(validateWatchlistMode(a.WatchlistMode))
+	check(validateRouteWatchlist(a.RouteWatchlist))
+	check(validateAircraftFilter(a.AircraftFilter, a.AircraftFilterMode))
+	check(validateCompassPoints(a.CompassPoints))
+	check(unit.Validate(a.Units))
+	check(precision.Validate(a.DistancePrecision))
+	check(validateOutputFormat(a.OutputFormat))
+	check(validateColor(a.Color))
+	switch a.SpokenDistanceStyle {
+	case PreciseDistance, FriendlyDistance:
+	default:
+		check(fmt.Errorf("unknown spoken-distance-style %q", a.SpokenDistanceStyle))
+	}
+	switch a.DistanceSpeech {
+	case PhoneticDistanceSpeech, NaturalDistanceSpeech:
+	default:
+		check(fmt.Errorf("unknown distance-speech %q; must be %s or %s", a.DistanceSpeech, PhoneticDistanceSpeech, NaturalDistanceSpeech))
+	}
+	switch a.AlertOn {
+	case AlertOnApproach, AlertOnDepart, AlertOnBoth:
+	default:
+		check(fmt.Errorf("unknown alert-on %q; must be %s, %s, or %s", a.AlertOn, AlertOnApproach, AlertOnDepart, AlertOnBoth))
+	}
+	if a.TrailLength < 1 {
+		check(errors.New("trail-length must be at least 1"))
+	}
+	if a.TTSRate <= 0 {
+		check(errors.New("tts-rate must be positive"))
+	}
+	if a.TTSMaxAge < 0 {
+		check(errors.New("tts-max-age must not be negative"))
+	}
+	switch a.PhoneticStyle {
+	case StandardPhonetics, AviationPhonetics:
+	default:
+		check(fmt.Errorf("unknown phonetic-style %q", a.PhoneticStyle))
+	}
+	switch a.AltitudeMode {
+	case AltitudeModeMSL, AltitudeModeRelative:
+	default:
+		check(fmt.Errorf("unknown altitude-mode %q", a.AltitudeMode))
+	}
+	return problems
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benburwell/firehose"
+)
+
+func TestValidateConfig(t *testing.T) {
+	valid := func() *App {
+		return &App{
+			Source:               FirehoseSource,
+			Username:             "user",
+			Password:             "secret",
+			Latitude:             42.36,
+			Longitude:            -71.01,
+			InterestingRadiusNM:  10,
+			InterestingCeilingFt: 15000,
+			AlertRadiusNM:        3,
+			WatchlistMode:        WatchlistAlso,
+			AircraftFilterMode:   AircraftFilterExclude,
+			CompassPoints:        8,
+			Units:                ImperialUnits,
+			DistancePrecision:    "1",
+			SpokenDistanceStyle:  PreciseDistance,
+			TTSTimeout:           30 * time.Second,
+			DistanceSpeech:       PhoneticDistanceSpeech,
+			LocationSource:       StaticLocation,
+			AltitudeMode:         AltitudeModeMSL,
+			TTSRate:              DefaultTTSRate,
+			TrailLength:          1,
+			AlertOn:              AlertOnApproach,
+			OutputFormat:         TextOutput,
+			PhoneticStyle:        StandardPhonetics,
+			Color:                ColorAuto,
+		}
+	}
+	tests := []struct {
+		name     string
+		modify   func(*App)
+		problems []string
+	}{
+		{"valid", func(a *App) {}, nil},
+		{"unset location", func(a *App) { a.Latitude, a.Longitude = 0, 0 }, []string{"latitude and longitude are both 0"}},
+		{"out of range", func(a *App) { a.Latitude = 142 }, []string{"latitude 142"}},
+		{"alert radius too big", func(a *App) { a.AlertRadiusNM = 12 }, []string{"exceeds interesting-radius"}},
+		{"no credentials", func(a *App) { a.Password = "" }, []string{"username and password are required"}},
+		{"no credentials for dump1090", func(a *App) {
+			a.Username, a.Password, a.Source, a.Dump1090Interval = "", "", Dump1090Source, time.Second
+		}, nil},
+		{"no credentials for replay", func(a *App) { a.Username, a.Password, a.ReplayFile = "", "", "positions.ndjson" }, nil},
+		{"negative interesting radius", func(a *App) { a.InterestingRadiusNM = -1 }, []string{"must not be negative"}},
+		{"zero interesting radius", func(a *App) { a.InterestingRadiusNM = 0 }, []string{"requires an observation-box or geofence"}},
+		{"zero interesting radius with box", func(a *App) {
+			a.InterestingRadiusNM = 0
+			a.ObservationBox = &firehose.Rectangle{LowLat: 42, LowLon: -72, HiLat: 43, HiLon: -71}
+		}, nil},
+		{"inverted observation box", func(a *App) {
+			a.ObservationBox = &firehose.Rectangle{LowLat: 43, LowLon: -72, HiLat: 42, HiLon: -71}
+		}, []string{"invalid observation-box"}},
+		{"inverted speed bounds", func(a *App) { a.MinSpeedKts, a.MaxSpeedKts = 300, 100 }, []string{"min-speed must not exceed max-speed"}},
+		{"no tts timeout", func(a *App) { a.TTSTimeout = 0 }, []string{"tts-timeout must be positive"}},
+		{"unknown altitude mode", func(a *App) { a.AltitudeMode = "agl" }, []string{"unknown altitude-mode"}},
+		{"several problems", func(a *App) {
+			a.Latitude, a.Longitude, a.Username, a.Units = 0, 0, "", "furlongs"
+		}, []string{"latitude and longitude", "username and password", "unknown units"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := valid()
+			test.modify(app)
+			problems := validateConfig(app, false)
+			if len(problems) != len(test.problems) {
+				t.Fatalf("expected %d problems, got %v", len(test.problems), problems)
+			}
+			for i, problem := range problems {
+				if !strings.Contains(problem.Error(), test.problems[i]) {
+					t.Errorf("expected problem %d to mention %q, got %q", i, test.problems[i], problem)
+				}
+			}
+		})
+	}
+}
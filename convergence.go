@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// ConvergenceMaxAge is how recent another flight's position must be for us
+	// to compare it against a new position.
+	ConvergenceMaxAge = time.Minute
+	// ConvergenceVerticalFt is the vertical separation beyond which flights are
+	// not considered to be converging, regardless of lateral distance.
+	ConvergenceVerticalFt = 1000.0
+)
+
+// A flightPair identifies two flights, ordered by flight ID so that a pair is
+// the same regardless of which flight we are looking from.
+type flightPair [2]string
+
+func newFlightPair(a, b string) flightPair {
+	if a > b {
+		a, b = b, a
+	}
+	return flightPair{a, b}
+}
+
+// checkConvergence compares a flight's new position against the other flights
+// within the alert radius, alerting the first time the flight closes to within
+// ConvergenceNM of one of them. Only flights within the alert radius are
+// compared, which keeps the pairwise checks cheap.
+func (a *App) checkConvergence(prev, curr *Position) {
+	if curr.Distance >= a.AlertRadiusNM {
+		return
+	}
+	for id, flight := range a.flights {
+		other := flight.last
+		if id == curr.FlightID || other.Distance >= a.AlertRadiusNM {
+			continue
+		}
+		if curr.Timestamp.Sub(other.Timestamp) > ConvergenceMaxAge {
+			continue
+		}
+		if curr.Altitude != nil && other.Altitude != nil && math.Abs(*curr.Altitude-*other.Altitude) > ConvergenceVerticalFt {
+			continue
+		}
+		before, now := prev.Point.DistNM(other.Point), curr.Point.DistNM(other.Point)
+		if now >= before || now > a.ConvergenceNM {
+			continue
+		}
+		pair := newFlightPair(curr.FlightID, id)
+		if a.converging[pair] {
+			continue
+		}
+		if a.converging == nil {
+			a.converging = make(map[flightPair]bool)
+		}
+		a.converging[pair] = true
+		a.goPending(func() { a.alertConvergence(curr, other, now) })
+	}
+}
+
+// forgetConvergences removes any recorded convergences involving the flight.
+func (a *App) forgetConvergences(flightID string) {
+	for pair := range a.converging {
+		if pair[0] == flightID || pair[1] == flightID {
+			delete(a.converging, pair)
+		}
+	}
+}
+
+func (a *App) alertConvergence(curr, other *Position, separationNM float64) {
+	if a.OutputFormat == JSONLinesOutput {
+		// Keep stdout to one kind of record.
+		slog.Info("flights converging", "flight_id", curr.FlightID, "other_flight_id", other.FlightID, "separation_nm", separationNM)
+	} else {
+		fmt.Printf("[%s] %s and %s are converging %s apart, %s to the %s\n",
+			a.formatTime(curr.Timestamp), flightName(curr), flightName(other),
+			formatDistance(separationNM, a.Units, a.DistancePrecision),
+			formatDistance(curr.Distance, a.Units, a.DistancePrecision), cardinalDirection(curr.Bearing))
+	}
+
+	if !a.canAnnounce(curr.Timestamp) {
+		return
+	}
+	var words []string
+	words = append(words, "traffic alert", ",")
+	words = append(words, a.flightNameToWords(curr)...)
+	words = append(words, "and")
+	words = append(words, a.flightNameToWords(other)...)
+	words = append(words, "converging to the", cardinalDirection(curr.Bearing))
+	a.speak(strings.Join(words, " "))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"overhead/internal/unit"
+)
+
+// DiscordColor is the color of the bar beside alert embeds.
+const DiscordColor = 0x1f8b4c
+
+// discordMessage is a message for a Discord webhook.
+// https://discord.com/developers/docs/resources/webhook#execute-webhook
+type discordMessage struct {
+	Embeds []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title     string         `json:"title"`
+	URL       string         `json:"url,omitempty"`
+	Color     int            `json:"color,omitempty"`
+	Timestamp string         `json:"timestamp,omitempty"`
+	Fields    []discordField `json:"fields,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// discordMessage builds an embed describing the position.
+func (a *App) discordMessage(pos *Position) discordMessage {
+	title := flightName(pos)
+	if pos.AircraftType != "" {
+		title += " (" + a.aircraftTypeName(pos.AircraftType) + ")"
+	}
+	if pos.Departed {
+		title += ", departing the area"
+	}
+	fields := []discordField{
+		{Name: "Distance", Value: formatDistance(pos.Distance, a.Units, a.DistancePrecision), Inline: true},
+		{Name: "Bearing", Value: fmt.Sprintf("%s° (%s)", unit.FormatBearing(pos.Bearing), a.direction(pos.Bearing)), Inline: true},
+	}
+	if pos.Altitude != nil {
+		fields = append(fields, discordField{Name: "Altitude", Value: formatAltitude(*pos.Altitude, a.Units), Inline: true})
+	}
+	if pos.Speed != nil {
+		fields = append(fields, discordField{Name: "Speed", Value: formatSpeed(*pos.Speed, a.Units), Inline: true})
+	}
+	if pos.Origin != "" || pos.Destination != "" {
+		route := strings.TrimSpace(pos.Origin + " → " + pos.Destination)
+		fields = append(fields, discordField{Name: "Route", Value: route, Inline: true})
+	}
+	return discordMessage{
+		Embeds: []discordEmbed{{
+			Title:     title,
+			URL:       flightAwareLink(pos.FlightID),
+			Color:     DiscordColor,
+			Timestamp: pos.Timestamp.UTC().Format("2006-01-02T15:04:05Z"),
+			Fields:    fields,
+		}},
+	}
+}
+
+// postDiscord queues the position to be sent to the Discord webhook, if one is
+// configured.
+func (a *App) postDiscord(pos *Position) {
+	if a.DiscordWebhookURL == "" {
+		return
+	}
+	a.queueMessage("Discord", a.DiscordWebhookURL, pos.FlightID, a.discordMessage(pos))
+}
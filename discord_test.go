@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPostDiscord(t *testing.T) {
+	received := make(chan discordMessage, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var msg discordMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Errorf("invalid message %q: %v", body, err)
+		}
+		received <- msg
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	alt, speed := 3500.0, 180.0
+	app := &App{DiscordWebhookURL: srv.URL, CompassPoints: 8}
+	app.startWebhooks()
+	defer app.stopWebhooks(time.Second)
+	app.postDiscord(&Position{
+		FlightID:     "UAL641-1720083075-fa-2029p",
+		Ident:        "UAL641",
+		AircraftType: "B738",
+		Origin:       "KBOS",
+		Destination:  "KORD",
+		Distance:     2.4,
+		Bearing:      45,
+		Altitude:     &alt,
+		Speed:        &speed,
+		Timestamp:    time.Unix(1720083075, 0),
+	})
+
+	msg := <-received
+	if len(msg.Embeds) != 1 {
+		t.Fatalf("expected one embed, got %d", len(msg.Embeds))
+	}
+	embed := msg.Embeds[0]
+	if embed.Title != "UAL641 (Boeing 737-800)" {
+		t.Errorf("unexpected title: %s", embed.Title)
+	}
+	if embed.URL != "https://www.flightaware.com/live/flight/id/UAL641-1720083075-fa-2029p" {
+		t.Errorf("unexpected URL: %s", embed.URL)
+	}
+	fields := make(map[string]string)
+	for _, f := range embed.Fields {
+		fields[f.Name] = f.Value
+	}
+	expected := map[string]string{
+		"Distance": "2.4nm",
+		"Bearing":  "045° (northeast)",
+		"Altitude": "3500ft",
+		"Speed":    "180kts",
+		"Route":    "KBOS → KORD",
+	}
+	for name, value := range expected {
+		if fields[name] != value {
+			t.Errorf("%s: expected %q, got %q", name, value, fields[name])
+		}
+	}
+}
+
+func TestDiscordDeparture(t *testing.T) {
+	app := &App{CompassPoints: 8}
+	pos := &Position{FlightID: "UAL641-1", Ident: "UAL641", AircraftType: "B738", Distance: 3, Timestamp: time.Unix(0, 0)}
+	if title := app.discordMessage(pos).Embeds[0].Title; title != "UAL641 (Boeing 737-800)" {
+		t.Errorf("unexpected title: %s", title)
+	}
+	pos.Departed = true
+	if title := app.discordMessage(pos).Embeds[0].Title; title != "UAL641 (Boeing 737-800), departing the area" {
+		t.Errorf("unexpected departure title: %s", title)
+	}
+}
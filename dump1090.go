@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/skypies/geo"
+)
+
+// Sources of position data.
+const (
+	FirehoseSource = "firehose"
+	Dump1090Source = "dump1090"
+)
+
+const (
+	// Dump1090Timeout bounds each request for aircraft.json.
+	Dump1090Timeout = 5 * time.Second
+	// Dump1090MaxAge is how long ago an aircraft's position may have been
+	// received for us to still use it. dump1090 keeps listing aircraft for a
+	// while after it stops hearing them.
+	Dump1090MaxAge = time.Minute
+	// Dump1090Resolution is the precision of the times in aircraft.json.
+	Dump1090Resolution = 100 * time.Millisecond
+)
+
+func validateSource(source string) error {
+	switch source {
+	case FirehoseSource, Dump1090Source:
+		return nil
+	}
+	return fmt.Errorf("unknown source %q", source)
+}
+
+// dump1090Data is the subset of dump1090's aircraft.json that we use.
+type dump1090Data struct {
+	// Now is when the file was generated, in seconds since the epoch.
+	Now      float64            `json:"now"`
+	Aircraft []dump1090Aircraft `json:"aircraft"`
+}
+
+type dump1090Aircraft struct {
+	Hex    string `json:"hex"`
+	Flight string `json:"flight"`
+	Squawk string `json:"squawk"`
+	// Registration and Type are only present when the decoder has been set up
+	// with an aircraft database, as with readsb.
+	Registration string   `json:"r"`
+	Type         string   `json:"t"`
+	Lat          *float64 `json:"lat"`
+	Lon          *float64 `json:"lon"`
+	// AltBaro is either the barometric altitude in feet or "ground". Older
+	// versions of dump1090 report it as Altitude instead.
+	AltBaro  any      `json:"alt_baro"`
+	Altitude any      `json:"altitude"`
+	GS       *float64 `json:"gs"`
+	Speed    *float64 `json:"speed"`
+	Track    *float64 `json:"track"`
+	// BaroRate and GeomRate are vertical rates in feet per minute. Older
+	// versions of dump1090 report VertRate instead.
+	BaroRate *float64 `json:"baro_rate"`
+	GeomRate *float64 `json:"geom_rate"`
+	VertRate *float64 `json:"vert_rate"`
+	SeenPos  float64  `json:"seen_pos"`
+}
+
+// pollDump1090 fetches aircraft positions from dump1090 every Dump1090Interval
+// until the context is canceled. Failed requests are logged and retried on
+// the next poll.
+func (a *App) pollDump1090(ctx context.Context) error {
+	ticker := time.NewTicker(a.Dump1090Interval)
+	defer ticker.Stop()
+	// There is no connection to dump1090 as such, so health depends only on
+	// how recently it answered.
+	a.setConnected(true)
+	defer a.setConnected(false)
+	for {
+		if err := a.fetchDump1090(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("could not fetch aircraft from dump1090", "url", a.Dump1090URL, "error", err)
+		} else if err == nil {
+			a.markReceived()
+		}
+		a.cleanupStaleFlights()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) fetchDump1090(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, Dump1090Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.Dump1090URL, nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("got HTTP response code %s", res.Status)
+	}
+	var data dump1090Data
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return err
+	}
+	for _, pos := range a.dump1090Positions(data) {
+		a.trackPosition(pos)
+	}
+	return nil
+}
+
+// dump1090Positions translates the aircraft with a recent position into
+// Positions. dump1090 has no notion of a flight ID, so the aircraft's ICAO
+// address is used instead. Aircraft whose position hasn't been updated since
+// the previous poll are left out, so that polling faster than positions arrive
+// doesn't repeat them.
+func (a *App) dump1090Positions(data dump1090Data) []*Position {
+	now := time.Unix(0, int64(data.Now*float64(time.Second)))
+	lastSeen := a.dump1090Seen
+	a.dump1090Seen = make(map[string]time.Time)
+	var positions []*Position
+	for _, ac := range data.Aircraft {
+		if ac.Lat == nil || ac.Lon == nil {
+			continue
+		}
+		seen := time.Duration(ac.SeenPos * float64(time.Second))
+		if seen > Dump1090MaxAge {
+			continue
+		}
+		at := now.Add(-seen).Round(Dump1090Resolution)
+		a.dump1090Seen[ac.Hex] = at
+		if last, ok := lastSeen[ac.Hex]; ok && !at.After(last) {
+			continue
+		}
+		pos := &Position{
+			FlightID:     ac.Hex,
+			ICAOHex:      normalizeICAOHex(ac.Hex),
+			Squawk:       ac.Squawk,
+			Point:        geo.Latlong{Lat: *ac.Lat, Long: *ac.Lon},
+			Ident:        strings.TrimSpace(ac.Flight),
+			Reg:          ac.Registration,
+			AircraftType: a.normalizeAircraftType(ac.Type),
+			Category:     aircraftCategory(ac.Type),
+			Heading:      ac.Track,
+			Timestamp:    now.Add(-seen).Truncate(time.Second),
+		}
+		pos.Altitude = dump1090Altitude(ac.AltBaro)
+		if pos.Altitude == nil {
+			pos.Altitude = dump1090Altitude(ac.Altitude)
+		}
+		pos.Speed = ac.GS
+		if pos.Speed == nil {
+			pos.Speed = ac.Speed
+		}
+		for _, rate := range []*float64{ac.BaroRate, ac.GeomRate, ac.VertRate} {
+			if rate != nil {
+				pos.VerticalRate = rate
+				break
+			}
+		}
+		home := a.myLocation()
+		pos.Distance = pos.Point.DistNM(home)
+		pos.Bearing = home.BearingTowards(pos.Point)
+		positions = append(positions, pos)
+	}
+	return positions
+}
+
+// dump1090Altitude interprets an altitude from aircraft.json, which is a number
+// of feet or the string "ground".
+func dump1090Altitude(v any) *float64 {
+	switch alt := v.(type) {
+	case float64:
+		return &alt
+	case string:
+		if alt == "ground" {
+			var zero float64
+			return &zero
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDump1090Positions(t *testing.T) {
+	const aircraftJSON = `{
+		"now": 1720083075.5,
+		"aircraft": [
+			{"hex": "a1b2c3", "flight": "UAL641  ", "r": "N12345", "t": "B39M", "lat": 42.05, "lon": -71.0, "alt_baro": 1050, "gs": 141.2, "track": 190.3, "seen_pos": 1.5},
+			{"hex": "c0ffee", "lat": 42.0, "lon": -71.05, "alt_baro": "ground", "seen_pos": 0.1},
+			{"hex": "abcdef", "flight": "OLD1", "lat": 42.0, "lon": -71.0, "altitude": 3000, "speed": 120, "seen_pos": 0},
+			{"hex": "def123", "flight": "STALE1", "lat": 42.0, "lon": -71.0, "seen_pos": 120},
+			{"hex": "fedcba", "flight": "NOPOS1", "alt_baro": 5000}
+		]
+	}`
+	var data dump1090Data
+	if err := json.Unmarshal([]byte(aircraftJSON), &data); err != nil {
+		t.Fatal(err)
+	}
+	app := &App{Latitude: 42.0, Longitude: -71.0}
+	positions := app.dump1090Positions(data)
+	if len(positions) != 3 {
+		t.Fatalf("expected 3 positions, got %d", len(positions))
+	}
+
+	ual := positions[0]
+	if ual.FlightID != "a1b2c3" || ual.Ident != "UAL641" || ual.Reg != "N12345" || ual.AircraftType != "B39M" {
+		t.Errorf("unexpected identification: %+v", ual)
+	}
+	if ual.Altitude == nil || *ual.Altitude != 1050 {
+		t.Errorf("unexpected altitude: %v", ual.Altitude)
+	}
+	if ual.Speed == nil || *ual.Speed != 141.2 || ual.Heading == nil || *ual.Heading != 190.3 {
+		t.Errorf("unexpected speed or heading: %v %v", ual.Speed, ual.Heading)
+	}
+	if exp := time.Unix(1720083074, 0); !ual.Timestamp.Equal(exp) {
+		t.Errorf("expected timestamp %s, got %s", exp, ual.Timestamp)
+	}
+	if ual.Distance < 2.9 || ual.Distance > 3.1 || cardinalDirection(ual.Bearing) != "north" {
+		t.Errorf("unexpected distance or bearing: %f %f", ual.Distance, ual.Bearing)
+	}
+
+	if ground := positions[1]; ground.Altitude == nil || *ground.Altitude != 0 || ground.Speed != nil {
+		t.Errorf("unexpected ground aircraft: %+v", ground)
+	}
+	if old := positions[2]; old.Altitude == nil || *old.Altitude != 3000 || old.Speed == nil || *old.Speed != 120 {
+		t.Errorf("unexpected legacy fields: %+v", old)
+	}
+}
+
+func TestDump1090PositionsUnchanged(t *testing.T) {
+	poll := func(now, seenPos float64) dump1090Data {
+		return dump1090Data{Now: now, Aircraft: []dump1090Aircraft{{Hex: "a1b2c3", Lat: new(float64), Lon: new(float64), SeenPos: seenPos}}}
+	}
+	app := &App{Latitude: 42.0, Longitude: -71.0}
+	if positions := app.dump1090Positions(poll(1720083075.5, 1.5)); len(positions) != 1 {
+		t.Fatalf("expected the first position, got %d", len(positions))
+	}
+	// A second later nothing new has been received, so the position is older.
+	if positions := app.dump1090Positions(poll(1720083076.5, 2.5)); len(positions) != 0 {
+		t.Errorf("expected an unchanged position to be skipped, got %d", len(positions))
+	}
+	if positions := app.dump1090Positions(poll(1720083077.5, 0.2)); len(positions) != 1 {
+		t.Errorf("expected an updated position, got %d", len(positions))
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// FlightLogBuffer is how many positions may be waiting to be written to
+	// the flight log before further positions are dropped.
+	FlightLogBuffer = 1024
+	// FlightLogBatchSize is the most positions written in one transaction.
+	FlightLogBatchSize = 100
+)
+
+const flightLogSchema = `
+CREATE TABLE IF NOT EXISTS positions (
+	flight_id TEXT NOT NULL,
+	ident TEXT NOT NULL,
+	reg TEXT NOT NULL,
+	aircraft_type TEXT NOT NULL,
+	lat REAL NOT NULL,
+	lon REAL NOT NULL,
+	altitude REAL,
+	speed REAL,
+	heading REAL,
+	timestamp INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS positions_timestamp ON positions (timestamp);
+CREATE INDEX IF NOT EXISTS positions_ident ON positions (ident);
+`
+
+const flightLogInsert = `
+INSERT INTO positions (flight_id, ident, reg, aircraft_type, lat, lon, altitude, speed, heading, timestamp)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+// A flightLog records interesting positions in a SQLite database. Positions
+// are written in batches by a background goroutine so that a slow disk does not
+// hold up the stream.
+type flightLog struct {
+	db        *sql.DB
+	insert    *sql.Stmt
+	positions chan *Position
+	done      chan struct{}
+}
+
+// openFlightLog opens the SQLite database at path, creating it and its schema
+// if necessary, and starts writing positions to it.
+func openFlightLog(path string) (*flightLog, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open flight log: %w", err)
+	}
+	if _, err := db.Exec(flightLogSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not create flight log schema: %w", err)
+	}
+	insert, err := db.Prepare(flightLogInsert)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not prepare flight log insert: %w", err)
+	}
+	l := &flightLog{
+		db:        db,
+		insert:    insert,
+		positions: make(chan *Position, FlightLogBuffer),
+		done:      make(chan struct{}),
+	}
+	go l.run()
+	return l, nil
+}
+
+// Log queues the position to be written. If the writer has fallen too far
+// behind, the position is dropped rather than blocking.
+func (l *flightLog) Log(pos *Position) {
+	select {
+	case l.positions <- pos:
+	default:
+		slog.Warn("flight log is falling behind; dropped position", "flight_id", pos.FlightID)
+	}
+}
+
+// Close writes any queued positions and closes the database. Log must not be
+// called after Close.
+func (l *flightLog) Close() error {
+	close(l.positions)
+	<-l.done
+	l.insert.Close()
+	return l.db.Close()
+}
+
+func (l *flightLog) run() {
+	defer close(l.done)
+	for pos := range l.positions {
+		batch := []*Position{pos}
+	drain:
+		for len(batch) < FlightLogBatchSize {
+			select {
+			case pos, ok := <-l.positions:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, pos)
+			default:
+				break drain
+			}
+		}
+		if err := l.write(batch); err != nil {
+			slog.Error("could not write to flight log", "positions", len(batch), "error", err)
+		}
+	}
+}
+
+func (l *flightLog) write(batch []*Position) error {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	insert := tx.Stmt(l.insert)
+	for _, pos := range batch {
+		_, err := insert.Exec(
+			pos.FlightID, pos.Ident, pos.Reg, pos.AircraftType,
+			pos.Point.Lat, pos.Point.Long,
+			pos.Altitude, pos.Speed, pos.Heading,
+			pos.Timestamp.Unix(),
+		)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
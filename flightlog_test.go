@@ -0,0 +1,53 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/skypies/geo"
+)
+
+func TestFlightLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flights.db")
+	alt := 1500.0
+	flightLog, err := openFlightLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flightLog.Log(&Position{FlightID: "A-1", Ident: "A", Point: geo.Latlong{Lat: 42, Long: -71}, Altitude: &alt, Timestamp: time.Unix(1000, 0)})
+	flightLog.Log(&Position{FlightID: "B-1", Ident: "B", Point: geo.Latlong{Lat: 42, Long: -71}, Timestamp: time.Unix(1010, 0)})
+	if err := flightLog.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening an existing database should leave its contents alone.
+	flightLog, err = openFlightLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer flightLog.Close()
+	var count int
+	if err := flightLog.db.QueryRow("SELECT COUNT(*) FROM positions").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 positions, got %d", count)
+	}
+	rows, err := flightLog.db.Query("SELECT ident FROM positions ORDER BY timestamp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var idents []string
+	for rows.Next() {
+		var ident string
+		if err := rows.Scan(&ident); err != nil {
+			t.Fatal(err)
+		}
+		idents = append(idents, ident)
+	}
+	if len(idents) != 2 || idents[0] != "A" || idents[1] != "B" {
+		t.Errorf("unexpected idents: %v", idents)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/benburwell/firehose"
+	"github.com/skypies/geo"
+
+	"overhead/internal/validate"
+)
+
+// A Vertex is a corner of a Polygon.
+type Vertex struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// A Polygon is an area bounded by straight lines between consecutive vertices,
+// with the last vertex joined back to the first. Latitude and longitude are
+// treated as plane coordinates, which is accurate enough for areas of a few
+// dozen miles that don't cross the antimeridian.
+type Polygon []Vertex
+
+// Validate checks that the polygon encloses an area and that its vertices are
+// real places.
+func (p Polygon) Validate() error {
+	if len(p) < 3 {
+		return fmt.Errorf("polygon needs at least 3 vertices, not %d", len(p))
+	}
+	for i, v := range p {
+		if err := validate.Location(v.Latitude, v.Longitude, true); err != nil {
+			return fmt.Errorf("vertex %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Contains reports whether the point lies inside the polygon or on its edge.
+func (p Polygon) Contains(point geo.Latlong) bool {
+	x, y := point.Long, point.Lat
+	inside := false
+	for i := range p {
+		a, b := p[i], p[(i+1)%len(p)]
+		if onSegment(x, y, a, b) {
+			return true
+		}
+		// Cast a ray to the east and count the edges it crosses.
+		if (a.Latitude > y) != (b.Latitude > y) {
+			crossing := a.Longitude + (y-a.Latitude)/(b.Latitude-a.Latitude)*(b.Longitude-a.Longitude)
+			if x < crossing {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// onSegment reports whether (x, y) lies on the line segment from a to b.
+func onSegment(x, y float64, a, b Vertex) bool {
+	const epsilon = 1e-9
+	cross := (b.Longitude-a.Longitude)*(y-a.Latitude) - (b.Latitude-a.Latitude)*(x-a.Longitude)
+	if math.Abs(cross) > epsilon {
+		return false
+	}
+	return x >= math.Min(a.Longitude, b.Longitude)-epsilon && x <= math.Max(a.Longitude, b.Longitude)+epsilon &&
+		y >= math.Min(a.Latitude, b.Latitude)-epsilon && y <= math.Max(a.Latitude, b.Latitude)+epsilon
+}
+
+// Bounds returns the smallest rectangle enclosing the polygon.
+func (p Polygon) Bounds() firehose.Rectangle {
+	r := firehose.Rectangle{
+		LowLat: math.Inf(1),
+		LowLon: math.Inf(1),
+		HiLat:  math.Inf(-1),
+		HiLon:  math.Inf(-1),
+	}
+	for _, v := range p {
+		r.LowLat = math.Min(r.LowLat, v.Latitude)
+		r.LowLon = math.Min(r.LowLon, v.Longitude)
+		r.HiLat = math.Max(r.HiLat, v.Latitude)
+		r.HiLon = math.Max(r.HiLon, v.Longitude)
+	}
+	return r
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/skypies/geo"
+)
+
+func TestPolygonContains(t *testing.T) {
+	// An L shape, so that one point inside its bounds is outside the polygon.
+	polygon := Polygon{
+		{Latitude: 42.0, Longitude: -71.0},
+		{Latitude: 42.0, Longitude: -70.8},
+		{Latitude: 42.1, Longitude: -70.8},
+		{Latitude: 42.1, Longitude: -70.9},
+		{Latitude: 42.2, Longitude: -70.9},
+		{Latitude: 42.2, Longitude: -71.0},
+	}
+	tests := []struct {
+		name  string
+		point geo.Latlong
+		exp   bool
+	}{
+		{"inside", geo.Latlong{Lat: 42.05, Long: -70.95}, true},
+		{"inside upper arm", geo.Latlong{Lat: 42.15, Long: -70.95}, true},
+		{"in the notch", geo.Latlong{Lat: 42.15, Long: -70.85}, false},
+		{"outside", geo.Latlong{Lat: 41.9, Long: -70.9}, false},
+		{"on an edge", geo.Latlong{Lat: 42.0, Long: -70.9}, true},
+		{"on a vertex", geo.Latlong{Lat: 42.1, Long: -70.8}, true},
+		{"on the notch edge", geo.Latlong{Lat: 42.15, Long: -70.9}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := polygon.Contains(test.point); actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+		})
+	}
+
+	bounds := polygon.Bounds()
+	if bounds.LowLat != 42.0 || bounds.HiLat != 42.2 || bounds.LowLon != -71.0 || bounds.HiLon != -70.8 {
+		t.Errorf("unexpected bounds: %+v", bounds)
+	}
+}
+
+func TestIsInterestingGeofence(t *testing.T) {
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingCeilingFt: 15000,
+		Geofence: Polygon{
+			{Latitude: 41.9, Longitude: -71.1},
+			{Latitude: 41.9, Longitude: -70.9},
+			{Latitude: 42.1, Longitude: -71.0},
+		},
+	}
+	inside := &Position{Point: geo.Latlong{Lat: 41.95, Long: -71.0}}
+	outside := &Position{Point: geo.Latlong{Lat: 42.05, Long: -71.09}}
+	if !app.isInteresting(inside) {
+		t.Error("expected a flight inside the geofence to be interesting")
+	}
+	if app.isInteresting(outside) {
+		t.Error("expected a flight outside the geofence not to be interesting")
+	}
+	if box := app.subscriptionBox(); len(box) != 1 || box[0] != app.Geofence.Bounds() {
+		t.Errorf("expected subscription box to enclose the geofence, got %+v", box)
+	}
+}
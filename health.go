@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// health is the body of a /healthz response.
+type health struct {
+	Healthy   bool
+	Connected bool
+	// LastMessage is when we last heard from the source, by our own clock.
+	LastMessage time.Time `json:",omitempty"`
+}
+
+// setConnected records whether we are connected to the source of positions.
+func (a *App) setConnected(connected bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.connected = connected
+}
+
+// markReceived records that a message of any kind arrived from the source.
+func (a *App) markReceived() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastMessage = a.wallClock()
+}
+
+// health reports whether we are connected to the source and have heard from
+// it within the stream timeout. A replay is healthy for as long as it runs,
+// however far apart its messages are.
+func (a *App) health() health {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	h := health{Connected: a.connected, LastMessage: a.lastMessage}
+	fresh := a.ReplayFile != "" || a.StreamTimeout <= 0 || a.wallClock().Sub(a.lastMessage) <= a.StreamTimeout
+	h.Healthy = a.connected && fresh
+	return h
+}
+
+// handleHealth responds with 200 if we are healthy and 503 otherwise, for use
+// as a liveness probe.
+func (a *App) handleHealth(w http.ResponseWriter, r *http.Request) {
+	h := a.health()
+	w.Header().Set("content-type", "application/json")
+	if !h.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(h); err != nil {
+		slog.Warn("could not write health response", "error", err)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleHealth(t *testing.T) {
+	now := time.Unix(1720083075, 0)
+	app := &App{StreamTimeout: time.Minute, clock: func() time.Time { return now }}
+	status := func() int {
+		rec := httptest.NewRecorder()
+		app.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		return rec.Code
+	}
+
+	if code := status(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected to be unhealthy before connecting, got %d", code)
+	}
+	app.setConnected(true)
+	app.markReceived()
+	if code := status(); code != http.StatusOK {
+		t.Errorf("expected to be healthy after a message, got %d", code)
+	}
+	now = now.Add(2 * time.Minute)
+	if code := status(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected to be unhealthy once messages stop, got %d", code)
+	}
+	app.markReceived()
+	app.setConnected(false)
+	if code := status(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected to be unhealthy when disconnected, got %d", code)
+	}
+}
+
+func TestReplayHealth(t *testing.T) {
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		ReplayRealtime:       true,
+		StreamTimeout:        time.Millisecond,
+		SummaryOutput:        io.Discard,
+	}
+	home := app.myLocation()
+	// The replay waits an hour between messages, far longer than the stream
+	// timeout.
+	first, _ := json.Marshal(testPosition("A", moveNM(home, 0, 6), 1000))
+	second, _ := json.Marshal(testPosition("A", moveNM(home, 0, 5), 4600))
+	app.ReplayFile = writeReplayFile(t, string(first), string(second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- app.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !app.health().Healthy {
+		if time.Now().After(deadline) {
+			t.Fatal("expected to be healthy while replaying")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if !app.health().Healthy {
+		t.Error("expected to stay healthy between replayed messages")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if app.health().Healthy {
+		t.Error("expected to be unhealthy once the replay stops")
+	}
+}
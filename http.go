@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// HTTPShutdownTimeout bounds how long we wait for in-flight HTTP requests when
+// shutting down.
+const HTTPShutdownTimeout = 5 * time.Second
+
+// serveHTTP serves the HTTP API on the listener until the context is canceled.
+func (a *App) serveHTTP(ctx context.Context, l net.Listener) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /flights", a.handleFlights)
+	mux.HandleFunc("GET /flights/{id}/trail", a.handleTrail)
+	mux.HandleFunc("GET /healthz", a.handleHealth)
+	if a.stream != nil {
+		mux.HandleFunc("GET /stream", a.handleStream(ctx))
+	}
+	serve(ctx, l, mux, "HTTP")
+}
+
+// serve serves the handler on the listener until the context is canceled,
+// then shuts down gracefully. The name identifies the server in logs.
+func serve(ctx context.Context, l net.Listener, handler http.Handler, name string) {
+	srv := &http.Server{Handler: handler}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), HTTPShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("could not shut down server", "server", name, "error", err)
+		}
+	}()
+
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("server failed", "server", name, "error", err)
+	}
+}
+
+// handleFlights responds with the latest position of each tracked flight,
+// nearest first.
+func (a *App) handleFlights(w http.ResponseWriter, r *http.Request) {
+	flights := a.trackedFlights()
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(flights); err != nil {
+		slog.Warn("could not write flights response", "error", err)
+	}
+}
+
+// handleTrail responds with the recent positions of a tracked flight, oldest
+// first.
+func (a *App) handleTrail(w http.ResponseWriter, r *http.Request) {
+	points, ok := a.flightTrail(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "flight not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(points); err != nil {
+		slog.Warn("could not write trail response", "error", err)
+	}
+}
+
+// flightTrail returns a snapshot of a tracked flight's trail. ok is false if
+// the flight isn't being tracked.
+func (a *App) flightTrail(id string) ([]Position, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	flight, ok := a.flights[id]
+	if !ok {
+		return nil, false
+	}
+	return flight.trail.points(), true
+}
+
+// trackedFlights returns a snapshot of the latest position of each tracked
+// flight, sorted by distance.
+func (a *App) trackedFlights() []Position {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	flights := make([]Position, 0, len(a.flights))
+	for _, flight := range a.flights {
+		flights = append(flights, *flight.last)
+	}
+	sort.Slice(flights, func(i, j int) bool {
+		return flights[i].Distance < flights[j].Distance
+	})
+	return flights
+}
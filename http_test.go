@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleFlights(t *testing.T) {
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+	}
+
+	rec := httptest.NewRecorder()
+	app.handleFlights(rec, httptest.NewRequest(http.MethodGet, "/flights", nil))
+	if body := strings.TrimSpace(rec.Body.String()); body != "[]" {
+		t.Errorf("expected an empty array, got %s", body)
+	}
+
+	home := app.myLocation()
+	app.handlePosition(testPosition("A", moveNM(home, 0, 5), 1000))
+	app.handlePosition(testPosition("B", moveNM(home, 90, 2), 1000))
+
+	rec = httptest.NewRecorder()
+	app.handleFlights(rec, httptest.NewRequest(http.MethodGet, "/flights", nil))
+	var flights []Position
+	if err := json.NewDecoder(rec.Body).Decode(&flights); err != nil {
+		t.Fatal(err)
+	}
+	if len(flights) != 2 || flights[0].FlightID != "B" || flights[1].FlightID != "A" {
+		t.Fatalf("unexpected flights: %+v", flights)
+	}
+	if flights[0].Distance < 1.99 || flights[0].Distance > 2.01 || flights[0].Bearing < 89 || flights[0].Bearing > 91 {
+		t.Errorf("unexpected distance and bearing: %f %f", flights[0].Distance, flights[0].Bearing)
+	}
+}
+
+func TestHandleTrail(t *testing.T) {
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		TrailLength:          2,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /flights/{id}/trail", app.handleTrail)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/flights/A/trail", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected not found for an untracked flight, got %d", rec.Code)
+	}
+
+	home := app.myLocation()
+	for i, dist := range []float64{5, 4, 3} {
+		app.handlePosition(testPosition("A", moveNM(home, 0, dist), int64(1000+i)))
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/flights/A/trail", nil))
+	var trail []Position
+	if err := json.NewDecoder(rec.Body).Decode(&trail); err != nil {
+		t.Fatal(err)
+	}
+	if len(trail) != 2 || trail[0].Distance < 3.99 || trail[0].Distance > 4.01 || trail[1].Distance < 2.99 || trail[1].Distance > 3.01 {
+		t.Errorf("unexpected trail: %+v", trail)
+	}
+}
@@ -0,0 +1,21 @@
+// Package airport holds helpers for the airport codes in Firehose positions,
+// shared by overhead and nearest.
+package airport
+
+import "strings"
+
+// IsCode reports whether the given string looks like an airport. It needs to
+// be non-blank and at most 4 characters long (ICAO aerodrome).
+func IsCode(s string) bool {
+	return s != "" && len(s) <= 4
+}
+
+// Normalize returns the code in upper case without surrounding space, or an
+// empty string if it doesn't look like an airport.
+func Normalize(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if !IsCode(s) {
+		return ""
+	}
+	return s
+}
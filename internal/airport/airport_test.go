@@ -0,0 +1,41 @@
+package airport
+
+import "testing"
+
+func TestIsCode(t *testing.T) {
+	tests := []struct {
+		code string
+		exp  bool
+	}{
+		{"KBOS", true},
+		{"BOS", true},
+		{"", false},
+		{"L 42.36, -71.01", false},
+	}
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			if actual := IsCode(test.code); actual != test.exp {
+				t.Errorf("expected %v but got %v", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		code string
+		exp  string
+	}{
+		{"KBOS", "KBOS"},
+		{" kbos ", "KBOS"},
+		{"", ""},
+		{"L 42.36, -71.01", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			if actual := Normalize(test.code); actual != test.exp {
+				t.Errorf("expected %q but got %q", test.exp, actual)
+			}
+		})
+	}
+}
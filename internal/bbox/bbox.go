@@ -0,0 +1,64 @@
+// Package bbox works out the rectangles of latitude and longitude to request
+// from Firehose so that they enclose a radius around a point, shared by
+// overhead and nearest.
+package bbox
+
+import (
+	"math"
+
+	"github.com/benburwell/firehose"
+	"github.com/skypies/geo"
+)
+
+// EarthRadiusNM is the mean radius of the Earth in nautical miles.
+const EarthRadiusNM = 3440.065
+
+// Around returns rectangles which together enclose every point within the
+// radius of the center. Away from the poles a single rectangle is needed,
+// widened in longitude to account for meridians converging at higher
+// latitudes. A radius crossing the antimeridian is split into a rectangle on
+// either side of it, since a rectangle's longitudes can't wrap, and a radius
+// reaching over a pole covers every longitude.
+func Around(center geo.Latlong, radiusNM float64) []firehose.Rectangle {
+	// The radius as an angle at the center of the Earth.
+	d := radiusNM / EarthRadiusNM * 180 / math.Pi
+
+	lowLat, hiLat := center.Lat-d, center.Lat+d
+	if lowLat <= -90 || hiLat >= 90 {
+		return []firehose.Rectangle{{
+			LowLat: math.Max(lowLat, -90),
+			LowLon: -180,
+			HiLat:  math.Min(hiLat, 90),
+			HiLon:  180,
+		}}
+	}
+
+	// The widest longitude of a circle on a sphere is found where a great
+	// circle through the pole is tangent to it.
+	lat, rad := center.Lat*math.Pi/180, d*math.Pi/180
+	dLon := math.Asin(math.Min(math.Sin(rad)/math.Cos(lat), 1)) * 180 / math.Pi
+	lowLon, hiLon := center.Long-dLon, center.Long+dLon
+	switch {
+	case lowLon < -180:
+		return []firehose.Rectangle{
+			{LowLat: lowLat, LowLon: lowLon + 360, HiLat: hiLat, HiLon: 180},
+			{LowLat: lowLat, LowLon: -180, HiLat: hiLat, HiLon: hiLon},
+		}
+	case hiLon > 180:
+		return []firehose.Rectangle{
+			{LowLat: lowLat, LowLon: lowLon, HiLat: hiLat, HiLon: 180},
+			{LowLat: lowLat, LowLon: -180, HiLat: hiLat, HiLon: hiLon - 360},
+		}
+	}
+	return []firehose.Rectangle{{LowLat: lowLat, LowLon: lowLon, HiLat: hiLat, HiLon: hiLon}}
+}
+
+// Contains reports whether the point lies within any of the rectangles.
+func Contains(rects []firehose.Rectangle, p geo.Latlong) bool {
+	for _, r := range rects {
+		if p.Lat >= r.LowLat && p.Lat <= r.HiLat && p.Long >= r.LowLon && p.Long <= r.HiLon {
+			return true
+		}
+	}
+	return false
+}
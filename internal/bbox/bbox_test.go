@@ -0,0 +1,59 @@
+package bbox
+
+import (
+	"math"
+	"testing"
+
+	"github.com/skypies/geo"
+)
+
+// normalize wraps a longitude into [-180, 180].
+func normalize(p geo.Latlong) geo.Latlong {
+	p.Long = math.Mod(p.Long+540, 360) - 180
+	return p
+}
+
+func TestAround(t *testing.T) {
+	tests := []struct {
+		name   string
+		center geo.Latlong
+		radius float64
+		rects  int
+	}{
+		{"mid latitudes", geo.Latlong{Lat: 42.36, Long: -71.01}, 10, 1},
+		{"near the antimeridian", geo.Latlong{Lat: 52, Long: 179.9}, 20, 2},
+		{"just west of the antimeridian", geo.Latlong{Lat: -17, Long: -179.95}, 10, 2},
+		{"high latitude", geo.Latlong{Lat: 70, Long: 25}, 50, 1},
+		{"over the pole", geo.Latlong{Lat: 89.9, Long: 0}, 20, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rects := Around(test.center, test.radius)
+			if len(rects) != test.rects {
+				t.Fatalf("expected %d rectangles, got %+v", test.rects, rects)
+			}
+			for _, r := range rects {
+				if r.LowLat > r.HiLat || r.LowLon > r.HiLon || r.LowLon < -180 || r.HiLon > 180 {
+					t.Errorf("malformed rectangle %+v", r)
+				}
+			}
+			// Every point on the edge of the radius must be enclosed.
+			for bearing := 0.0; bearing < 360; bearing += 5 {
+				p := normalize(test.center.MoveKM(bearing, geo.NM2KM(test.radius*0.999)))
+				if !Contains(rects, p) {
+					t.Errorf("point at bearing %.0f %+v is outside %+v", bearing, p, rects)
+				}
+			}
+		})
+	}
+}
+
+func TestAroundHighLatitudeWidth(t *testing.T) {
+	// At 70°N a degree of longitude is about a third as long as at the
+	// equator, so the box must be about three times as wide in degrees.
+	rects := Around(geo.Latlong{Lat: 70, Long: 25}, 60)
+	width := rects[0].HiLon - rects[0].LowLon
+	if width < 5.8 || width > 5.9 {
+		t.Errorf("unexpected width of %f degrees", width)
+	}
+}
@@ -0,0 +1,30 @@
+// Package credentials finds the Firehose credentials for overhead and nearest.
+package credentials
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ResolvePassword picks the Firehose password. One given explicitly by flag or
+// environment variable wins; otherwise the password file is read if there is
+// one, with surrounding whitespace trimmed; otherwise the password from the
+// config file is used.
+func ResolvePassword(password string, explicit bool, passwordFile string) (string, error) {
+	if explicit || passwordFile == "" {
+		return password, nil
+	}
+	b, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return "", fmt.Errorf("could not read password-file: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// InEnv reports whether the password is given by the environment variable. An
+// empty variable counts as unset, as it does to viper, which would otherwise
+// fall back to the config file's password.
+func InEnv(name string) bool {
+	return os.Getenv(name) != ""
+}
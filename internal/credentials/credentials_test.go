@@ -0,0 +1,51 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("  from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		password string
+		explicit bool
+		file     string
+		exp      string
+	}{
+		{"config only", "from-config", false, "", "from-config"},
+		{"file over config", "from-config", false, path, "from-file"},
+		{"flag or env over file", "from-env", true, path, "from-env"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := ResolvePassword(test.password, test.explicit, test.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != test.exp {
+				t.Errorf("expected %q, got %q", test.exp, actual)
+			}
+		})
+	}
+
+	if _, err := ResolvePassword("", false, filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for a missing password-file")
+	}
+}
+
+func TestInEnv(t *testing.T) {
+	t.Setenv("TEST_PASSWORD", "secret")
+	if !InEnv("TEST_PASSWORD") {
+		t.Error("expected a set password to be in the environment")
+	}
+	t.Setenv("TEST_PASSWORD", "")
+	if InEnv("TEST_PASSWORD") {
+		t.Error("expected an empty password not to count")
+	}
+}
@@ -0,0 +1,44 @@
+// Package precision decides how many decimal places to show distances to,
+// shared by overhead and nearest.
+package precision
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Adaptive shows distances to two decimal places under 1nm, to one up to
+// 10nm, and as whole numbers beyond that.
+const Adaptive = "adaptive"
+
+// Max is the most decimal places distances may be shown to.
+const Max = 3
+
+// Validate checks that a precision is a number of decimal places or Adaptive.
+func Validate(precision string) error {
+	if precision == Adaptive {
+		return nil
+	}
+	if n, err := strconv.Atoi(precision); err != nil || n < 0 || n > Max {
+		return fmt.Errorf("unknown distance-precision %q; must be 0 to %d or %s", precision, Max, Adaptive)
+	}
+	return nil
+}
+
+// Decimals returns how many decimal places to show a distance in nautical
+// miles to. An empty precision means one decimal place.
+func Decimals(nm float64, precision string) int {
+	if precision == Adaptive {
+		switch {
+		case nm < 1:
+			return 2
+		case nm > 10:
+			return 0
+		}
+		return 1
+	}
+	if n, err := strconv.Atoi(precision); err == nil {
+		return n
+	}
+	return 1
+}
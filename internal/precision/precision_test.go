@@ -0,0 +1,49 @@
+package precision
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		precision string
+		valid     bool
+	}{
+		{"0", true},
+		{"1", true},
+		{"3", true},
+		{Adaptive, true},
+		{"", false},
+		{"-1", false},
+		{"4", false},
+		{"auto", false},
+	}
+	for _, test := range tests {
+		t.Run(test.precision, func(t *testing.T) {
+			if err := Validate(test.precision); (err == nil) != test.valid {
+				t.Errorf("unexpected validation result: %v", err)
+			}
+		})
+	}
+}
+
+func TestDecimals(t *testing.T) {
+	tests := []struct {
+		nm        float64
+		precision string
+		exp       int
+	}{
+		{0.5, "", 1},
+		{0.5, "2", 2},
+		{12, "0", 0},
+		{0.5, Adaptive, 2},
+		{1, Adaptive, 1},
+		{10, Adaptive, 1},
+		{10.5, Adaptive, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.precision, func(t *testing.T) {
+			if actual := Decimals(test.nm, test.precision); actual != test.exp {
+				t.Errorf("expected %d decimals for %f, got %d", test.exp, test.nm, actual)
+			}
+		})
+	}
+}
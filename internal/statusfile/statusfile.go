@@ -0,0 +1,28 @@
+// Package statusfile writes the JSON status files which overhead and nearest
+// keep for other programs to read.
+package statusfile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Write atomically replaces the file at path with v encoded as JSON. The JSON
+// is written to a temporary file in the same directory which is then renamed
+// over path, so that readers never see a partial write.
+func Write(path string, v any) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := json.NewEncoder(f).Encode(v); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
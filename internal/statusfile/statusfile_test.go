@@ -0,0 +1,39 @@
+package statusfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "status.json")
+	for _, v := range []any{map[string]string{"FlightID": "A"}, struct{}{}} {
+		if err := Write(path, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "{}\n" {
+		t.Errorf("expected the second write to replace the first, got %q", b)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the status file to remain, got %v", entries)
+	}
+
+	// Nothing is left behind when the value can't be encoded.
+	if err := Write(path, func() {}); err == nil {
+		t.Error("expected an error encoding a func")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected the temporary file to be removed, got %v", entries)
+	}
+}
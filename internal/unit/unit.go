@@ -0,0 +1,49 @@
+// Package unit holds the systems of units that overhead and nearest can
+// display, the factors for converting from the units Firehose reports in, and
+// the formatting the two share.
+package unit
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Systems of units in which distances, altitudes, and speeds can be shown.
+// Settings naming them are case-insensitive.
+const (
+	// Imperial uses nautical miles, feet, and knots, as in aviation.
+	Imperial = "imperial"
+	// Metric uses kilometers, meters, and kilometers per hour.
+	Metric = "metric"
+)
+
+// Conversion factors from the units Firehose reports in.
+const (
+	KMPerNM       = 1.852
+	MetersPerFoot = 0.3048
+	// KPHPerKnot converts knots (nautical miles per hour) to kilometers per
+	// hour.
+	KPHPerKnot = KMPerNM
+)
+
+// Validate checks that a units setting is one we know about.
+func Validate(units string) error {
+	switch strings.ToLower(units) {
+	case Imperial, Metric:
+		return nil
+	default:
+		return fmt.Errorf("unknown units %q; must be %s or %s", units, Imperial, Metric)
+	}
+}
+
+// IsMetric reports whether a units setting is Metric.
+func IsMetric(units string) bool {
+	return strings.EqualFold(units, Metric)
+}
+
+// FormatBearing formats a bearing as three whole degrees, e.g. "007",
+// rounding before wrapping so that 359.6 reads "000" rather than "360".
+func FormatBearing(bearing float64) string {
+	return fmt.Sprintf("%03.0f", math.Mod(math.Round(bearing), 360))
+}
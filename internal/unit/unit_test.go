@@ -0,0 +1,54 @@
+package unit
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, units := range []string{"imperial", "metric", "Metric", "IMPERIAL"} {
+		if err := Validate(units); err != nil {
+			t.Errorf("expected %s to be valid: %v", units, err)
+		}
+	}
+	if err := Validate("furlongs"); err == nil {
+		t.Errorf("expected unknown units to be invalid")
+	}
+}
+
+func TestIsMetric(t *testing.T) {
+	tests := []struct {
+		units string
+		exp   bool
+	}{
+		{"metric", true},
+		{"Metric", true},
+		{"imperial", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if actual := IsMetric(test.units); actual != test.exp {
+			t.Errorf("IsMetric(%q) = %t", test.units, actual)
+		}
+	}
+}
+
+func TestFormatBearing(t *testing.T) {
+	tests := []struct {
+		bearing float64
+		exp     string
+	}{
+		{0, "000"},
+		{7.4, "007"},
+		{90, "090"},
+		{359.4, "359"},
+		{359.6, "000"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%f", test.bearing), func(t *testing.T) {
+			if actual := FormatBearing(test.bearing); actual != test.exp {
+				t.Errorf("unexpected bearing: %s", actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// Package validate checks configuration values shared by overhead and
+// nearest.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Location checks that a location is a real place. A location of exactly 0, 0
+// almost certainly means that latitude and longitude were never set, so it is
+// rejected unless explicitly allowed.
+func Location(lat, lon float64, allowNullIsland bool) error {
+	if lat < -90 || lat > 90 {
+		return fmt.Errorf("latitude %f is not between -90 and 90", lat)
+	}
+	if lon < -180 || lon > 180 {
+		return fmt.Errorf("longitude %f is not between -180 and 180", lon)
+	}
+	if lat == 0 && lon == 0 && !allowNullIsland {
+		return errors.New("latitude and longitude are both 0; set them in your config file (or pass --allow-null-island if you really mean it)")
+	}
+	return nil
+}
+
+// Radius checks a configured radius against the maximum we are willing to
+// subscribe to, to guard against accidentally requesting an enormous area from
+// Firehose. An excessive radius is either clamped to the maximum or rejected.
+func Radius(name string, radius, max float64, clamp bool) (float64, error) {
+	if radius <= max {
+		return radius, nil
+	}
+	if !clamp {
+		return 0, fmt.Errorf("%s of %.1fnm exceeds max-radius of %.1fnm", name, radius, max)
+	}
+	slog.Warn("radius exceeds max-radius; clamping", "setting", name, "radius_nm", radius, "max_radius_nm", max)
+	return max, nil
+}
@@ -0,0 +1,54 @@
+package validate
+
+import "testing"
+
+func TestLocation(t *testing.T) {
+	tests := []struct {
+		name       string
+		lat, lon   float64
+		nullIsland bool
+		valid      bool
+	}{
+		{"boston", 42.36, -71.01, false, true},
+		{"unset", 0, 0, false, false},
+		{"null island allowed", 0, 0, true, true},
+		{"equator", 0, -71.01, false, true},
+		{"latitude out of range", 91, -71.01, false, false},
+		{"longitude out of range", 42.36, -181, false, false},
+		{"out of range with null island", -91, 0, true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Location(test.lat, test.lon, test.nullIsland)
+			if (err == nil) != test.valid {
+				t.Errorf("unexpected validation result: %v", err)
+			}
+		})
+	}
+}
+
+func TestRadius(t *testing.T) {
+	tests := []struct {
+		name   string
+		radius float64
+		clamp  bool
+		exp    float64
+		valid  bool
+	}{
+		{"within limit", 10, false, 10, true},
+		{"at limit", 100, false, 100, true},
+		{"over limit", 1000, false, 0, false},
+		{"clamped", 1000, true, 100, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Radius("interesting-radius", test.radius, 100, test.clamp)
+			if (err == nil) != test.valid {
+				t.Fatalf("unexpected result: %v", err)
+			}
+			if actual != test.exp {
+				t.Errorf("unexpected radius: %f", actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skypies/geo"
+
+	"overhead/internal/validate"
+)
+
+// Sources of the observer's location.
+const (
+	// StaticLocation uses the configured latitude and longitude.
+	StaticLocation = "static"
+	// NMEALocation reads NMEA sentences from a GPS receiver's serial device.
+	NMEALocation = "nmea"
+	// HTTPLocation polls a URL for a JSON object with latitude and longitude.
+	HTTPLocation = "http"
+)
+
+const (
+	// LocationTimeout bounds each request to the location URL.
+	LocationTimeout = 5 * time.Second
+	// LocationRetryInterval is how long to wait before reopening the GPS
+	// device after it fails.
+	LocationRetryInterval = 5 * time.Second
+)
+
+// errNoFix indicates that a location source is working but doesn't yet know
+// where it is.
+var errNoFix = errors.New("no location fix")
+
+func validateLocationSource(a *App) error {
+	switch a.LocationSource {
+	case StaticLocation:
+		return nil
+	case NMEALocation:
+		if a.LocationDevice == "" {
+			return errors.New("location-device is required with the nmea location-source")
+		}
+	case HTTPLocation:
+		if a.LocationURL == "" {
+			return errors.New("location-url is required with the http location-source")
+		}
+		if a.LocationInterval <= 0 {
+			return errors.New("location-interval must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown location-source %q", a.LocationSource)
+	}
+	if a.LocationReboxNM <= 0 {
+		return errors.New("location-rebox-distance must be positive")
+	}
+	return nil
+}
+
+// isMoving reports whether our location comes from a source which may change
+// while running.
+func (a *App) isMoving() bool {
+	return a.LocationSource == NMEALocation || a.LocationSource == HTTPLocation
+}
+
+// myLocation returns where we are: the latest fix from a moving location
+// source, or otherwise the configured latitude and longitude.
+func (a *App) myLocation() geo.Latlong {
+	if a.isMoving() {
+		a.locationMu.Lock()
+		defer a.locationMu.Unlock()
+		if a.location != nil {
+			return *a.location
+		}
+	}
+	return geo.Latlong{
+		Lat:  a.Latitude,
+		Long: a.Longitude,
+	}
+}
+
+// setLocation records a new fix. The first fix unblocks waitForFix, and moving
+// more than LocationReboxNM from where we last subscribed ends the current
+// Firehose stream so that it is reopened around the new location.
+func (a *App) setLocation(loc geo.Latlong) {
+	a.locationMu.Lock()
+	defer a.locationMu.Unlock()
+	if a.location == nil {
+		slog.Info("got location fix", "latitude", loc.Lat, "longitude", loc.Long)
+		if a.fixed != nil {
+			close(a.fixed)
+		}
+	}
+	a.location = &loc
+	if a.resubscribe != nil && loc.DistNM(a.subscribedAt) > a.LocationReboxNM {
+		slog.Info("location moved; resubscribing", "latitude", loc.Lat, "longitude", loc.Long)
+		a.resubscribe()
+		a.resubscribe = nil
+	}
+}
+
+// watchSubscription arranges for cancel to be called when we move far enough
+// from where the subscription box was drawn around that it should be redrawn.
+// Boxes which don't depend on our location are never redrawn.
+func (a *App) watchSubscription(center geo.Latlong, cancel context.CancelFunc) {
+	if !a.isMoving() || a.ObservationBox != nil || len(a.Geofence) > 0 {
+		return
+	}
+	a.locationMu.Lock()
+	defer a.locationMu.Unlock()
+	a.subscribedAt = center
+	a.resubscribe = cancel
+}
+
+// trackLocation updates our location from the location source until the
+// context is canceled.
+func (a *App) trackLocation(ctx context.Context) {
+	switch a.LocationSource {
+	case NMEALocation:
+		for {
+			err := a.readNMEA(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("could not read GPS; retrying", "device", a.LocationDevice, "error", err)
+			if err := sleep(ctx, LocationRetryInterval); err != nil {
+				return
+			}
+		}
+	case HTTPLocation:
+		ticker := time.NewTicker(a.LocationInterval)
+		defer ticker.Stop()
+		for {
+			if loc, err := a.fetchLocation(ctx); errors.Is(err, errNoFix) {
+				slog.Debug("location URL has no fix", "url", a.LocationURL)
+			} else if err != nil && ctx.Err() == nil {
+				slog.Warn("could not fetch location", "url", a.LocationURL, "error", err)
+			} else if err == nil {
+				a.setLocation(loc)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// waitForFix blocks until a moving location source has given us a location,
+// since distances can't be worked out without one.
+func (a *App) waitForFix(ctx context.Context) error {
+	if !a.isMoving() {
+		return nil
+	}
+	select {
+	case <-a.fixed:
+		return nil
+	default:
+	}
+	slog.Info("waiting for a location fix", "source", a.LocationSource)
+	select {
+	case <-a.fixed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// readNMEA reads sentences from the GPS device until it fails or the context
+// is canceled. The device is expected to already be set to the right baud
+// rate, e.g. with stty.
+func (a *App) readNMEA(ctx context.Context) error {
+	f, err := os.Open(a.LocationDevice)
+	if err != nil {
+		return err
+	}
+	// Closing the device is the only way to interrupt a blocked read, so it is
+	// closed once we return or the context is canceled, whichever is first.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		loc, err := parseNMEA(scanner.Text())
+		if err == nil {
+			a.setLocation(loc)
+		} else if !errors.Is(err, errNoFix) {
+			slog.Debug("ignoring NMEA sentence", "sentence", scanner.Text(), "error", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("GPS device closed")
+}
+
+// parseNMEA reads a location from an RMC or GGA sentence, e.g.
+//
+//	$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A
+//
+// Other sentences are ignored with an error, and errNoFix is returned if the
+// receiver says it doesn't have a fix.
+func parseNMEA(sentence string) (geo.Latlong, error) {
+	sentence = strings.TrimSpace(sentence)
+	body, ok := strings.CutPrefix(sentence, "$")
+	if !ok {
+		return geo.Latlong{}, errors.New("not an NMEA sentence")
+	}
+	if data, sum, ok := strings.Cut(body, "*"); ok {
+		expected, err := strconv.ParseUint(sum, 16, 8)
+		if err != nil {
+			return geo.Latlong{}, fmt.Errorf("bad checksum %q", sum)
+		}
+		var actual byte
+		for i := 0; i < len(data); i++ {
+			actual ^= data[i]
+		}
+		if actual != byte(expected) {
+			return geo.Latlong{}, fmt.Errorf("checksum mismatch: %02X != %02X", actual, expected)
+		}
+		body = data
+	}
+	fields := strings.Split(body, ",")
+	if len(fields[0]) != 5 {
+		return geo.Latlong{}, fmt.Errorf("unknown sentence %q", fields[0])
+	}
+	// The talker ID, e.g. GP for GPS or GN for several systems, doesn't matter.
+	var lat, ns, lon, ew string
+	switch fields[0][2:] {
+	case "RMC":
+		if len(fields) < 7 {
+			return geo.Latlong{}, errors.New("short RMC sentence")
+		}
+		if fields[2] != "A" {
+			return geo.Latlong{}, errNoFix
+		}
+		lat, ns, lon, ew = fields[3], fields[4], fields[5], fields[6]
+	case "GGA":
+		if len(fields) < 7 {
+			return geo.Latlong{}, errors.New("short GGA sentence")
+		}
+		if fields[6] == "" || fields[6] == "0" {
+			return geo.Latlong{}, errNoFix
+		}
+		lat, ns, lon, ew = fields[2], fields[3], fields[4], fields[5]
+	default:
+		return geo.Latlong{}, fmt.Errorf("unknown sentence %q", fields[0])
+	}
+	latitude, err := parseNMEACoordinate(lat, ns, "N", "S", 2)
+	if err != nil {
+		return geo.Latlong{}, fmt.Errorf("bad latitude: %w", err)
+	}
+	longitude, err := parseNMEACoordinate(lon, ew, "E", "W", 3)
+	if err != nil {
+		return geo.Latlong{}, fmt.Errorf("bad longitude: %w", err)
+	}
+	return geo.Latlong{Lat: latitude, Long: longitude}, nil
+}
+
+// parseNMEACoordinate converts degrees and decimal minutes, e.g. 4807.038 for
+// 48°07.038', to decimal degrees, negative in the given hemisphere.
+func parseNMEACoordinate(value, hemisphere, positive, negative string, degreeDigits int) (float64, error) {
+	if len(value) < degreeDigits+2 {
+		return 0, fmt.Errorf("%q is too short", value)
+	}
+	degrees, err := strconv.Atoi(value[:degreeDigits])
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.ParseFloat(value[degreeDigits:], 64)
+	if err != nil {
+		return 0, err
+	}
+	coord := float64(degrees) + minutes/60
+	switch hemisphere {
+	case positive:
+		return coord, nil
+	case negative:
+		return -coord, nil
+	}
+	return 0, fmt.Errorf("unknown hemisphere %q", hemisphere)
+}
+
+// httpLocation is the JSON object expected from the location URL. Either field
+// may be null or missing when there is no fix.
+type httpLocation struct {
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// fetchLocation gets our location from the location URL, returning errNoFix
+// if it doesn't have one.
+func (a *App) fetchLocation(ctx context.Context) (geo.Latlong, error) {
+	ctx, cancel := context.WithTimeout(ctx, LocationTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.LocationURL, nil)
+	if err != nil {
+		return geo.Latlong{}, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return geo.Latlong{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return geo.Latlong{}, fmt.Errorf("got HTTP response code %s", res.Status)
+	}
+	var data httpLocation
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return geo.Latlong{}, err
+	}
+	if data.Latitude == nil || data.Longitude == nil {
+		return geo.Latlong{}, errNoFix
+	}
+	if err := validate.Location(*data.Latitude, *data.Longitude, false); err != nil {
+		return geo.Latlong{}, err
+	}
+	return geo.Latlong{Lat: *data.Latitude, Long: *data.Longitude}, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/skypies/geo"
+)
+
+func TestParseNMEA(t *testing.T) {
+	tests := []struct {
+		name     string
+		sentence string
+		lat      float64
+		lon      float64
+		err      error
+	}{
+		{"RMC", "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A", 48.1173, 11.516667, nil},
+		{"GGA", "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47", 48.1173, 11.516667, nil},
+		{"southern hemisphere", "$GNRMC,220516,A,3352.130,S,15112.480,E,000.0,000.0,130998,011.3,E*71", -33.868833, 151.208, nil},
+		{"RMC without fix", "$GNRMC,001225,V,,,,,,,,,,N*49", 0, 0, errNoFix},
+		{"GGA without fix", "$GPGGA,001225,,,,,0,00,,,M,,M,,*62", 0, 0, errNoFix},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			loc, err := parseNMEA(test.sentence)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if math.Abs(loc.Lat-test.lat) > 1e-6 || math.Abs(loc.Long-test.lon) > 1e-6 {
+				t.Errorf("expected %f, %f, got %f, %f", test.lat, test.lon, loc.Lat, loc.Long)
+			}
+		})
+	}
+
+	for _, sentence := range []string{
+		"$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6B",
+		"$GPGSV,2,1,08,01,40,083,46,02,17,308,41,12,07,344,39,14,22,228,45",
+		"$GPRMC,123519,A,4807.038,X,01131.000,E,022.4,084.4,230394,003.1,W",
+		"GPRMC,123519,A,4807.038,N,01131.000,E",
+		"",
+	} {
+		t.Run(sentence, func(t *testing.T) {
+			if _, err := parseNMEA(sentence); err == nil || errors.Is(err, errNoFix) {
+				t.Errorf("expected an error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFetchLocation(t *testing.T) {
+	body := `{"latitude": 42.36, "longitude": -71.01}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	app := &App{LocationURL: srv.URL}
+	loc, err := app.fetchLocation(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc.Lat != 42.36 || loc.Long != -71.01 {
+		t.Errorf("unexpected location: %+v", loc)
+	}
+
+	body = `{"latitude": null, "longitude": null}`
+	if _, err := app.fetchLocation(context.Background()); !errors.Is(err, errNoFix) {
+		t.Errorf("expected no fix, got %v", err)
+	}
+}
+
+func TestMovingLocation(t *testing.T) {
+	app := &App{
+		Latitude:            42.0,
+		Longitude:           -71.0,
+		InterestingRadiusNM: 10,
+		LocationSource:      HTTPLocation,
+		LocationReboxNM:     2,
+		fixed:               make(chan struct{}),
+	}
+	if err := app.waitForFix(canceledContext()); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected to wait for a fix, got %v", err)
+	}
+
+	start := geo.Latlong{Lat: 42.5, Long: -71.5}
+	app.setLocation(start)
+	if err := app.waitForFix(context.Background()); err != nil {
+		t.Errorf("expected a fix, got %v", err)
+	}
+	if app.myLocation() != start {
+		t.Errorf("expected the live location, got %+v", app.myLocation())
+	}
+	pos, err := app.newPosition(testPosition("A", moveNM(start, 90, 5), 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(pos.Distance-5) > 0.01 {
+		t.Errorf("expected distance from the live location, got %f", pos.Distance)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.watchSubscription(start, cancel)
+	app.setLocation(moveNM(start, 0, 1))
+	if ctx.Err() != nil {
+		t.Error("expected a small move to keep the subscription")
+	}
+	app.setLocation(moveNM(start, 0, 3))
+	if ctx.Err() == nil {
+		t.Error("expected a large move to resubscribe")
+	}
+}
+
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func TestValidateLocationSource(t *testing.T) {
+	tests := []struct {
+		name  string
+		app   *App
+		valid bool
+	}{
+		{"static", &App{LocationSource: StaticLocation}, true},
+		{"nmea", &App{LocationSource: NMEALocation, LocationDevice: "/dev/ttyUSB0", LocationReboxNM: 2}, true},
+		{"nmea without device", &App{LocationSource: NMEALocation}, false},
+		{"http", &App{LocationSource: HTTPLocation, LocationURL: "http://gps.local/", LocationInterval: 1, LocationReboxNM: 2}, true},
+		{"http without rebox distance", &App{LocationSource: HTTPLocation, LocationURL: "http://gps.local/", LocationInterval: 1}, false},
+		{"http without url", &App{LocationSource: HTTPLocation, LocationInterval: 1}, false},
+		{"unknown", &App{LocationSource: "gpsd"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := validateLocationSource(test.app); (err == nil) != test.valid {
+				t.Errorf("unexpected validation result: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Formats for log output.
+const (
+	TextLogFormat = "text"
+	JSONLogFormat = "json"
+)
+
+// newLogger creates a logger writing to w in the given format, discarding
+// records below the given level (debug, info, warn, or error).
+func newLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log-level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case TextLogFormat:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case JSONLogFormat:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log-format %q", format)
+}
+
+// fatal logs an error and exits. It is only for use during startup.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// logConfig logs the settings which took effect, so that the combination of
+// config file and flags can be checked. Credentials are left out.
+func (a *App) logConfig() {
+	slog.Info("configuration",
+		"source", a.Source,
+		"latitude", a.Latitude,
+		"longitude", a.Longitude,
+		"location_source", a.LocationSource,
+		"interesting_radius_nm", a.InterestingRadiusNM,
+		"interesting_ceiling_ft", a.InterestingCeilingFt,
+		"altitude_bands", len(a.AltitudeBands),
+		"exclusion_zones", len(a.ExclusionZones),
+		"watchlist", a.Watchlist,
+		"route_watchlist", a.RouteWatchlist,
+		"blocklist", a.Blocklist,
+		"aircraft_filter", a.AircraftFilter,
+		"military_only", a.MilitaryOnly,
+		"alert_radius_nm", a.AlertRadiusNM,
+		"alert_cooldown", a.AlertCooldown,
+		"announce", a.Announce,
+		"beep", a.Beep,
+		"dry_run", a.DryRun,
+		"tts_command", a.TTSCommand,
+		"tts_voice", a.TTSVoice,
+		"units", a.Units,
+		"output_format", a.OutputFormat,
+		"altitude_mode", a.AltitudeMode,
+		"webhook", a.WebhookURL != "",
+		"discord", a.DiscordWebhookURL != "",
+		"slack", a.SlackWebhookURL != "",
+		"mqtt_broker", a.MQTTBroker,
+		"http_listen", a.HTTPListen,
+		"metrics_listen", a.MetricsListen,
+		"db_path", a.DBPath,
+	)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	var b strings.Builder
+	logger, err := newLogger(&b, JSONLogFormat, "warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("ignored")
+	logger.Warn("lost Firehose stream", "error", "EOF")
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(b.String()), &record); err != nil {
+		t.Fatalf("expected a single JSON record, got %q: %v", b.String(), err)
+	}
+	if record["level"] != "WARN" || record["msg"] != "lost Firehose stream" || record["error"] != "EOF" {
+		t.Errorf("unexpected record: %v", record)
+	}
+
+	if _, err := newLogger(&b, "xml", "info"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if _, err := newLogger(&b, TextLogFormat, "loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
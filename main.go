@@ -6,35 +6,259 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/benburwell/firehose"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/skypies/geo"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+
+	"overhead/internal/airport"
+	"overhead/internal/bbox"
+	"overhead/internal/credentials"
+	"overhead/internal/unit"
+	"overhead/internal/validate"
+)
+
+const (
+	CleanupAfter = 10 * time.Minute
+	// CleanupInterval is how often stale flights are cleaned up even when no
+	// messages are arriving.
+	CleanupInterval = 30 * time.Second
+	WebhookTimeout  = 10 * time.Second
+	// WebhookDeadline bounds the total time spent on a webhook, including
+	// retries.
+	WebhookDeadline = time.Minute
+	// MaxWebhookRedirects is how many redirects we will follow when posting a
+	// webhook, if following redirects is enabled at all.
+	MaxWebhookRedirects = 10
+	// MaxAnnouncedETA is the furthest out a closest approach can be for its ETA
+	// to be worth announcing.
+	MaxAnnouncedETA = 5 * time.Minute
+	// InitialBackoff and MaxBackoff bound how long we wait between attempts to
+	// open the Firehose stream.
+	InitialBackoff = time.Second
+	MaxBackoff     = time.Minute
+	// RegDedupWindow is how long after alerting on a registration we will
+	// ignore it showing up again under a different flight ID.
+	RegDedupWindow = 10 * time.Minute
+	// ShutdownTimeout bounds how long we wait for pending alerts to finish
+	// when exiting.
+	ShutdownTimeout = 5 * time.Second
+	// ZuluTimeFormat renders times in UTC the way they are written in aviation.
+	ZuluTimeFormat = "15:04Z"
+	// EnvPrefix begins the names of environment variables settings can be
+	// given in.
+	EnvPrefix = "OVERHEAD"
+	// DefaultTimestampFormat renders times of day when displaying flights.
+	DefaultTimestampFormat = "15:04:05"
+	// ShortTimestampFormat renders times of day to the minute, for summaries
+	// spanning several minutes, unless a timestamp format is configured.
+	ShortTimestampFormat = "15:04"
+)
+
+// Vertical states of a flight.
+const (
+	Climbing   = "climbing"
+	Descending = "descending"
+	Level      = "level"
+	// LevelDeadbandFPM is the vertical rate either side of zero within which a
+	// flight is considered level.
+	LevelDeadbandFPM = 200
+)
+
+// Whether a flight is heading toward or away from us.
+const (
+	Approaching = "approaching"
+	Departing   = "departing"
+	// ApproachToleranceDeg is how far either side of the bearing to us a
+	// flight's heading may be for it to count as approaching.
+	ApproachToleranceDeg = 90
+)
+
+// Events which can trigger an alert.
+const (
+	// AlertOnApproach alerts when a flight comes closer within the alert
+	// radius.
+	AlertOnApproach = "approach"
+	// AlertOnDepart alerts once when a flight leaves the alert radius.
+	AlertOnDepart = "depart"
+	// AlertOnBoth alerts on both approaching and departing flights.
+	AlertOnBoth = "both"
+)
+
+// Styles for speaking distances.
+const (
+	// PreciseDistance speaks distances to a tenth of a mile.
+	PreciseDistance = "precise"
+	// FriendlyDistance avoids overly precise figures when they aren't useful,
+	// for very close or fairly distant flights.
+	FriendlyDistance = "friendly"
 )
 
+// Ways of wording spoken distances.
 const (
-	CleanupAfter   = 10 * time.Minute
-	WebhookTimeout = 10 * time.Second
+	// PhoneticDistanceSpeech reads out the figure digit by digit according to
+	// SpokenDistanceStyle, e.g. "two point five nautical miles".
+	PhoneticDistanceSpeech = "phonetic"
+	// NaturalDistanceSpeech rounds to a fraction that's easier on the ear,
+	// e.g. "2 and a half miles".
+	NaturalDistanceSpeech = "natural"
 )
 
+// Styles for pronouncing digits.
+const (
+	// StandardPhonetics speaks digits as English numbers, except for 9 which
+	// is niner.
+	StandardPhonetics = "standard"
+	// AviationPhonetics follows radio convention, speaking 3, 5, and 9 as
+	// tree, fife, and niner.
+	AviationPhonetics = "aviation"
+)
+
+// Ways of reporting a flight's altitude.
+const (
+	// AltitudeModeMSL reports altitude above mean sea level, as broadcast.
+	AltitudeModeMSL = "msl"
+	// AltitudeModeRelative reports height above or below the observer's
+	// elevation.
+	AltitudeModeRelative = "relative"
+)
+
+// webhookBackoff is how long to wait before the first retry of a webhook.
+var webhookBackoff = time.Second
+
+// firehoseBackoff is how long to wait before the first retry of opening the
+// Firehose stream.
+var firehoseBackoff = InitialBackoff
+
+// ErrAuthentication indicates that Firehose rejected our credentials. Retrying
+// will not help, so it is always fatal.
+var ErrAuthentication = errors.New("firehose authentication failed")
+
+// ErrStreamStalled indicates that nothing arrived on the Firehose stream for
+// longer than the stream timeout, as happens on a half-open connection.
+var ErrStreamStalled = errors.New("firehose stream stalled")
+
 func main() {
+	pflag.String("source", FirehoseSource, "Where to get aircraft positions from: firehose or dump1090")
+	pflag.String("dump1090-url", "http://localhost:8080/data/aircraft.json", "URL of dump1090's aircraft.json, when the source is dump1090")
+	pflag.Duration("dump1090-interval", time.Second, "How often to poll dump1090 for aircraft positions")
+	pflag.String("location-source", StaticLocation, "Where to get our location from: static for latitude and longitude, nmea for a GPS, or http")
+	pflag.String("location-device", "", "Serial device of an NMEA GPS receiver, when the location-source is nmea, e.g. /dev/ttyUSB0")
+	pflag.String("location-url", "", "URL returning a JSON object with our latitude and longitude, when the location-source is http")
+	pflag.Duration("location-interval", 10*time.Second, "How often to poll the location-url")
+	pflag.Float64("location-rebox-distance", 2, "Distance in nautical miles to move before resubscribing around a moving location")
+	pflag.String("replay-file", "", "Replay position messages from a file of newline-delimited JSON instead of connecting to a source")
+	pflag.Bool("replay-realtime", false, "Replay messages with their original timing rather than as fast as possible")
 	pflag.String("username", "", "Username for Firehose authentication")
-	pflag.String("password", "", "Password for Firehose authentication")
-	pflag.Float64("interesting-radius", 10, "Radius in nautical miles around location to watch for flights")
+	pflag.String("password", "", "Password for Firehose authentication (or set OVERHEAD_PASSWORD)")
+	pflag.String("password-file", "", "File to read the Firehose password from")
+	pflag.Float64("interesting-radius", 10, "Radius in nautical miles around location to watch for flights, or 0 to watch the whole observation-box or geofence")
 	pflag.Float64("interesting-ceiling", 15000, "Maximum altitude in feet to watch for flights")
+	pflag.Float64("interesting-floor", 0, "Minimum altitude in feet to watch for flights")
+	pflag.Bool("exclude-unknown-altitude", false, "Ignore flights that do not report an altitude")
+	pflag.Float64("min-speed", 0, "Minimum ground speed in knots of interesting flights")
+	pflag.Float64("max-speed", 0, "Maximum ground speed in knots of interesting flights, or 0 for no maximum")
+	pflag.Bool("filter-missing-speed", false, "Ignore flights that do not report a speed when min-speed or max-speed is set")
 	pflag.Float64("alert-radius", 3, "Radius in nautical miles around location to alert on approaching flights")
 	pflag.Bool("announce", false, "Aurally announce approaching aircraft")
+	pflag.String("spoken-distance-style", PreciseDistance, "How to speak distances: precise or friendly")
+	pflag.String("distance-speech", PhoneticDistanceSpeech, "How to word spoken distances: phonetic, or natural for e.g. two and a half miles")
+	pflag.String("phonetic-style", StandardPhonetics, "How to pronounce digits: standard, or aviation for tree, fife, and niner")
+	pflag.String("altitude-mode", AltitudeModeMSL, "How to report altitudes: msl, or relative to observer-elevation")
+	pflag.Float64("observer-elevation", 0, "Elevation of the observer in feet MSL, for relative altitudes")
+	pflag.String("tts-command", defaultTTSCommand(), "Text-to-speech command used for announcements, e.g. say or espeak")
+	pflag.Duration("tts-timeout", 30*time.Second, "Maximum time to allow a single announcement to take")
+	pflag.Int("tts-rate", DefaultTTSRate, "Speaking rate for announcements in words per minute")
+	pflag.Duration("tts-max-age", 15*time.Second, "Skip announcements which waited longer than this behind others (0 to never skip)")
+	pflag.String("tts-voice", "", "Voice for announcements, e.g. Samantha for say or en-us for espeak (default is the system voice)")
+	pflag.Bool("beep", false, "Play a sound when a flight alerts, whether or not it is announced")
+	pflag.String("beep-command", defaultBeepCommand(), "Sound player used for the alert sound, e.g. afplay or aplay")
+	pflag.String("beep-sound", defaultBeepSound(), "Sound file to play when a flight alerts, or empty for a tone")
+	pflag.Float64("transition-altitude", 18000, "Altitude in feet at or above which to announce flight levels (0 to disable)")
+	pflag.Bool("announce-eta", false, "Include the estimated time until closest approach in announcements")
+	pflag.Float64("magnetic-declination", 0, "Magnetic declination in degrees at your location (east positive)")
+	pflag.Int("compass-points", 8, "Number of compass points to describe bearings with: 8 or 16")
+	pflag.Bool("show-both-bearings", false, "Show bearings relative to both true and magnetic north")
+	pflag.String("callsign-file", "", "CSV file mapping ICAO or IATA airline codes to spoken callsigns")
+	pflag.String("airport-file", "", "CSV file mapping ICAO or IATA airport codes to names for display")
+	pflag.String("type-aliases", "", "CSV file mapping aircraft type codes to canonical names")
+	pflag.String("type-names", "", "CSV file mapping aircraft type codes to full names for display")
+	pflag.Bool("announce-type-names", false, "Include the full aircraft type name in announcements")
 	pflag.String("webhook-url", "", "URL to optionally send position updates to")
+	pflag.String("discord-webhook-url", "", "Discord webhook URL to optionally post alerts to")
+	pflag.String("slack-webhook-url", "", "Slack incoming webhook URL to optionally post alerts to")
+	pflag.Bool("alert-convergence", false, "Alert when two flights near you are converging on each other")
+	pflag.Float64("convergence-distance", 1, "Lateral separation in nautical miles at which converging flights are alerted on")
+	pflag.Bool("proximity-warning", false, "Urgently warn about flights that are very close and very low")
+	pflag.Float64("proximity-warning-radius", 0.5, "Radius in nautical miles within which to warn about low flights")
+	pflag.Float64("proximity-warning-altitude", 500, "Altitude in feet below which to warn about close flights, above the observer in relative altitude mode")
+	pflag.Duration("alert-cooldown", time.Minute, "Minimum time between alerts for the same flight")
+	pflag.String("alert-on", AlertOnApproach, "When to alert on flights: approach, depart, or both")
+	pflag.Bool("notify-on-appear", false, "Log and send a webhook when an interesting flight first appears, before it alerts")
+	pflag.Bool("alert-once", false, "Alert on each flight at most once while it is being tracked")
+	pflag.Float64("alert-hysteresis-nm", 0, "Distance in nautical miles beyond the alert radius a flight must go before it can alert again")
+	pflag.Bool("pass-summary", false, "Log a summary of each flight's pass once it is no longer tracked")
+	pflag.Bool("pass-summary-webhook", false, "Also send pass summaries to the webhook URL")
+	pflag.String("summary-file", "", "File to write a JSON summary of the session to on exit, in addition to printing it")
+	pflag.String("watchlist-mode", WatchlistAlso, "How the watchlist combines with the radius and altitude checks: only or also")
+	pflag.String("aircraft-filter-mode", AircraftFilterExclude, "Whether flights in the aircraft-filter categories are the only ones watched or ignored: include or exclude")
+	pflag.Bool("exclude-unknown-category", false, "Ignore flights whose aircraft category is unknown when aircraft-filter is set")
+	pflag.Bool("require-airport", false, "Only watch flights with a known origin or destination airport")
+	pflag.Bool("military-only", false, "Only watch flights that look like military traffic, by transponder address, squawk, or callsign")
+	pflag.Bool("dedup-by-reg", false, "Don't re-alert on a registration that recently alerted under a different flight ID")
+	pflag.String("units", ImperialUnits, "Units to display and speak distances, altitudes, and speeds in: imperial or metric")
+	pflag.String("distance-precision", "1", "Decimal places to display distances to, or adaptive for more when closer")
+	pflag.Bool("zulu", false, "Display and announce times in UTC (zulu)")
+	pflag.String("timezone", "", "Timezone to display times and observe quiet-hours in, e.g. America/New_York (default local time)")
+	pflag.String("timestamp-format", DefaultTimestampFormat, "Go time layout to display times with, e.g. \"3:04:05 PM\" or \"2006-01-02 15:04:05\"")
+	pflag.Bool("include-observer", false, "Include the observer location in webhook payloads")
+	pflag.String("station-id", "", "Name identifying this observer in webhook payloads")
+	pflag.String("db-path", "", "SQLite database to optionally record every interesting position in")
+	pflag.String("mqtt-broker", "", "MQTT broker to optionally publish alerts to, e.g. tcp://localhost:1883")
+	pflag.String("mqtt-topic", "overhead/alerts", "MQTT topic to publish alerts to")
+	pflag.String("mqtt-username", "", "Username for MQTT authentication")
+	pflag.String("mqtt-password", "", "Password for MQTT authentication")
+	pflag.String("mqtt-client-id", "", "Client ID to connect to the MQTT broker with (default overhead-<hostname>)")
+	pflag.String("webhook-template", "", "Go template file or inline template to render webhook bodies with")
+	pflag.String("webhook-content-type", "", "Content type of webhook bodies, guessed from the body if unset")
+	pflag.Int("webhook-retries", 3, "How many times to retry a webhook after a connection error or 5xx response")
+	pflag.Duration("webhook-min-interval", 10*time.Second, "Minimum time between webhooks for the same flight")
+	pflag.Bool("webhook-compress", false, "Gzip webhook bodies, sending them with Content-Encoding: gzip")
+	pflag.Bool("webhook-follow-redirects", false, "Follow redirects returned by the webhook URL, re-sending the POST")
+	pflag.String("http-listen", "", "Address on which to serve the tracked flights, their trails, a WebSocket stream of positions, and a health check over HTTP, e.g. :8080")
+	pflag.Bool("dry-run", false, "Log which flights would alert without displaying, announcing, recording, or sending them anywhere")
+	pflag.String("status-file", "", "File to keep the most recently alerted flight in as JSON")
+	pflag.String("metrics-listen", "", "Address on which to serve Prometheus metrics, e.g. :9100")
+	pflag.Duration("stream-timeout", time.Minute, "Reconnect if no message of any kind arrives from Firehose for this long (0 to wait forever)")
+	pflag.Bool("init-retry", true, "Retry with backoff if the Firehose stream cannot be initialized at startup, rather than exiting")
+	pflag.Float64("max-radius", 100, "Maximum radius in nautical miles that may be configured")
+	pflag.Int("trail-length", 1, "Number of recent positions to keep for each flight, served by the HTTP API")
+	pflag.Int("max-flights", 0, "Maximum number of flights to track at once, evicting the least recently updated, or 0 for no limit")
+	pflag.Bool("clamp-radius", false, "Reduce a radius exceeding max-radius to the maximum instead of exiting")
+	pflag.Bool("allow-null-island", false, "Allow watching latitude 0, longitude 0")
+	pflag.String("log-format", TextLogFormat, "Format of log output: text or json")
+	pflag.String("output-format", TextOutput, "Format of alerts written to stdout: text or jsonl")
+	pflag.String("color", ColorAuto, "Whether to color alerts written to stdout: auto, always, or never")
+	pflag.String("log-level", "info", "Minimum level of log output: debug, info, warn, or error")
+	listCallsigns := pflag.Bool("list-callsigns", false, "List the airline callsigns that will be spoken and exit")
+	listTemplates := pflag.Bool("list-templates", false, "Print the default templates for each sink and exit")
+	selfTest := pflag.Bool("selftest", false, "Speak a test phrase and exit, to check text-to-speech")
 	configFile := pflag.StringP("config-file", "c", "overhead.toml", "Config file name")
 	showHelp := pflag.BoolP("help", "h", false, "Show help")
 	pflag.Parse()
@@ -44,6 +268,11 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *listTemplates {
+		printTemplates(os.Stdout, DefaultTemplates)
+		os.Exit(0)
+	}
+
 	// If the user has specified a particular config file, read that one.
 	if *configFile != "" {
 		viper.SetConfigFile(*configFile)
@@ -54,82 +283,836 @@ func main() {
 		viper.AddConfigPath(".")
 	}
 	if err := viper.ReadInConfig(); err != nil {
-		log.Fatal(err.Error())
+		fatal("could not read config file", "error", err)
 	}
 
 	if err := viper.BindPFlags(pflag.CommandLine); err != nil {
-		log.Fatal(err.Error())
+		fatal("could not bind flags", "error", err)
+	}
+	// Any setting can also come from an environment variable such as
+	// OVERHEAD_PASSWORD or OVERHEAD_ALERT_RADIUS.
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
+
+	logger, err := newLogger(os.Stderr, viper.GetString("log-format"), viper.GetString("log-level"))
+	if err != nil {
+		fatal("invalid logging configuration", "error", err)
+	}
+	slog.SetDefault(logger)
+
+	if *selfTest {
+		app := &App{
+			TTSCommand:    viper.GetString("tts-command"),
+			TTSTimeout:    viper.GetDuration("tts-timeout"),
+			TTSRate:       viper.GetInt("tts-rate"),
+			TTSVoice:      viper.GetString("tts-voice"),
+			PhoneticStyle: viper.GetString("phonetic-style"),
+		}
+		if err := app.selfTest(); err != nil {
+			fatal("self test failed", "error", err)
+		}
+		slog.Info("self test passed", "phrase", app.selfTestPhrase())
+		os.Exit(0)
+	}
+
+	if *listCallsigns {
+		app := &App{CallsignFile: viper.GetString("callsign-file")}
+		if err := app.loadCallsigns(); err != nil {
+			fatal("could not load callsigns", "error", err)
+		}
+		printCallsigns(os.Stdout, app.allCallsigns())
+		os.Exit(0)
+	}
+
+	var exclusionZones []Zone
+	if err := viper.UnmarshalKey("exclusion-zones", &exclusionZones); err != nil {
+		fatal("invalid exclusion-zones", "error", err)
+	}
+
+	var geofence Polygon
+	if err := viper.UnmarshalKey("geofence", &geofence); err != nil {
+		fatal("invalid geofence", "error", err)
+	}
+
+	var altitudeBands []AltitudeBand
+	if err := viper.UnmarshalKey("altitude-bands", &altitudeBands); err != nil {
+		fatal("invalid altitude-bands", "error", err)
+	}
+
+	var typeNames map[string]string
+	if path := viper.GetString("type-names"); path != "" {
+		if typeNames, err = loadTypeNames(path); err != nil {
+			fatal("could not load type names", "error", err)
+		}
+	}
+
+	var timezone *time.Location
+	if name := viper.GetString("timezone"); name != "" {
+		if timezone, err = time.LoadLocation(name); err != nil {
+			fatal("invalid timezone", "timezone", name, "error", err)
+		}
+	}
+
+	var quietHours *QuietHours
+	if viper.IsSet("quiet-hours") {
+		// Quiet hours are in the display timezone unless given their own.
+		zone := viper.GetString("quiet-hours.timezone")
+		if zone == "" {
+			zone = viper.GetString("timezone")
+		}
+		quietHours, err = parseQuietHours(
+			viper.GetString("quiet-hours.start"),
+			viper.GetString("quiet-hours.end"),
+			zone,
+		)
+		if err != nil {
+			fatal("invalid quiet-hours", "error", err)
+		}
+	}
+
+	var typeAliases map[string]string
+	if path := viper.GetString("type-aliases"); path != "" {
+		if typeAliases, err = loadTypeAliases(path); err != nil {
+			fatal("could not load type aliases", "error", err)
+		}
+	}
+
+	var observationBox *firehose.Rectangle
+	if viper.IsSet("observation-box") {
+		observationBox = &firehose.Rectangle{
+			LowLat: viper.GetFloat64("observation-box.low-lat"),
+			LowLon: viper.GetFloat64("observation-box.low-lon"),
+			HiLat:  viper.GetFloat64("observation-box.high-lat"),
+			HiLon:  viper.GetFloat64("observation-box.high-lon"),
+		}
 	}
 
+	// Password and radius problems are reported along with the rest of the
+	// configuration's.
+	var problems []error
+	passwordInEnv := credentials.InEnv(EnvPrefix + "_PASSWORD")
+	password, err := credentials.ResolvePassword(viper.GetString("password"), pflag.CommandLine.Changed("password") || passwordInEnv, viper.GetString("password-file"))
+	if err != nil {
+		problems = append(problems, err)
+	}
+	maxRadius, clampRadius := viper.GetFloat64("max-radius"), viper.GetBool("clamp-radius")
+	limitRadius := func(name string) float64 {
+		radius, err := validate.Radius(name, viper.GetFloat64(name), maxRadius, clampRadius)
+		if err != nil {
+			problems = append(problems, err)
+			return viper.GetFloat64(name)
+		}
+		return radius
+	}
+	interestingRadius := limitRadius("interesting-radius")
+	alertRadius := limitRadius("alert-radius")
+
 	app := &App{
-		Username:             viper.GetString("username"),
-		Password:             viper.GetString("password"),
-		Latitude:             viper.GetFloat64("latitude"),
-		Longitude:            viper.GetFloat64("longitude"),
-		InterestingRadiusNM:  viper.GetFloat64("interesting-radius"),
-		InterestingCeilingFt: viper.GetFloat64("interesting-ceiling"),
-		AlertRadiusNM:        viper.GetFloat64("alert-radius"),
-		Announce:             viper.GetBool("announce"),
-		WebhookURL:           viper.GetString("webhook-url"),
+		Source:                 viper.GetString("source"),
+		Dump1090URL:            viper.GetString("dump1090-url"),
+		Dump1090Interval:       viper.GetDuration("dump1090-interval"),
+		ReplayFile:             viper.GetString("replay-file"),
+		ReplayRealtime:         viper.GetBool("replay-realtime"),
+		Username:               viper.GetString("username"),
+		Password:               password,
+		Latitude:               viper.GetFloat64("latitude"),
+		Longitude:              viper.GetFloat64("longitude"),
+		LocationSource:         viper.GetString("location-source"),
+		LocationDevice:         viper.GetString("location-device"),
+		LocationURL:            viper.GetString("location-url"),
+		LocationInterval:       viper.GetDuration("location-interval"),
+		LocationReboxNM:        viper.GetFloat64("location-rebox-distance"),
+		InterestingRadiusNM:    interestingRadius,
+		InterestingCeilingFt:   viper.GetFloat64("interesting-ceiling"),
+		InterestingFloorFt:     viper.GetFloat64("interesting-floor"),
+		AltitudeBands:          altitudeBands,
+		ExcludeUnknownAlt:      viper.GetBool("exclude-unknown-altitude"),
+		MinSpeedKts:            viper.GetFloat64("min-speed"),
+		MaxSpeedKts:            viper.GetFloat64("max-speed"),
+		FilterMissingSpeed:     viper.GetBool("filter-missing-speed"),
+		AlertRadiusNM:          alertRadius,
+		Announce:               viper.GetBool("announce"),
+		AnnounceETA:            viper.GetBool("announce-eta"),
+		QuietHours:             quietHours,
+		Beep:                   viper.GetBool("beep"),
+		BeepCommand:            viper.GetString("beep-command"),
+		BeepSound:              viper.GetString("beep-sound"),
+		TransitionAltitudeFt:   viper.GetFloat64("transition-altitude"),
+		TTSCommand:             viper.GetString("tts-command"),
+		TTSTimeout:             viper.GetDuration("tts-timeout"),
+		TTSRate:                viper.GetInt("tts-rate"),
+		TTSVoice:               viper.GetString("tts-voice"),
+		TTSMaxAge:              viper.GetDuration("tts-max-age"),
+		SpokenDistanceStyle:    viper.GetString("spoken-distance-style"),
+		DistanceSpeech:         viper.GetString("distance-speech"),
+		AltitudeMode:           viper.GetString("altitude-mode"),
+		PhoneticStyle:          viper.GetString("phonetic-style"),
+		ObserverElevationFt:    viper.GetFloat64("observer-elevation"),
+		MagneticDeclination:    viper.GetFloat64("magnetic-declination"),
+		ShowBothBearings:       viper.GetBool("show-both-bearings"),
+		CompassPoints:          viper.GetInt("compass-points"),
+		WebhookURL:             viper.GetString("webhook-url"),
+		DiscordWebhookURL:      viper.GetString("discord-webhook-url"),
+		SlackWebhookURL:        viper.GetString("slack-webhook-url"),
+		InitRetry:              viper.GetBool("init-retry"),
+		StreamTimeout:          viper.GetDuration("stream-timeout"),
+		HTTPListen:             viper.GetString("http-listen"),
+		MetricsListen:          viper.GetString("metrics-listen"),
+		StatusFile:             viper.GetString("status-file"),
+		MaxFlights:             viper.GetInt("max-flights"),
+		TrailLength:            viper.GetInt("trail-length"),
+		DryRun:                 viper.GetBool("dry-run"),
+		WebhookFollowRedirects: viper.GetBool("webhook-follow-redirects"),
+		WebhookMinInterval:     viper.GetDuration("webhook-min-interval"),
+		WebhookRetries:         viper.GetInt("webhook-retries"),
+		WebhookContentType:     viper.GetString("webhook-content-type"),
+		WebhookCompress:        viper.GetBool("webhook-compress"),
+		DBPath:                 viper.GetString("db-path"),
+		MQTTBroker:             viper.GetString("mqtt-broker"),
+		MQTTTopic:              viper.GetString("mqtt-topic"),
+		MQTTUsername:           viper.GetString("mqtt-username"),
+		MQTTPassword:           viper.GetString("mqtt-password"),
+		MQTTClientID:           viper.GetString("mqtt-client-id"),
+		IncludeObserver:        viper.GetBool("include-observer"),
+		StationID:              viper.GetString("station-id"),
+		Zulu:                   viper.GetBool("zulu"),
+		Timezone:               timezone,
+		TimestampFormat:        viper.GetString("timestamp-format"),
+		Units:                  viper.GetString("units"),
+		DistancePrecision:      viper.GetString("distance-precision"),
+		OutputFormat:           viper.GetString("output-format"),
+		Color:                  viper.GetString("color"),
+		DedupByReg:             viper.GetBool("dedup-by-reg"),
+		AlertCooldown:          viper.GetDuration("alert-cooldown"),
+		AlertOnce:              viper.GetBool("alert-once"),
+		AlertOn:                viper.GetString("alert-on"),
+		NotifyOnAppear:         viper.GetBool("notify-on-appear"),
+		AlertHysteresisNM:      viper.GetFloat64("alert-hysteresis-nm"),
+		PassSummary:            viper.GetBool("pass-summary"),
+		PassSummaryWebhook:     viper.GetBool("pass-summary-webhook"),
+		SummaryFile:            viper.GetString("summary-file"),
+		ProximityWarning:       viper.GetBool("proximity-warning"),
+		ProximityRadiusNM:      viper.GetFloat64("proximity-warning-radius"),
+		ProximityAltitudeFt:    viper.GetFloat64("proximity-warning-altitude"),
+		AlertConvergence:       viper.GetBool("alert-convergence"),
+		ConvergenceNM:          viper.GetFloat64("convergence-distance"),
+		ExclusionZones:         exclusionZones,
+		Geofence:               geofence,
+		Watchlist:              viper.GetStringSlice("watchlist"),
+		WatchlistMode:          viper.GetString("watchlist-mode"),
+		RouteWatchlist:         viper.GetStringSlice("route-watchlist"),
+		Blocklist:              viper.GetStringSlice("blocklist"),
+		AircraftFilter:         viper.GetStringSlice("aircraft-filter"),
+		AircraftFilterMode:     viper.GetString("aircraft-filter-mode"),
+		ExcludeUnknownCategory: viper.GetBool("exclude-unknown-category"),
+		MilitaryOnly:           viper.GetBool("military-only"),
+		RequireAirport:         viper.GetBool("require-airport"),
+		MilitaryCallsigns:      viper.GetStringSlice("military-callsigns"),
+		ObservationBox:         observationBox,
+		TypeAliases:            typeAliases,
+		TypeNames:              typeNames,
+		AnnounceTypeNames:      viper.GetBool("announce-type-names"),
+		CallsignFile:           viper.GetString("callsign-file"),
+		AirportFile:            viper.GetString("airport-file"),
+	}
+	problems = append(problems, validateConfig(app, viper.GetBool("allow-null-island"))...)
+	if len(problems) > 0 {
+		for _, err := range problems {
+			slog.Error("invalid configuration", "error", err)
+		}
+		os.Exit(1)
+	}
+
+	sources := viper.GetStringMapString("templates")
+	if v := viper.GetString("webhook-template"); v != "" {
+		if _, ok := sources[WebhookSink]; ok {
+			fatal("webhook-template and templates.webhook cannot both be set")
+		}
+		src, err := readTemplateSource(v)
+		if err != nil {
+			fatal("could not read webhook-template", "error", err)
+		}
+		if sources == nil {
+			sources = make(map[string]string)
+		}
+		sources[WebhookSink] = src
+	}
+	templates, err := parseTemplates(sources, app.templateFuncs())
+	if err != nil {
+		fatal("invalid templates", "error", err)
+	}
+	app.Templates = templates
+	if app.TypeTemplates, err = parseTypeTemplates(viper.GetStringMapString("type-templates"), app.templateFuncs()); err != nil {
+		fatal("invalid type-templates", "error", err)
+	}
+
+	if app.Announce {
+		app.checkTTSVoice()
 	}
+	app.logConfig()
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
 	if err := app.Run(ctx); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		fatal("exiting", "error", err)
 	}
 }
 
 type App struct {
-	Username             string
-	Password             string
-	Latitude             float64
-	Longitude            float64
+	// Source is where positions come from, FirehoseSource or Dump1090Source.
+	// The remaining Firehose settings are ignored when using dump1090.
+	Source string
+	// Dump1090URL is polled every Dump1090Interval for aircraft.json.
+	Dump1090URL      string
+	Dump1090Interval time.Duration
+	// ReplayFile, if set, is read for position messages instead of Source,
+	// after which Run returns. ReplayRealtime keeps the original timing
+	// between messages.
+	ReplayFile     string
+	ReplayRealtime bool
+	Username       string
+	Password       string
+	Latitude       float64
+	Longitude      float64
+	// InterestingRadiusNM is how far away flights may be and still be
+	// interesting. Zero disables the distance check entirely, leaving it to
+	// ObservationBox to determine which flights we hear about.
 	InterestingRadiusNM  float64
 	InterestingCeilingFt float64
-	AlertRadiusNM        float64
-	Announce             bool
-	WebhookURL           string
+	InterestingFloorFt   float64
+	// AltitudeBands, if set, replaces the interesting floor and ceiling with a
+	// set of altitude ranges, any of which a flight may be in to be
+	// interesting.
+	AltitudeBands []AltitudeBand
+	// ExcludeUnknownAlt ignores flights which are not reporting an altitude,
+	// or whose altitude is malformed.
+	ExcludeUnknownAlt bool
+	// MinSpeedKts and MaxSpeedKts bound the ground speed of interesting
+	// flights; a MaxSpeedKts of 0 means there is no maximum. Flights not
+	// reporting a speed are ignored only if FilterMissingSpeed is set.
+	MinSpeedKts        float64
+	MaxSpeedKts        float64
+	FilterMissingSpeed bool
+	AlertRadiusNM      float64
+	Announce           bool
+	AnnounceETA        bool
+	// QuietHours optionally suppresses announcements overnight. Alerts are
+	// still displayed and sent to the webhook.
+	QuietHours *QuietHours
+	// Beep plays BeepSound with BeepCommand when a flight alerts, or a tone if
+	// BeepSound is empty. It is independent of Announce, but also respects
+	// QuietHours.
+	Beep        bool
+	BeepCommand string
+	BeepSound   string
+	// TransitionAltitudeFt is the altitude at and above which altitudes are
+	// announced as flight levels.
+	TransitionAltitudeFt float64
+	// TTSCommand is the text-to-speech program to run for announcements.
+	TTSCommand string
+	// TTSTimeout bounds how long the speech command may run before it is
+	// killed.
+	TTSTimeout time.Duration
+	// TTSRate is the speaking rate in words per minute, and TTSVoice the
+	// voice to speak in if not the default. How they are passed depends on
+	// the command.
+	TTSRate  int
+	TTSVoice string
+	// TTSMaxAge is how long an announcement may wait behind others before it
+	// is too stale to be worth speaking, or 0 to speak them all.
+	TTSMaxAge time.Duration
+	// SpokenDistanceStyle is one of PreciseDistance or FriendlyDistance.
+	SpokenDistanceStyle string
+	// DistanceSpeech is PhoneticDistanceSpeech or NaturalDistanceSpeech, in
+	// which case SpokenDistanceStyle doesn't apply.
+	DistanceSpeech string
+	// PhoneticStyle is StandardPhonetics or AviationPhonetics.
+	PhoneticStyle string
+	// AltitudeMode is one of AltitudeModeMSL or AltitudeModeRelative, in which
+	// case altitudes are displayed and announced relative to
+	// ObserverElevationFt.
+	AltitudeMode        string
+	ObserverElevationFt float64
+	// MagneticDeclination is the angle in degrees between true and magnetic
+	// north at our location, positive when magnetic north lies to the east.
+	MagneticDeclination float64
+	ShowBothBearings    bool
+	// CompassPoints is how finely bearings to flights are described, 8 or 16
+	// points. Headings are always described with 8.
+	CompassPoints int
+	WebhookURL    string
+	// DiscordWebhookURL is a Discord channel webhook to post alerts to as
+	// embeds, independently of WebhookURL.
+	DiscordWebhookURL string
+	// SlackWebhookURL is a Slack incoming webhook to post alerts to,
+	// independently of WebhookURL.
+	SlackWebhookURL string
+	// WebhookFollowRedirects re-sends the webhook to wherever the URL redirects
+	// to. Otherwise the redirect is logged and not followed.
+	WebhookFollowRedirects bool
+	// WebhookMinInterval is the minimum time between webhooks for the same
+	// flight. Updates within it are dropped.
+	WebhookMinInterval time.Duration
+	// WebhookRetries is how many times to retry a webhook which fails with a
+	// connection error or a 5xx response.
+	WebhookRetries int
+	// WebhookContentType overrides the content type of webhook bodies, which
+	// is otherwise application/json if the body is valid JSON or text/plain.
+	WebhookContentType string
+	// WebhookCompress gzips webhook bodies of at least WebhookCompressMinSize
+	// bytes.
+	WebhookCompress bool
+	// IncludeObserver adds our location and StationID to webhook payloads, so
+	// that a backend collecting from several stations can tell them apart.
+	IncludeObserver bool
+	StationID       string
+	// DBPath is the SQLite database in which to log interesting positions, if
+	// any. It is created if it doesn't exist.
+	DBPath string
+	// MQTTBroker is the URL of an MQTT broker to publish alerts to on
+	// MQTTTopic, if any.
+	MQTTBroker   string
+	MQTTTopic    string
+	MQTTUsername string
+	MQTTPassword string
+	// MQTTClientID identifies us to the broker, which disconnects any other
+	// client using the same ID. It defaults to one based on our hostname.
+	MQTTClientID string
+	// InitRetry controls whether failures to first connect to or initialize
+	// the Firehose stream are retried with backoff or returned immediately.
+	// Reconnecting after the stream drops is always retried.
+	InitRetry bool
+	// StreamTimeout is how long to wait for the next message from Firehose,
+	// including keepalives, before reconnecting. Zero waits forever.
+	StreamTimeout time.Duration
+	// HTTPListen is the address on which to serve the HTTP API, if any.
+	HTTPListen string
+	// MetricsListen is the address on which to serve Prometheus metrics, if
+	// any.
+	MetricsListen string
+	// StatusFile is a file in which the most recently alerted flight is kept
+	// as JSON for other tools to read, and emptied once that flight is
+	// forgotten.
+	StatusFile string
+	// MaxFlights caps how many flights are tracked at once. When a new flight
+	// would exceed it, the least recently updated flight is forgotten.
+	MaxFlights int
+	// TrailLength is how many of each flight's most recent positions are
+	// kept, for drawing its track.
+	TrailLength int
+	// DryRun logs alerts instead of displaying, announcing, or sending them,
+	// for trying out settings. Nothing is recorded to DBPath either.
+	DryRun bool
+	// Zulu renders times in UTC and prefixes announcements with the time.
+	Zulu bool
+	// Timezone is where displayed times are rendered, or local time if nil.
+	// TimestampFormat is the layout they are rendered with. Both are ignored
+	// if Zulu is set.
+	Timezone        *time.Location
+	TimestampFormat string
+	// Units is the system of units to display, ImperialUnits or MetricUnits.
+	// Spoken distances are also in these units.
+	// Positions are always stored in the units Firehose reports.
+	Units string
+	// DistancePrecision is the number of decimal places to display distances
+	// to, or precision.Adaptive.
+	DistancePrecision string
+	// OutputFormat is how alerts are written to stdout, TextOutput or
+	// JSONLinesOutput. Logs always go to stderr.
+	OutputFormat string
+	// Color is ColorAuto, ColorAlways, or ColorNever, deciding whether text
+	// alerts are colored.
+	Color string
+	// DedupByReg suppresses alerts for an aircraft that Firehose has started
+	// reporting under a new flight ID, keyed by registration.
+	DedupByReg bool
+	// AlertCooldown is the minimum time between alerts for the same flight.
+	AlertCooldown time.Duration
+	// AlertOnce only alerts on the first qualifying approach of each flight,
+	// until it goes stale and is forgotten.
+	AlertOnce bool
+	// AlertOn is one of AlertOnApproach, AlertOnDepart, or AlertOnBoth. If
+	// empty, flights alert on approach.
+	AlertOn string
+	// NotifyOnAppear logs and sends a webhook for each interesting flight when
+	// it is first seen, separately from its alerts.
+	NotifyOnAppear bool
+	// AlertHysteresisNM stops a flight alerting again after an alert until it
+	// has gone further than AlertRadiusNM plus this distance from us, so that
+	// jitter near the edge of the alert radius doesn't cause repeat alerts.
+	AlertHysteresisNM float64
+	// PassSummary logs when each flight was first and last seen, and how close
+	// it came, once we stop tracking it. PassSummaryWebhook additionally sends
+	// the summary to the webhook.
+	PassSummary        bool
+	PassSummaryWebhook bool
+	// SummaryFile, if set, is where a JSON summary of what was seen is written
+	// on exit, as well as being printed to SummaryOutput, or stdout if that is
+	// nil.
+	SummaryFile   string
+	SummaryOutput io.Writer
+	// ProximityWarning issues an urgent warning, independent of the usual alert
+	// logic, when a flight is within ProximityRadiusNM and below
+	// ProximityAltitudeFt, which is above the observer rather than sea level
+	// in AltitudeModeRelative.
+	ProximityWarning    bool
+	ProximityRadiusNM   float64
+	ProximityAltitudeFt float64
+	// AlertConvergence alerts when two flights within the alert radius are
+	// closing to within ConvergenceNM of each other.
+	AlertConvergence bool
+	ConvergenceNM    float64
+	// ExclusionZones are areas within the interesting radius in which flights
+	// should be ignored, e.g. directly over a busy airport.
+	ExclusionZones []Zone
+	// Geofence optionally limits interesting flights to those within a
+	// polygon, in addition to the interesting radius if that is non-zero.
+	Geofence Polygon
+	// Watchlist, if not empty, limits interesting flights to those whose ident
+	// or registration matches one of its entries. WatchlistMode determines
+	// whether the radius and altitude checks still apply to them.
+	Watchlist     []string
+	WatchlistMode string
+	// Blocklist lists idents and registrations, matched like the Watchlist,
+	// of flights which are never interesting, even if on a watchlist.
+	Blocklist []string
+	// RouteWatchlist lists ORIG-DEST routes whose flights are interesting
+	// regardless of the other checks, except for exclusion zones.
+	RouteWatchlist []string
+	// AircraftFilter lists aircraft categories which, depending on
+	// AircraftFilterMode, are either the only ones watched or are ignored.
+	// ExcludeUnknownCategory also ignores flights of unknown type.
+	AircraftFilter         []string
+	AircraftFilterMode     string
+	ExcludeUnknownCategory bool
+	// MilitaryOnly limits interesting flights to those which look like
+	// military traffic. MilitaryCallsigns adds to the built-in callsign
+	// prefixes used to recognize them.
+	MilitaryOnly      bool
+	MilitaryCallsigns []string
+	// RequireAirport ignores flights with neither an origin nor a destination
+	// airport, such as overflights without a flight plan.
+	RequireAirport bool
+	// ObservationBox optionally overrides the rectangle we subscribe to from
+	// Firehose, which is otherwise derived from the interesting radius. Local
+	// filtering still applies either way.
+	ObservationBox *firehose.Rectangle
+	// Templates format alerts for each sink, from the configured templates or
+	// DefaultTemplates. Sinks without one use the default.
+	Templates Templates
+	// TypeTemplates optionally override the terminal and speech formats for
+	// particular aircraft types.
+	TypeTemplates TypeTemplates
+	// TypeAliases maps aircraft type codes to a canonical name, e.g. to group
+	// variants of the same family.
+	TypeAliases map[string]string
+	// TypeNames maps aircraft type codes to the full names displayed for them,
+	// taking precedence over the built-in table. AnnounceTypeNames also speaks
+	// them.
+	TypeNames         map[string]string
+	AnnounceTypeNames bool
+	// CallsignFile optionally names a CSV file of ICAO airline codes and their
+	// spoken callsigns, which take precedence over the built-in table.
+	CallsignFile string
+	// AirportFile optionally names a CSV file of ICAO or IATA airport codes and
+	// their names, which take precedence over the built-in table.
+	AirportFile string
+
+	// LocationSource is where our location comes from: StaticLocation for
+	// Latitude and Longitude, or NMEALocation or HTTPLocation to follow a
+	// moving location from LocationDevice or LocationURL, polled every
+	// LocationInterval. When we move LocationReboxNM from where we subscribed
+	// to Firehose, the subscription is redrawn around the new location.
+	LocationSource   string
+	LocationDevice   string
+	LocationURL      string
+	LocationInterval time.Duration
+	LocationReboxNM  float64
+
+	// OnStale is optionally called when a flight is evicted after not being seen
+	// for a while, or to make room under MaxFlights, with its last known
+	// position and the position at which it was closest to us. It is called
+	// one flight at a time without holding any of the App's locks, so it may
+	// call back into the App, but it should return promptly since positions
+	// wait on it.
+	OnStale func(last, closest Position)
+	// OnAlert is optionally called whenever a flight alerts, in addition to the
+	// built-in alert sinks. Like OnStale it is called without holding any of
+	// the App's locks.
+	OnAlert func(Position)
+	// Connect optionally replaces firehose.Connect for opening the Firehose
+	// stream, e.g. to substitute a scripted stream in tests.
+	Connect func() (FirehoseStream, error)
+
+	// mu guards flights, which is read by the HTTP server, along with the
+	// clock and alert bookkeeping which is updated alongside it
+	mu      sync.Mutex
+	flights map[string]*track
+	// staleMu serializes calls to OnStale, which are made from both the
+	// stream and the periodic cleanup
+	staleMu sync.Mutex
+	// webhooks queues webhooks for the single worker which sends them, and
+	// webhooksDone is closed when it finishes. webhooks is nil once stopped.
+	webhookQueueMu sync.Mutex
+	webhooks       chan webhookJob
+	webhooksDone   chan struct{}
+	// webhookMu guards lastWebhook, the time each flight's most recent webhook
+	// to each URL was queued
+	webhookMu   sync.Mutex
+	lastWebhook map[webhookKey]time.Time
+	// session tallies what we have seen since starting, guarded by mu
+	session session
+	// flightLog writes positions to DBPath, if configured
+	flightLog *flightLog
+	// mqtt is the connection to MQTTBroker, if configured
+	mqtt mqtt.Client
+	// stream broadcasts interesting positions to WebSocket clients when the
+	// HTTP API is enabled
+	stream *streamHub
+	// pending tracks goroutines sending alerts, so that they can finish
+	// before we exit
+	pending sync.WaitGroup
+	// ttsWarning ensures we only warn once about a missing TTS command
+	ttsWarning sync.Once
+	// beepWarning ensures we only warn once about a missing sound player
+	beepWarning sync.Once
+	// speech queues announcements for the single worker which speaks them,
+	// and speechDone is closed when it finishes. speech is nil once stopped.
+	speechMu   sync.Mutex
+	speech     chan speechJob
+	speechDone chan struct{}
 
-	flights map[string]*Position
-	// currentTime stores the most recently received clock
-	currentTime time.Time
+	// locationMu guards our moving location and where the Firehose
+	// subscription was drawn around. fixed is closed on the first fix.
+	locationMu   sync.Mutex
+	location     *geo.Latlong
+	fixed        chan struct{}
+	subscribedAt geo.Latlong
+	resubscribe  context.CancelFunc
+	// callsigns holds the callsigns loaded from CallsignFile, keyed by ICAO
+	// or IATA code
+	callsigns map[string]string
+	// statusFlightID is the flight currently in the status file
+	statusFlightID string
+	// airports holds the airport names loaded from AirportFile
+	airports map[string]string
+	// lastAlerted records when each flight last alerted
+	lastAlerted map[string]time.Time
+	// alertedRegs records the most recent alert for each registration
+	alertedRegs map[string]regAlert
+	// converging records pairs of flights we have already alerted on
+	converging map[flightPair]bool
+	// currentTime stores the most recently received clock, and currentTimeAt
+	// when we received it by our own clock
+	currentTime   time.Time
+	currentTimeAt time.Time
+	// clock overrides time.Now for our own clock in tests
+	clock func() time.Time
+	// dump1090Seen records when dump1090 last received each aircraft's
+	// position, keyed by ICAO address
+	dump1090Seen map[string]time.Time
+	// defaultTemplates are compiled on first use for sinks missing from
+	// Templates
+	defaultTemplatesOnce sync.Once
+	defaultTemplates     Templates
+	// color is set if text alerts are to be colored
+	color bool
+	// connected is set while we are connected to the source, and lastMessage
+	// is when we last heard from it by our own clock, for health checks
+	connected   bool
+	lastMessage time.Time
 }
 
 func (a *App) Run(ctx context.Context) error {
-	box := a.flightObservationBox()
+	a.color = useColor(a.Color, os.Stdout)
+	a.session.started = a.wallClock()
+	if err := a.loadCallsigns(); err != nil {
+		return err
+	}
+	if err := a.loadAirports(); err != nil {
+		return err
+	}
 
-	stream, err := firehose.Connect()
-	if err != nil {
-		return fmt.Errorf("could not establish Firehose connection: %w", err)
+	if a.DBPath != "" && !a.DryRun {
+		flightLog, err := openFlightLog(a.DBPath)
+		if err != nil {
+			return err
+		}
+		defer flightLog.Close()
+		a.flightLog = flightLog
 	}
-	defer stream.Close()
 
-	cmd := firehose.InitCommand{
-		Live:     true,
-		Username: a.Username,
-		Password: a.Password,
-		Events:   []firehose.Event{firehose.PositionEvent},
-		LatLong:  []firehose.Rectangle{box},
+	if err := a.connectMQTT(); err != nil {
+		return err
+	}
+	if a.mqtt != nil {
+		defer a.mqtt.Disconnect(250)
 	}
 
-	if err := stream.Init(cmd.String()); err != nil {
-		return fmt.Errorf("could not initialize firehose: %w", err)
+	if a.Announce && !a.DryRun {
+		a.startSpeech()
+		defer a.stopSpeech(ShutdownTimeout)
+	}
+
+	if a.sendsWebhooks() && !a.DryRun {
+		a.startWebhooks()
+		defer a.stopWebhooks(ShutdownTimeout)
+	}
+
+	if a.HTTPListen != "" {
+		a.stream = newStreamHub()
+		defer a.stream.close()
+		l, err := net.Listen("tcp", a.HTTPListen)
+		if err != nil {
+			return fmt.Errorf("could not start HTTP server: %w", err)
+		}
+		go a.serveHTTP(ctx, l)
+	}
+
+	if a.MetricsListen != "" {
+		l, err := net.Listen("tcp", a.MetricsListen)
+		if err != nil {
+			return fmt.Errorf("could not start metrics server: %w", err)
+		}
+		go serveMetrics(ctx, l)
+	}
+
+	// Cleanup can start pending work of its own, so it is stopped before we
+	// wait for that work to finish.
+	cleanupCtx, stopCleanup := context.WithCancel(ctx)
+	cleanupDone := make(chan struct{})
+	go func() {
+		defer close(cleanupDone)
+		a.cleanupPeriodically(cleanupCtx)
+	}()
+
+	// A moving location is likewise tracked only until the source returns.
+	stopLocation := func() {}
+	if a.isMoving() {
+		a.fixed = make(chan struct{})
+		locationCtx, cancel := context.WithCancel(ctx)
+		locationDone := make(chan struct{})
+		go func() {
+			defer close(locationDone)
+			a.trackLocation(locationCtx)
+		}()
+		stopLocation = func() {
+			cancel()
+			<-locationDone
+		}
+	}
+	err := a.waitForFix(ctx)
+	switch {
+	case err != nil:
+		// Canceled while waiting for a location fix.
+	case a.ReplayFile != "":
+		err = a.replay(ctx)
+	case a.Source == Dump1090Source:
+		err = a.pollDump1090(ctx)
+	default:
+		err = a.runFirehose(ctx)
+	}
+	stopLocation()
+	stopCleanup()
+	<-cleanupDone
+	a.drain(ShutdownTimeout)
+	if errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	if err == nil {
+		a.printSessionSummary()
 	}
+	return err
+}
+
+// goPending runs f in a goroutine which drain waits for. It must not be called
+// once drain has started.
+func (a *App) goPending(f func()) {
+	a.pending.Add(1)
+	go func() {
+		defer a.pending.Done()
+		f()
+	}()
+}
+
+// drain waits for pending alerts to finish being displayed, announced, and
+// sent, but no longer than the timeout in case one of them is stuck.
+func (a *App) drain(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		a.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		slog.Warn("gave up waiting for pending alerts to finish", "timeout", timeout)
+	}
+}
 
+// runFirehose streams positions from Firehose, reconnecting with backoff if
+// the stream drops, until the context is canceled or Firehose rejects our
+// credentials. InitRetry only applies to the first connection: once we have
+// been connected, failures to reconnect are always retried.
+func (a *App) runFirehose(ctx context.Context) error {
+	backoff := firehoseBackoff
+	retry := a.InitRetry
 	for {
-		msg, err := stream.NextMessage(ctx)
-		if errors.Is(err, context.Canceled) {
-			return nil
-		} else if err != nil {
+		center := a.myLocation()
+		stream, err := a.openStream(ctx, retry)
+		if err != nil {
 			return err
 		}
+		retry = true
+
+		connected := time.Now()
+		a.setConnected(true)
+		streamCtx, cancel := context.WithCancel(ctx)
+		a.watchSubscription(center, cancel)
+		err = a.readStream(streamCtx, stream)
+		cancel()
+		a.setConnected(false)
+		if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+			// We moved, so resubscribe around our new location right away.
+			continue
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, ErrAuthentication) {
+			return err
+		}
+
+		// If the stream was healthy for a while before it dropped, start over
+		// with a short backoff rather than continuing to escalate.
+		if time.Since(connected) > MaxBackoff {
+			backoff = firehoseBackoff
+		}
+		slog.Warn("lost Firehose stream; reconnecting", "error", err, "backoff", backoff)
+		if err := sleep(ctx, backoff); err != nil {
+			return err
+		}
+		backoff = min(backoff*2, MaxBackoff)
+	}
+}
+
+// readStream handles messages from the stream until it fails or the context is
+// canceled. It always returns a non-nil error.
+func (a *App) readStream(ctx context.Context, stream FirehoseStream) error {
+	defer stream.Close()
+
+	for {
+		msg, err := a.nextMessage(ctx, stream)
+		if err != nil {
+			return err
+		}
+		a.markReceived()
 		switch m := msg.Payload.(type) {
 		case firehose.PositionMessage:
 			a.handlePosition(&m)
 		case firehose.ErrorMessage:
+			if isAuthError(m.ErrorMessage) {
+				return fmt.Errorf("%w: %s", ErrAuthentication, m.ErrorMessage)
+			}
 			return fmt.Errorf("firehose error: %s", m.ErrorMessage)
 		}
 
@@ -137,38 +1120,365 @@ func (a *App) Run(ctx context.Context) error {
 	}
 }
 
+// nextMessage waits for the next message of any type from the stream, for no
+// longer than StreamTimeout. If it times out, the stream is closed and
+// ErrStreamStalled returned.
+func (a *App) nextMessage(ctx context.Context, stream FirehoseStream) (*firehose.Message, error) {
+	if a.StreamTimeout <= 0 {
+		return stream.NextMessage(ctx)
+	}
+	msgCtx, cancel := context.WithTimeout(ctx, a.StreamTimeout)
+	defer cancel()
+	msg, err := stream.NextMessage(msgCtx)
+	if err == nil || ctx.Err() != nil {
+		return msg, err
+	}
+	// The timeout shows up either as the context expiring or as the
+	// connection's read deadline passing.
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return nil, fmt.Errorf("%w: no message for %s", ErrStreamStalled, a.StreamTimeout)
+	}
+	return msg, err
+}
+
+// openStream connects to Firehose and sends our init command. If retry is
+// set, failures are retried with exponential backoff until the context is
+// canceled.
+func (a *App) openStream(ctx context.Context, retry bool) (FirehoseStream, error) {
+	backoff := firehoseBackoff
+	for {
+		stream, err := a.initStream()
+		if err == nil || !retry {
+			return stream, err
+		}
+		slog.Warn("could not open Firehose stream; retrying", "error", err, "backoff", backoff)
+		if err := sleep(ctx, backoff); err != nil {
+			return nil, err
+		}
+		backoff = min(backoff*2, MaxBackoff)
+	}
+}
+
+// sleep waits for the duration to elapse, returning early with the context's
+// error if it is canceled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// A FirehoseStream is a connection to Firehose, as made by firehose.Connect.
+type FirehoseStream interface {
+	Init(command string) error
+	NextMessage(ctx context.Context) (*firehose.Message, error)
+	Close() error
+}
+
+// connect opens a connection to Firehose, using Connect if it is set.
+func (a *App) connect() (FirehoseStream, error) {
+	if a.Connect != nil {
+		return a.Connect()
+	}
+	stream, err := firehose.Connect()
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (a *App) initStream() (FirehoseStream, error) {
+	stream, err := a.connect()
+	if err != nil {
+		return nil, fmt.Errorf("could not establish Firehose connection: %w", err)
+	}
+
+	cmd := firehose.InitCommand{
+		Live:     true,
+		Username: a.Username,
+		Password: a.Password,
+		Events:   []firehose.Event{firehose.PositionEvent},
+		LatLong:  a.subscriptionBox(),
+	}
+
+	if err := stream.Init(cmd.String()); err != nil {
+		stream.Close()
+		return nil, fmt.Errorf("could not initialize firehose: %w", err)
+	}
+	return stream, nil
+}
+
+// authErrors are the phrases with which Firehose reports rejecting our
+// credentials, in lower case.
+var authErrors = []string{
+	"authentication failed",
+	"not authorized",
+	"unauthorized",
+	"invalid credentials",
+}
+
+// isAuthError reports whether a Firehose error message indicates that our
+// credentials were rejected.
+func isAuthError(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, phrase := range authErrors {
+		if strings.Contains(msg, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
 // cleanupStaleFlights removes any flights that have not been seen recently from the map.
 func (a *App) cleanupStaleFlights() {
+	a.notifyStale(a.removeStaleFlights())
+}
+
+// removeStaleFlights does the work of cleanupStaleFlights under the lock,
+// returning the flights which were forgotten.
+func (a *App) removeStaleFlights() []*track {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.streamTime()
+	var stale []*track
 	for id, flight := range a.flights {
 		// last heard + cleanup after < current time
-		if flight.Timestamp.Add(CleanupAfter).Before(a.currentTime) {
-			delete(a.flights, id)
+		if flight.last.Timestamp.Add(CleanupAfter).Before(now) {
+			a.forgetFlight(id, flight)
+			stale = append(stale, flight)
+		}
+	}
+	for id, at := range a.lastAlerted {
+		if at.Add(a.AlertCooldown).Before(now) {
+			delete(a.lastAlerted, id)
 		}
 	}
+	for reg, alerted := range a.alertedRegs {
+		if alerted.at.Add(RegDedupWindow).Before(now) {
+			delete(a.alertedRegs, reg)
+		}
+	}
+	return stale
+}
+
+// notifyStale calls OnStale for flights we have stopped tracking. The caller
+// must not hold a.mu.
+func (a *App) notifyStale(flights []*track) {
+	if a.OnStale == nil {
+		return
+	}
+	a.staleMu.Lock()
+	defer a.staleMu.Unlock()
+	for _, flight := range flights {
+		a.OnStale(*flight.last, *flight.closest)
+	}
+}
+
+// forgetFlight stops tracking a flight. The caller must hold a.mu, and should
+// pass the flight to notifyStale once it has been released.
+func (a *App) forgetFlight(id string, flight *track) {
+	delete(a.flights, id)
+	flightsTracked.Set(float64(len(a.flights)))
+	a.forgetConvergences(id)
+	if id == a.statusFlightID {
+		a.updateStatus(nil)
+	}
+	if a.PassSummary {
+		a.goPending(func() { a.summarizePass(flight) })
+	}
+}
+
+// evictOldestFlight forgets the flight which was least recently updated, to
+// make room for a new one, returning it if there was one. The caller must hold
+// a.mu.
+func (a *App) evictOldestFlight() *track {
+	var oldestID string
+	var oldest *track
+	for id, flight := range a.flights {
+		if oldest == nil || flight.last.Timestamp.Before(oldest.last.Timestamp) {
+			oldestID, oldest = id, flight
+		}
+	}
+	if oldest != nil {
+		slog.Debug("tracking too many flights; evicting least recently updated", "flight_id", oldestID, "max_flights", a.MaxFlights)
+		a.forgetFlight(oldestID, oldest)
+	}
+	return oldest
 }
 
-func (a *App) flightObservationBox() firehose.Rectangle {
-	center := a.myLocation()
-	minLat := center.MoveNM(180, a.InterestingRadiusNM)
-	maxLat := center.MoveNM(0, a.InterestingRadiusNM)
-	minLon := center.MoveNM(270, a.InterestingRadiusNM)
-	maxLon := center.MoveNM(90, a.InterestingRadiusNM)
-	return firehose.Rectangle{
-		LowLat: minLat.Lat,
-		LowLon: minLon.Long,
-		HiLat:  maxLat.Lat,
-		HiLon:  maxLon.Long,
+// streamTime estimates the current time by the stream's clock, which is the
+// clock of the most recent message advanced by however long it has been since
+// we received it. This keeps time moving while the stream is quiet.
+func (a *App) streamTime() time.Time {
+	if a.currentTime.IsZero() {
+		return a.currentTime
 	}
+	return a.currentTime.Add(a.wallClock().Sub(a.currentTimeAt))
+}
+
+// wallClock returns the current time on our own clock.
+func (a *App) wallClock() time.Time {
+	if a.clock != nil {
+		return a.clock()
+	}
+	return time.Now()
+}
+
+// cleanupPeriodically cleans up stale flights every CleanupInterval until the
+// context is canceled, so that they are forgotten even if no further messages
+// arrive.
+func (a *App) cleanupPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(CleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.cleanupStaleFlights()
+		}
+	}
+}
+
+// subscriptionBox returns the rectangles to request positions within from
+// Firehose.
+func (a *App) subscriptionBox() []firehose.Rectangle {
+	if a.ObservationBox != nil {
+		return []firehose.Rectangle{*a.ObservationBox}
+	}
+	return a.flightObservationBox()
+}
+
+// validateRectangle checks that a rectangle's low corner is actually below and
+// to the left of its high corner.
+func validateRectangle(r firehose.Rectangle) error {
+	if r.LowLat >= r.HiLat {
+		return fmt.Errorf("low latitude %f must be less than high latitude %f", r.LowLat, r.HiLat)
+	}
+	if r.LowLon >= r.HiLon {
+		return fmt.Errorf("low longitude %f must be less than high longitude %f", r.LowLon, r.HiLon)
+	}
+	return nil
+}
+
+func (a *App) flightObservationBox() []firehose.Rectangle {
+	if len(a.Geofence) > 0 {
+		return []firehose.Rectangle{a.Geofence.Bounds()}
+	}
+	radius := a.InterestingRadiusNM
+	if a.isMoving() {
+		// Leave room to move before resubscribing.
+		radius += a.LocationReboxNM
+	}
+	return bbox.Around(a.myLocation(), radius)
 }
 
 func (a *App) isInteresting(pos *Position) bool {
-	if pos.Distance > a.InterestingRadiusNM {
+	if a.onBlocklist(pos) {
+		return false
+	}
+	if a.onRouteWatchlist(pos) {
+		return !a.inExclusionZone(pos.Point)
+	}
+	if len(a.Watchlist) > 0 && !a.onWatchlist(pos) {
+		return false
+	}
+	if len(a.Watchlist) == 0 || a.WatchlistMode != WatchlistOnly {
+		if a.InterestingRadiusNM > 0 && pos.Distance > a.InterestingRadiusNM {
+			return false
+		}
+		if len(a.Geofence) > 0 && !a.Geofence.Contains(pos.Point) {
+			return false
+		}
+		if !a.isInterestingAltitude(pos.Altitude) {
+			return false
+		}
+		if !a.isInterestingSpeed(pos.Speed) {
+			return false
+		}
+	}
+	if a.inExclusionZone(pos.Point) {
+		return false
+	}
+	if a.MilitaryOnly && !a.isLikelyMilitary(pos) {
 		return false
 	}
-	if pos.Altitude != nil && *pos.Altitude > a.InterestingCeilingFt {
+	if a.RequireAirport && !airport.IsCode(pos.Origin) && !airport.IsCode(pos.Destination) {
+		return false
+	}
+	return a.isInterestingCategory(pos)
+}
+
+func (a *App) inExclusionZone(point geo.Latlong) bool {
+	for _, zone := range a.ExclusionZones {
+		if zone.Contains(point) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *App) isInterestingAltitude(alt *float64) bool {
+	if alt == nil {
+		return !a.ExcludeUnknownAlt
+	}
+	if len(a.AltitudeBands) == 0 {
+		return *alt >= a.InterestingFloorFt && *alt <= a.InterestingCeilingFt
+	}
+	for _, band := range a.AltitudeBands {
+		if band.Contains(*alt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *App) isInterestingSpeed(speed *float64) bool {
+	if a.MinSpeedKts <= 0 && a.MaxSpeedKts <= 0 {
+		return true
+	}
+	if speed == nil {
+		return !a.FilterMissingSpeed
+	}
+	if *speed < a.MinSpeedKts {
 		return false
 	}
-	return true
+	return a.MaxSpeedKts <= 0 || *speed <= a.MaxSpeedKts
+}
+
+// An AltitudeBand is an inclusive range of altitudes in feet.
+type AltitudeBand struct {
+	MinFt float64 `mapstructure:"min"`
+	MaxFt float64 `mapstructure:"max"`
+}
+
+// Contains reports whether the altitude falls within the band.
+func (b AltitudeBand) Contains(alt float64) bool {
+	return alt >= b.MinFt && alt <= b.MaxFt
+}
+
+// A Zone is a circular area around a point.
+type Zone struct {
+	Latitude  float64
+	Longitude float64
+	RadiusNM  float64 `mapstructure:"radius"`
+}
+
+// Contains reports whether the point lies within the zone.
+func (z Zone) Contains(point geo.Latlong) bool {
+	return point.DistNM(z.center()) <= z.RadiusNM
+}
+
+func (z Zone) center() geo.Latlong {
+	return geo.Latlong{
+		Lat:  z.Latitude,
+		Long: z.Longitude,
+	}
 }
 
 type Position struct {
@@ -180,8 +1490,24 @@ type Position struct {
 	Origin       string
 	Destination  string
 	AircraftType string
-	Speed        *float64
-	Heading      *float64
+	// Category is inferred from the aircraft type as reported, before any
+	// alias is applied.
+	Category string
+	// ICAOHex is the transponder's 24-bit ICAO address as six lowercase hex
+	// digits, and Squawk its beacon code, when the source reports them.
+	ICAOHex string
+	Squawk  string
+	// Departed is set on the position with which a flight alerted for leaving
+	// the alert radius.
+	Departed bool `json:",omitempty"`
+	// Appeared is set on the position with which a flight was first seen, for
+	// notify-on-appear. It is given by the webhook payload's Event instead.
+	Appeared bool `json:"-"`
+	Speed    *float64
+	Heading  *float64
+	// VerticalRate is the rate of climb (positive) or descent (negative) in
+	// feet per minute.
+	VerticalRate *float64
 	Timestamp    time.Time
 	Distance     float64
 	Bearing      float64
@@ -202,242 +1528,904 @@ func (a *App) newPosition(msg *firehose.PositionMessage) (*Position, error) {
 		Lat:  lat,
 		Long: lon,
 	}
-	if msg.Alt != "" {
-		alt, err := strconv.ParseFloat(msg.Alt, 64)
-		if err != nil {
-			return nil, fmt.Errorf("alt: %w", err)
-		}
-		pos.Altitude = &alt
-	}
+	pos.Altitude = parseOptionalFloat(msg.ID, "alt", msg.Alt)
 	pos.Ident = msg.Ident
 	pos.Reg = msg.Reg
 	pos.Origin = msg.Orig
 	pos.Destination = msg.Dest
-	pos.AircraftType = msg.AircraftType
-	if msg.GS != "" {
-		gs, err := strconv.ParseFloat(msg.GS, 64)
-		if err != nil {
-			return nil, fmt.Errorf("gs: %w", err)
-		}
-		pos.Speed = &gs
-	}
-	var heading string
-	if msg.Heading != "" {
-		heading = msg.Heading
+	pos.AircraftType = a.normalizeAircraftType(msg.AircraftType)
+	pos.Category = aircraftCategory(msg.AircraftType)
+	pos.ICAOHex = normalizeICAOHex(msg.Hexid)
+	pos.Squawk = msg.Squawk
+	pos.Speed = parseOptionalFloat(msg.ID, "gs", msg.GS)
+	pos.Heading = parseOptionalFloat(msg.ID, "heading_true", msg.HeadingTrue)
+	if pos.Heading == nil {
+		pos.Heading = parseOptionalFloat(msg.ID, "heading", msg.Heading)
 	}
-	if msg.HeadingTrue != "" {
-		heading = msg.HeadingTrue
-	}
-	if heading != "" {
-		hdg, err := strconv.ParseFloat(heading, 64)
-		if err != nil {
-			return nil, fmt.Errorf("heading: %w", err)
-		}
-		pos.Heading = &hdg
+	pos.VerticalRate = parseOptionalFloat(msg.ID, "vertRate", msg.VertRate)
+	if pos.VerticalRate == nil {
+		pos.VerticalRate = parseOptionalFloat(msg.ID, "vertRate_geom", msg.VertRateGeom)
 	}
 	clock, err := strconv.ParseInt(msg.Clock, 10, 64)
 	if err != nil {
 		return nil, fmt.Errorf("clock: %w", err)
 	}
 	pos.Timestamp = time.Unix(clock, 0)
-	pos.Distance = pos.Point.DistNM(a.myLocation())
-	pos.Bearing = a.myLocation().BearingTowards(pos.Point)
+	home := a.myLocation()
+	pos.Distance = pos.Point.DistNM(home)
+	pos.Bearing = home.BearingTowards(pos.Point)
 	return &pos, nil
 }
 
-func (a *App) myLocation() geo.Latlong {
-	return geo.Latlong{
-		Lat:  a.Latitude,
-		Long: a.Longitude,
+// parseOptionalFloat parses an optional numeric field of a position message.
+// A malformed value is treated as absent, so that one bad field doesn't cost us
+// the rest of the position.
+func parseOptionalFloat(flightID, field, value string) *float64 {
+	if value == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Debug("ignoring malformed position field", "flight_id", flightID, "field", field, "value", value)
+		malformedFields.Inc()
+		return nil
 	}
+	return &f
 }
 
 func (a *App) handlePosition(msg *firehose.PositionMessage) {
 	curr, err := a.newPosition(msg)
 	if err != nil {
-		log.Printf("could not translate position message: %v", err)
+		slog.Warn("could not translate position message", "flight_id", msg.ID, "error", err)
+		malformedPositions.Inc()
 		return
 	}
+	a.trackPosition(curr)
+}
+
+// trackPosition updates our view of a flight with its latest position and
+// alerts if necessary. Positions from every source end up here.
+func (a *App) trackPosition(curr *Position) {
+	// Alerts and appearances run callbacks and queue I/O, so send them once
+	// the lock is released.
+	var alerts []*Position
+	defer func() {
+		for _, pos := range alerts {
+			a.alert(pos)
+		}
+	}()
+	var appeared []*Position
+	defer func() {
+		for _, pos := range appeared {
+			a.appear(pos)
+		}
+	}()
+	var evicted []*track
+	defer func() { a.notifyStale(evicted) }()
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	a.currentTime = curr.Timestamp
-	if !a.isInteresting(curr) {
+	a.currentTimeAt = a.wallClock()
+	positionsReceived.Inc()
+	interesting := a.isInteresting(curr)
+	a.session.countPosition(curr, interesting)
+	if !interesting {
 		return
 	}
+	positionsInteresting.Inc()
+	a.publishPosition(curr)
+	if a.flightLog != nil {
+		a.flightLog.Log(curr)
+	}
 
 	if a.flights == nil {
-		a.flights = make(map[string]*Position)
+		a.flights = make(map[string]*track)
+	}
+	flight, ok := a.flights[curr.FlightID]
+	if !ok {
+		if a.MaxFlights > 0 && len(a.flights) >= a.MaxFlights {
+			if oldest := a.evictOldestFlight(); oldest != nil {
+				evicted = append(evicted, oldest)
+			}
+		}
+		flight = &track{first: curr.Timestamp, last: curr, closest: curr, trail: newTrail(a.TrailLength)}
+		flight.trail.add(curr)
+		a.flights[curr.FlightID] = flight
+		flightsTracked.Set(float64(len(a.flights)))
+		if a.NotifyOnAppear {
+			appeared = append(appeared, curr)
+		}
 	}
-	if prev, ok := a.flights[curr.FlightID]; ok {
-		if curr.Distance < prev.Distance && curr.Distance < a.AlertRadiusNM {
-			a.alert(curr)
+	if a.isProximityWarning(flight, curr) {
+		flight.warned = true
+		a.goPending(func() { a.warnProximity(curr) })
+	}
+	if !ok {
+		return
+	}
+	if flight.inAlertRadius && curr.Distance > a.AlertRadiusNM+a.AlertHysteresisNM {
+		flight.inAlertRadius = false
+	}
+	if !a.inCooldown(curr) && a.shouldAlert(flight, curr) {
+		flight.alerted = true
+		flight.inAlertRadius = true
+		if a.lastAlerted == nil {
+			a.lastAlerted = make(map[string]time.Time)
 		}
+		a.lastAlerted[curr.FlightID] = curr.Timestamp
+		a.recordAlertedReg(curr)
+		alerts = append(alerts, curr)
+	} else if a.shouldAlertDeparture(flight, curr) {
+		flight.departed = true
+		departure := *curr
+		departure.Departed = true
+		alerts = append(alerts, &departure)
+	}
+	a.session.alerts += len(alerts)
+	if len(alerts) > 0 && !a.DryRun {
+		// The status is written under the lock, so that it can't race with
+		// the flight being forgotten.
+		a.updateStatus(alerts[len(alerts)-1])
+	}
+	if a.AlertConvergence {
+		a.checkConvergence(flight.last, curr)
+	}
+	flight.last = curr
+	flight.trail.add(curr)
+	if curr.Distance < flight.closest.Distance {
+		flight.closest = curr
+	}
+}
+
+// isProximityWarning reports whether a flight has come close and low enough to
+// warrant a proximity warning. Each flight is only warned about once.
+func (a *App) isProximityWarning(flight *track, curr *Position) bool {
+	if !a.ProximityWarning || flight.warned || curr.Altitude == nil {
+		return false
+	}
+	altitude := *curr.Altitude
+	if a.AltitudeMode == AltitudeModeRelative {
+		altitude -= a.ObserverElevationFt
+	}
+	return curr.Distance <= a.ProximityRadiusNM && altitude <= a.ProximityAltitudeFt
+}
+
+func (a *App) warnProximity(curr *Position) {
+	slog.Warn(fmt.Sprintf("PROXIMITY WARNING: %s (%s) is %s to the %s %s",
+		flightName(curr), curr.AircraftType, formatDistance(curr.Distance, a.Units, a.DistancePrecision),
+		cardinalDirection(curr.Bearing), a.displayAltitude(*curr.Altitude)),
+		"flight_id", curr.FlightID, "distance_nm", curr.Distance, "altitude_ft", *curr.Altitude)
+
+	if !a.canAnnounce(curr.Timestamp) {
+		return
+	}
+	var words []string
+	words = append(words, "warning", ",", "low traffic", ",")
+	words = append(words, a.flightNameToWords(curr)...)
+	words = append(words, a.spokenAltitude(*curr.Altitude)...)
+	words = append(words, "to the", cardinalDirection(curr.Bearing))
+	a.speak(strings.Join(words, " "))
+}
+
+// inCooldown reports whether the flight alerted too recently to alert again.
+func (a *App) inCooldown(curr *Position) bool {
+	last, ok := a.lastAlerted[curr.FlightID]
+	return ok && curr.Timestamp.Sub(last) < a.AlertCooldown
+}
+
+// shouldAlert decides whether a new position for a tracked flight warrants an
+// alert.
+func (a *App) shouldAlert(flight *track, curr *Position) bool {
+	if a.AlertOn == AlertOnDepart {
+		return false
 	}
-	a.flights[curr.FlightID] = curr
+	if curr.Distance >= flight.last.Distance || curr.Distance >= a.AlertRadiusNM {
+		return false
+	}
+	if a.AlertOnce && flight.alerted {
+		return false
+	}
+	if a.AlertHysteresisNM > 0 && flight.inAlertRadius {
+		return false
+	}
+	return !a.isDuplicateReg(curr)
 }
 
+// shouldAlertDeparture decides whether a new position for a tracked flight
+// takes it out of the alert radius, widened by the hysteresis distance, for the
+// first time.
+func (a *App) shouldAlertDeparture(flight *track, curr *Position) bool {
+	if a.AlertOn != AlertOnDepart && a.AlertOn != AlertOnBoth {
+		return false
+	}
+	radius := a.AlertRadiusNM + a.AlertHysteresisNM
+	return !flight.departed && flight.last.Distance <= radius && curr.Distance > radius
+}
+
+// isDuplicateReg reports whether the position's registration was recently
+// alerted on under a different flight ID. Positions without a registration are
+// never considered duplicates, since we can only rely on the flight ID for
+// them.
+func (a *App) isDuplicateReg(curr *Position) bool {
+	if !a.DedupByReg || curr.Reg == "" {
+		return false
+	}
+	prev, ok := a.alertedRegs[curr.Reg]
+	return ok && prev.flightID != curr.FlightID && curr.Timestamp.Sub(prev.at) < RegDedupWindow
+}
+
+// recordAlertedReg remembers that we alerted on the position's registration,
+// for isDuplicateReg.
+func (a *App) recordAlertedReg(curr *Position) {
+	if !a.DedupByReg || curr.Reg == "" {
+		return
+	}
+	if a.alertedRegs == nil {
+		a.alertedRegs = make(map[string]regAlert)
+	}
+	a.alertedRegs[curr.Reg] = regAlert{flightID: curr.FlightID, at: curr.Timestamp}
+}
+
+// A regAlert records when we last alerted on a registration, and under which
+// flight ID.
+type regAlert struct {
+	flightID string
+	at       time.Time
+}
+
+// A track holds what we know about a flight we are following.
+type track struct {
+	// first is when we first saw the flight.
+	first time.Time
+	// last is the most recently received position.
+	last *Position
+	// closest is the position at which the flight was nearest to us.
+	closest *Position
+	// trail holds the most recent positions, up to TrailLength.
+	trail *trail
+	// alerted is set once we have alerted on the flight.
+	alerted bool
+	// inAlertRadius is set when we alert on the flight, and cleared once it
+	// leaves the alert radius widened by the hysteresis distance.
+	inAlertRadius bool
+	// warned is set once we have issued a proximity warning for the flight.
+	warned bool
+	// departed is set once we have alerted on the flight leaving the alert
+	// radius.
+	departed bool
+}
+
+// alert sends an alert for the position to every configured sink. The caller
+// must not hold a.mu.
 func (a *App) alert(curr *Position) {
-	go a.displayFlight(curr)
-	go a.postWebhook(curr)
-	go a.say(curr)
+	alertsFired.Inc()
+	if a.OnAlert != nil {
+		a.OnAlert(*curr)
+	}
+	if a.DryRun {
+		a.logDryRunAlert(curr)
+		return
+	}
+	a.goPending(func() { a.displayFlight(curr) })
+	a.postWebhook(curr)
+	a.goPending(func() { a.publishMQTT(curr) })
+	a.postDiscord(curr)
+	a.postSlack(curr)
+	a.goPending(func() {
+		a.beep(curr)
+		a.say(curr)
+	})
+}
+
+// appear notifies that a flight has been seen for the first time. This is
+// only logged and sent to the webhook, without the fanfare of an alert.
+func (a *App) appear(curr *Position) {
+	appearance := *curr
+	appearance.Appeared = true
+	slog.Info("flight appeared",
+		"flight_id", curr.FlightID,
+		"ident", flightName(curr),
+		"distance_nm", curr.Distance,
+		"bearing", curr.Bearing,
+	)
+	if a.DryRun || a.WebhookURL == "" {
+		return
+	}
+	body, err := a.webhookBody(&appearance)
+	if err != nil {
+		slog.Error("could not render webhook body", "flight_id", curr.FlightID, "error", err)
+		return
+	}
+	// Appearances aren't subject to WebhookMinInterval, so that they can't
+	// hold back the flight's first alert.
+	a.queueWebhook("", body)
+}
+
+// logDryRunAlert logs the alert which would have been made for a position.
+func (a *App) logDryRunAlert(curr *Position) {
+	args := []any{
+		"flight_id", curr.FlightID,
+		"ident", curr.Ident,
+		"aircraft_type", curr.AircraftType,
+		"distance_nm", curr.Distance,
+		"bearing", curr.Bearing,
+	}
+	if curr.Altitude != nil {
+		args = append(args, "altitude_ft", *curr.Altitude)
+	}
+	slog.Info("would alert", args...)
 }
 
 func (a *App) postWebhook(pos *Position) {
 	if a.WebhookURL == "" {
 		return
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), WebhookTimeout)
-	defer cancel()
-	body, err := json.Marshal(pos)
+	body, err := a.webhookBody(pos)
 	if err != nil {
-		log.Println(err.Error())
+		slog.Error("could not render webhook body", "flight_id", pos.FlightID, "error", err)
 		return
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.WebhookURL, bytes.NewReader(body))
+	a.queueWebhook(pos.FlightID, body)
+}
+
+// sendWebhook posts a job's body to its URL. Connection errors and 5xx
+// responses are retried up to WebhookRetries times with exponential backoff,
+// within an overall WebhookDeadline.
+func (a *App) sendWebhook(job webhookJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), WebhookDeadline)
+	defer cancel()
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		status, err := a.attemptWebhook(ctx, job)
+		retryable := err != nil || status >= 500
+		if err == nil && status < 400 {
+			slog.Info("sent webhook", "url", job.url, "status", status, "attempts", attempt)
+			webhooksSent.Inc()
+			return
+		}
+		if !retryable || attempt > a.WebhookRetries {
+			slog.Error("could not send webhook", "url", job.url, "status", status, "error", err, "attempts", attempt)
+			webhooksFailed.Inc()
+			return
+		}
+		slog.Warn("webhook failed; retrying", "url", job.url, "status", status, "error", err, "attempt", attempt, "backoff", backoff)
+		if err := sleep(ctx, backoff); err != nil {
+			slog.Error("gave up sending webhook after deadline", "url", job.url, "attempts", attempt)
+			webhooksFailed.Inc()
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// attemptWebhook makes a single attempt at posting a job's body, returning the
+// response status code.
+func (a *App) attemptWebhook(ctx context.Context, job webhookJob) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, WebhookTimeout)
+	defer cancel()
+	payload, encoding, err := encodeWebhook(job)
 	if err != nil {
-		log.Println(err.Error())
-		return
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("content-type", job.contentType)
+	if encoding != "" {
+		req.Header.Set("content-encoding", encoding)
 	}
-	req.Header.Set("content-type", "application/json")
 	req.Header.Set("user-agent", "overhead-webhook https://github.com/benburwell/overhead")
-	res, err := http.DefaultClient.Do(req)
+	res, err := a.webhookClient().Do(req)
 	if err != nil {
-		log.Println(err.Error())
-		return
+		return 0, err
 	}
-	log.Printf("sent webhook to %s and got HTTP response code %s", a.WebhookURL, res.Status)
+	res.Body.Close()
+	return res.StatusCode, nil
 }
 
-func (a *App) displayFlight(curr *Position) {
+// webhookContentType returns the configured webhook content type, or otherwise
+// guesses it from the body, which is usually JSON but may be anything when a
+// template is used.
+func (a *App) webhookContentType(body []byte) string {
+	if a.WebhookContentType != "" {
+		return a.WebhookContentType
+	}
+	if json.Valid(body) {
+		return "application/json"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+func (a *App) webhookClient() *http.Client {
+	return &http.Client{CheckRedirect: a.checkWebhookRedirect}
+}
+
+// checkWebhookRedirect decides whether to follow a redirect from the webhook
+// URL. When following, the original method, body, and headers are carried over
+// to the new request, since by default a 301 or 302 would turn our POST into a
+// GET with no body.
+func (a *App) checkWebhookRedirect(req *http.Request, via []*http.Request) error {
+	if !a.WebhookFollowRedirects {
+		slog.Warn("webhook redirected; not following", "location", req.URL.String())
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= MaxWebhookRedirects {
+		return fmt.Errorf("stopped after %d redirects", MaxWebhookRedirects)
+	}
+	orig := via[0]
+	if req.Method != orig.Method {
+		body, err := orig.GetBody()
+		if err != nil {
+			return err
+		}
+		req.Method = orig.Method
+		req.Body = body
+		req.GetBody = orig.GetBody
+		req.ContentLength = orig.ContentLength
+	}
+	for _, h := range []string{"content-type", "user-agent"} {
+		req.Header.Set(h, orig.Header.Get(h))
+	}
+	return nil
+}
+
+// webhookBody renders the webhook template, which by default marshals the
+// position as JSON.
+func (a *App) webhookBody(pos *Position) ([]byte, error) {
+	text, err := a.renderTemplate(WebhookSink, pos)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(text), nil
+}
+
+// Events reported in the webhook payload.
+const (
+	// AlertEvent is sent when a flight alerts.
+	AlertEvent = "alert"
+	// AppearedEvent is sent when a flight is first seen, with NotifyOnAppear.
+	AppearedEvent = "appeared"
+)
 
-	var alert strings.Builder
+// A WebhookPayload is the JSON body sent to the webhook: the event and
+// position, plus optionally who observed it.
+type WebhookPayload struct {
+	Event string
+	*Position
+	Observer *Observer `json:",omitempty"`
+}
 
-	alert.WriteString(fmt.Sprintf("[%s] ", curr.Timestamp.Format("15:04:05")))
+// An Observer identifies the station reporting a position.
+type Observer struct {
+	StationID string `json:",omitempty"`
+	Latitude  float64
+	Longitude float64
+}
 
-	alert.WriteString(curr.Ident)
-	if curr.AircraftType != "" {
-		alert.WriteString(" (" + curr.AircraftType + ")")
+func (a *App) newWebhookPayload(pos *Position) WebhookPayload {
+	payload := WebhookPayload{Event: AlertEvent, Position: pos}
+	if pos.Appeared {
+		payload.Event = AppearedEvent
 	}
-	alert.WriteString(" from " + curr.Origin)
-	if curr.Destination != "" {
-		alert.WriteString(" to " + curr.Destination)
+	if a.IncludeObserver {
+		loc := a.myLocation()
+		payload.Observer = &Observer{
+			StationID: a.StationID,
+			Latitude:  loc.Lat,
+			Longitude: loc.Long,
+		}
 	}
-	alert.WriteString(fmt.Sprintf(" is %.1fnm to the %s", curr.Distance, cardinalDirection(curr.Bearing)))
-	if curr.Altitude != nil {
-		alert.WriteString(fmt.Sprintf(" at %.0fft", *curr.Altitude))
+	return payload
+}
+
+func (a *App) displayFlight(curr *Position) {
+	if a.OutputFormat == JSONLinesOutput {
+		a.printJSONLine(curr)
+		return
 	}
-	dir := "travelling"
-	if curr.Heading != nil {
-		dir = cardinalDirection(*curr.Heading) + "bound"
+	text, err := a.renderTemplate(TerminalSink, curr)
+	if err != nil {
+		slog.Error("could not render terminal template", "flight_id", curr.FlightID, "error", err)
+		return
 	}
-	if curr.Speed != nil {
-		alert.WriteString(fmt.Sprintf(" %s at %.0fkts", dir, *curr.Speed))
+	fmt.Println(text)
+}
+
+// formatClosestApproach describes when and how close the flight will pass us
+// if it holds its course. ok is false if it isn't getting any closer.
+func (a *App) formatClosestApproach(pos *Position) (string, bool) {
+	eta, distNM, ok := closestApproach(pos)
+	if !ok {
+		return "", false
 	}
+	return fmt.Sprintf("closest approach in %s at %s", eta.Round(time.Second), formatDistance(distNM, a.Units, a.DistancePrecision)), true
+}
 
-	alert.WriteString(fmt.Sprintf("\n           https://www.flightaware.com/live/flight/id/%s", curr.FlightID))
+// formatTime renders a timestamp for display.
+func (a *App) formatTime(t time.Time) string {
+	if a.Zulu {
+		return t.UTC().Format(ZuluTimeFormat)
+	}
+	if a.Timezone != nil {
+		t = t.In(a.Timezone)
+	}
+	layout := a.TimestampFormat
+	if layout == "" {
+		layout = DefaultTimestampFormat
+	}
+	return t.Format(layout)
+}
 
-	fmt.Println(alert.String())
+// formatShortTime renders a time like formatTime, but only to the minute unless
+// TimestampFormat says otherwise.
+func (a *App) formatShortTime(t time.Time) string {
+	if a.Zulu || a.TimestampFormat != "" {
+		return a.formatTime(t)
+	}
+	if a.Timezone != nil {
+		t = t.In(a.Timezone)
+	}
+	return t.Format(ShortTimestampFormat)
 }
 
 func (a *App) say(curr *Position) {
-	if !a.Announce {
+	if !a.canAnnounce(curr.Timestamp) {
 		return
 	}
-	var words []string
-	words = append(words, identToWords(curr.Ident)...)
-	words = append(words, "is")
-	words = append(words, phonetic(fmt.Sprintf("%.1f", curr.Distance))...)
-	words = append(words, "nautical miles")
-	words = append(words, "to the", cardinalDirection(curr.Bearing), ",")
-	if curr.Altitude != nil {
-		words = append(words, "at")
-		words = append(words, altitudeToWords(*curr.Altitude)...)
-		words = append(words, ",")
+	alert, err := a.renderTemplate(SpeechSink, curr)
+	if err != nil {
+		slog.Error("could not render speech template", "flight_id", curr.FlightID, "error", err)
+		return
+	}
+	a.speak(alert)
+}
+
+// canAnnounce reports whether announcements are enabled and it is not quiet
+// hours at time t. Nothing is announced in a dry run.
+func (a *App) canAnnounce(t time.Time) bool {
+	return a.Announce && !a.DryRun && !a.QuietHours.Contains(t)
+}
+
+// speak announces the text, after any announcements already queued.
+func (a *App) speak(text string) {
+	if !a.queueSpeech(text) {
+		a.speakNow(text)
+	}
+}
+
+// speakNow runs the text-to-speech command, killing it if it runs for too
+// long.
+func (a *App) speakNow(text string) {
+	err := a.runSpeech(text)
+	switch {
+	case errors.Is(err, errNoTTSCommand):
+		a.ttsWarning.Do(func() {
+			slog.Warn("cannot make announcements", "error", err)
+		})
+	case errors.Is(err, errTTSTimeout):
+		slog.Warn("killed speech command after exceeding timeout", "timeout", a.TTSTimeout)
+	case err != nil:
+		slog.Error("speech command failed", "error", err)
+	}
+}
+
+// runSpeech runs the text-to-speech command to speak the text, returning
+// errNoTTSCommand if the command can't be found or errTTSTimeout if it was
+// killed for running too long.
+func (a *App) runSpeech(text string) error {
+	command, err := exec.LookPath(a.TTSCommand)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errNoTTSCommand, err)
 	}
-	if curr.Heading != nil {
-		words = append(words, cardinalDirection(*curr.Heading), "bound", ",")
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.TTSTimeout)
+	defer cancel()
+	if err := exec.CommandContext(ctx, command, ttsArgs(command, text, a.TTSRate, a.TTSVoice)...).Run(); errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", errTTSTimeout, a.TTSTimeout)
+	} else if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+	return nil
+}
+
+var nNumberRegex = regexp.MustCompile("^N([0-9]{1,5})([A-Z]{0,2})$")
+
+// flightName identifies a flight for display by its ident. Some positions
+// have no ident, in which case the registration is used, or failing that the
+// ICAO address or flight ID.
+func flightName(pos *Position) string {
+	switch {
+	case pos.Ident != "":
+		return pos.Ident
+	case pos.Reg != "":
+		return pos.Reg
+	case pos.ICAOHex != "":
+		return pos.ICAOHex
 	}
-	if curr.Speed != nil {
-		words = append(words, phonetic(fmt.Sprintf("%.0f", *curr.Speed))...)
-		words = append(words, "knots")
+	return pos.FlightID
+}
+
+// normalizeICAOHex lowercases a transponder's ICAO address, returning an empty
+// string if it isn't one. dump1090 marks addresses which aren't ICAO ones, as
+// from TIS-B, with a leading ~.
+func normalizeICAOHex(hex string) string {
+	hex = strings.ToLower(strings.TrimSpace(hex))
+	if !icaoHexRegex.MatchString(hex) {
+		return ""
 	}
-	alert := strings.Join(words, " ")
+	return hex
+}
+
+var icaoHexRegex = regexp.MustCompile("^[0-9a-f]{6}$")
 
-	if err := exec.Command("say", "-r", "200", alert).Run(); err != nil {
-		log.Println(err.Error())
+// flightNameToWords is how a flight is identified in announcements. Like
+// flightName, it falls back to the registration, ICAO address, or flight ID,
+// which are spelled out phonetically.
+func (a *App) flightNameToWords(pos *Position) []string {
+	if pos.Ident != "" {
+		return a.identToWords(pos.Ident)
 	}
+	name := strings.ToUpper(strings.ReplaceAll(flightName(pos), "-", ""))
+	return phonetic(name, a.PhoneticStyle)
 }
 
-func identToWords(ident string) []string {
-	icaoRegex := regexp.MustCompile("^[A-Z]{3}")
-	icao := icaoRegex.FindString(ident)
-	if icao == "" {
-		return phonetic(ident)
+func (a *App) identToWords(ident string) []string {
+	// US tail numbers are spoken as november, the grouped digits, and then any
+	// trailing letters phonetically.
+	if m := nNumberRegex.FindStringSubmatch(ident); m != nil {
+		if digits, ok := groupDigits(m[1]); ok {
+			words := append([]string{"november"}, digits...)
+			return append(words, phonetic(m[2], a.PhoneticStyle)...)
+		}
+		return phonetic(ident, a.PhoneticStyle)
 	}
-	suffix := ident[3:]
-	callsign := icaoCallsign(icao)
+
+	callsign, suffix := a.airlineCallsign(ident)
 	if callsign == "" {
-		return phonetic(ident)
+		return phonetic(ident, a.PhoneticStyle)
 	}
 
 	words := []string{callsign}
+	if digits, ok := groupDigits(suffix); ok {
+		words = append(words, digits...)
+	} else {
+		words = append(words, phonetic(suffix, a.PhoneticStyle)...)
+	}
+	return words
+}
+
+// groupDigits splits a flight number into the groups it is spoken in, e.g.
+// 1234 as "12 34" and 123 as "1 23". ok is false unless s is two to four
+// digits, which are spelled out digit by digit instead.
+func groupDigits(s string) (groups []string, ok bool) {
 	numberRegex := regexp.MustCompile("^[0-9]{2,4}$")
-	if numberRegex.MatchString(suffix) {
-		switch len(suffix) {
+	if !numberRegex.MatchString(s) {
+		return nil, false
+	}
+	switch len(s) {
+	case 2:
+		return []string{s}, true
+	case 3:
+		return []string{s[0:1], s[1:]}, true
+	default:
+		return []string{s[0:2], s[2:]}, true
+	}
+}
+
+// icaoCallsign looks up the spoken callsign for an ICAO airline code,
+// preferring any loaded from the callsign file over the built-in table.
+func (a *App) icaoCallsign(icao string) string {
+	if callsign, ok := a.callsigns[icao]; ok {
+		return callsign
+	}
+	return icaoCallsigns[icao]
+}
+
+// printCallsigns writes out a callsign table sorted by airline code.
+func printCallsigns(w io.Writer, callsigns map[string]string) {
+	codes := make([]string, 0, len(callsigns))
+	for code := range callsigns {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "%s\t%s\n", code, callsigns[code])
+	}
+}
+
+// icaoCallsigns maps ICAO airline codes to their spoken callsigns.
+var icaoCallsigns = map[string]string{
+	"UAL": "united",
+	"FDX": "fedex",
+	"DAL": "delta",
+	"KAP": "cair",
+	"NKS": "spirit",
+	"RPA": "brickyard",
+	"ACA": "air canada",
+	"POE": "porter",
+	"SWA": "southwest",
+	"JBU": "jet blue",
+	"EIN": "shamrock",
+	"AAL": "american",
+	"ASA": "alaska",
+	"FFT": "frontier flight",
+	"JAL": "japan air",
+	"JZA": "jazz",
+	"AFR": "air france",
+	"FPY": "player",
+	"WUP": "up jet",
+	"BAW": "speed bird",
+	"VJA": "vista am",
+}
+
+// distanceToWords verbalizes a distance given in nautical miles in the units
+// and distance style, speaking digits in the given phonetic style.
+func distanceToWords(nm float64, units, style, phoneticStyle string) []string {
+	dist, one, many := nm, "a mile", "nautical miles"
+	if unit.IsMetric(units) {
+		dist, one, many = nm*unit.KMPerNM, "a kilometer", "kilometers"
+	}
+	if style == FriendlyDistance {
+		if dist < 1 {
+			return []string{"less than", one}
+		}
+		if math.Round(dist*10)/10 >= 10 {
+			return append(phonetic(fmt.Sprintf("%.0f", dist), phoneticStyle), many)
+		}
+	}
+	return append(phonetic(fmt.Sprintf("%.1f", dist), phoneticStyle), many)
+}
+
+// spokenDistance verbalizes a distance in nautical miles according to
+// DistanceSpeech, in our units.
+func (a *App) spokenDistance(nm float64) []string {
+	if a.DistanceSpeech == NaturalDistanceSpeech {
+		return naturalDistanceToWords(nm, a.Units)
+	}
+	return distanceToWords(nm, a.Units, a.SpokenDistanceStyle, a.PhoneticStyle)
+}
+
+// spokenSpeed verbalizes a speed given in knots in our units, e.g. "two five
+// zero knots" or "four six three kilometers per hour".
+func (a *App) spokenSpeed(kts float64) []string {
+	if unit.IsMetric(a.Units) {
+		return append(phonetic(fmt.Sprintf("%.0f", kts*unit.KPHPerKnot), a.PhoneticStyle), "kilometers per hour")
+	}
+	return append(phonetic(fmt.Sprintf("%.0f", kts), a.PhoneticStyle), "knots")
+}
+
+// naturalDistanceToWords verbalizes a distance given in nautical miles in the
+// units the way a person would, rounding to the nearest quarter under 3 miles
+// or kilometers, the nearest half under 10, and the nearest whole one beyond
+// that.
+func naturalDistanceToWords(nm float64, units string) []string {
+	dist, name := nm, "mile"
+	if unit.IsMetric(units) {
+		dist, name = nm*unit.KMPerNM, "kilometer"
+	}
+	var quarters int
+	switch {
+	case dist < 3:
+		quarters = int(math.Round(dist * 4))
+	case dist < 10:
+		quarters = int(math.Round(dist*2)) * 2
+	default:
+		quarters = int(math.Round(dist)) * 4
+	}
+	whole, fraction := quarters/4, quarters%4
+	if whole == 0 {
+		switch fraction {
+		case 0:
+			return []string{"less than a quarter of a", name}
+		case 1:
+			return []string{"a quarter of a", name}
 		case 2:
-			words = append(words, suffix)
-		case 3:
-			words = append(words, suffix[0:1], suffix[1:])
-		case 4:
-			words = append(words, suffix[0:2], suffix[2:])
+			return []string{"half a", name}
 		}
-	} else {
-		words = append(words, phonetic(suffix)...)
+		return []string{"three quarters of a", name}
 	}
-	return words
+	words := []string{strconv.Itoa(whole)}
+	switch fraction {
+	case 1:
+		words = append(words, "and a quarter")
+	case 2:
+		words = append(words, "and a half")
+	case 3:
+		words = append(words, "and three quarters")
+	}
+	if quarters == 4 {
+		return append(words, name)
+	}
+	return append(words, name+"s")
 }
 
-func icaoCallsign(icao string) string {
-	callsigns := map[string]string{
-		"UAL": "united",
-		"FDX": "fedex",
-		"DAL": "delta",
-		"KAP": "cair",
-		"NKS": "spirit",
-		"RPA": "brickyard",
-		"ACA": "air canada",
-		"POE": "porter",
-		"SWA": "southwest",
-		"JBU": "jet blue",
-		"EIN": "shamrock",
-		"AAL": "american",
-		"ASA": "alaska",
-		"FFT": "frontier flight",
-		"JAL": "japan air",
-		"JZA": "jazz",
-		"AFR": "air france",
-		"FPY": "player",
-		"WUP": "up jet",
-		"BAW": "speed bird",
-		"VJA": "vista am",
-	}
-	return callsigns[icao]
-}
-
-func altitudeToWords(altitude float64) []string {
+// durationToWords roughly verbalizes a short duration, rounding to the nearest
+// 10 seconds under a minute and to the nearest minute otherwise.
+func durationToWords(d time.Duration) []string {
+	if d < 55*time.Second {
+		secs := int(d.Round(10*time.Second) / time.Second)
+		if secs < 10 {
+			secs = 10
+		}
+		return []string{strconv.Itoa(secs), "seconds"}
+	}
+	mins := int(d.Round(time.Minute) / time.Minute)
+	if mins == 1 {
+		return []string{"1", "minute"}
+	}
+	return []string{strconv.Itoa(mins), "minutes"}
+}
+
+// displayAltitude describes an altitude for display according to
+// AltitudeMode, e.g. "at 3500ft" or "2000ft above you".
+func (a *App) displayAltitude(altitude float64) string {
+	if a.AltitudeMode != AltitudeModeRelative {
+		return "at " + formatAltitude(altitude, a.Units)
+	}
+	height := altitude - a.ObserverElevationFt
+	if height < 0 {
+		return formatAltitude(-height, a.Units) + " below you"
+	}
+	return formatAltitude(height, a.Units) + " above you"
+}
+
+// spokenAltitude speaks an altitude according to AltitudeMode. Relative
+// heights are never given as flight levels, since those are pressure
+// altitudes.
+//
+// In metric units altitudes are spoken in meters, but flight levels are still
+// hundreds of feet.
+func (a *App) spokenAltitude(altitude float64) []string {
+	metric := unit.IsMetric(a.Units)
+	if a.AltitudeMode != AltitudeModeRelative {
+		words := []string{"at"}
+		if metric && (a.TransitionAltitudeFt <= 0 || altitude < a.TransitionAltitudeFt) {
+			return append(append(words, altitudeToWords(altitude*unit.MetersPerFoot, 0, a.PhoneticStyle)...), "meters")
+		}
+		return append(words, altitudeToWords(altitude, a.TransitionAltitudeFt, a.PhoneticStyle)...)
+	}
+	height := altitude - a.ObserverElevationFt
+	relation := "above you"
+	if height < 0 {
+		height, relation = -height, "below you"
+	}
+	name := "feet"
+	if metric {
+		height, name = height*unit.MetersPerFoot, "meters"
+	}
+	words := altitudeToWords(height, 0, a.PhoneticStyle)
+	if len(words) == 0 {
+		return []string{"level with you"}
+	}
+	return append(words, name, relation)
+}
+
+// altitudeToWords verbalizes an altitude in thousands and hundreds of feet (or
+// of meters, given one in meters), or as a flight level at or above the
+// transition altitude. A transition altitude of zero never uses flight levels.
+func altitudeToWords(altitude, transitionAltitude float64, style string) []string {
+	if transitionAltitude > 0 && altitude >= transitionAltitude {
+		level := fmt.Sprintf("%03.0f", altitude/100)
+		return append([]string{"flight level"}, phonetic(level, style)...)
+	}
 	var words []string
 	thousands := int(altitude) / 1000
 	if thousands > 0 {
-		words = append(words, phonetic(strconv.Itoa(thousands))...)
+		words = append(words, phonetic(strconv.Itoa(thousands), style)...)
 		words = append(words, "thousand")
 	}
 	hundreds := (int(altitude) - (thousands * 1000)) / 100
 	if hundreds > 0 {
-		words = append(words, phonetic(strconv.Itoa(hundreds))...)
+		words = append(words, phonetic(strconv.Itoa(hundreds), style)...)
 		words = append(words, "hundred")
 	}
 	return words
 }
 
-func phonetic(plain string) []string {
+// phonetic spells out the letters and digits of plain with the NATO phonetic
+// alphabet, with digits pronounced according to the style.
+func phonetic(plain, style string) []string {
 	var words []string
 	alphabet := map[rune]string{
 		'A': "alpha",
@@ -469,15 +2457,19 @@ func phonetic(plain string) []string {
 		'0': "zero",
 		'1': "one",
 		'2': "two",
-		'3': "three",
 		'4': "four",
-		'5': "five",
 		'6': "six",
 		'7': "seven",
 		'8': "eight",
-		'9': "niner",
 		'.': "point",
 	}
+	digits, ok := phoneticDigits[style]
+	if !ok {
+		digits = phoneticDigits[StandardPhonetics]
+	}
+	for r, word := range digits {
+		alphabet[r] = word
+	}
 	for _, r := range plain {
 		word, ok := alphabet[r]
 		if ok {
@@ -489,6 +2481,81 @@ func phonetic(plain string) []string {
 	return words
 }
 
+// phoneticDigits are the pronunciations of the digits which differ between
+// phonetic styles.
+var phoneticDigits = map[string]map[rune]string{
+	StandardPhonetics: {'3': "three", '5': "five", '9': "niner"},
+	AviationPhonetics: {'3': "tree", '5': "fife", '9': "niner"},
+}
+
+// closestApproach projects the flight along its current heading and speed and
+// works out how long until it passes closest to us and how far away it will be
+// at that point. The projection uses a flat-earth approximation, which is fine
+// over the handful of miles we care about. ok is false if the position is
+// missing a heading or speed, or if the flight is not getting any closer.
+func closestApproach(pos *Position) (eta time.Duration, distNM float64, ok bool) {
+	if pos.Heading == nil || pos.Speed == nil || *pos.Speed <= 0 {
+		return 0, 0, false
+	}
+
+	// Put ourselves at the origin with x pointing east and y pointing north.
+	bearing := pos.Bearing * math.Pi / 180
+	heading := *pos.Heading * math.Pi / 180
+	x, y := pos.Distance*math.Sin(bearing), pos.Distance*math.Cos(bearing)
+	vx, vy := *pos.Speed*math.Sin(heading), *pos.Speed*math.Cos(heading)
+
+	// Time (in hours) at which the distance to the origin is minimized.
+	t := -(x*vx + y*vy) / (vx*vx + vy*vy)
+	if t <= 0 {
+		return 0, 0, false
+	}
+
+	eta = time.Duration(t * float64(time.Hour))
+	distNM = math.Hypot(x+vx*t, y+vy*t)
+	return eta, distNM, true
+}
+
+// verticalState describes whether a flight with the given vertical rate is
+// Climbing, Descending, or Level. Rates within LevelDeadbandFPM of zero count as
+// level, since they are usually just noise.
+func verticalState(fpm float64) string {
+	switch {
+	case fpm > LevelDeadbandFPM:
+		return Climbing
+	case fpm < -LevelDeadbandFPM:
+		return Descending
+	}
+	return Level
+}
+
+// approachState describes whether a flight is Approaching or Departing, by
+// comparing its heading with the bearing from it to us. ok is false if the
+// flight isn't reporting a heading.
+func approachState(pos *Position) (state string, ok bool) {
+	if pos.Heading == nil {
+		return "", false
+	}
+	toUs := math.Mod(pos.Bearing+180, 360)
+	diff := math.Abs(math.Mod(*pos.Heading-toUs, 360))
+	if diff > 180 {
+		diff = 360 - diff
+	}
+	if diff < ApproachToleranceDeg {
+		return Approaching, true
+	}
+	return Departing, true
+}
+
+// magneticBearing converts a true bearing into a magnetic one given the local
+// declination (east positive), normalized to [0, 360).
+func magneticBearing(trueBearing, declination float64) float64 {
+	mag := math.Mod(trueBearing-declination, 360)
+	if mag < 0 {
+		mag += 360
+	}
+	return mag
+}
+
 func cardinalDirection(bearing float64) string {
 	if bearing > 337.5 || bearing <= 22.5 {
 		return "north"
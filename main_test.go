@@ -1,9 +1,27 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/benburwell/firehose"
+	"github.com/skypies/geo"
 )
 
 func TestAltitudeToWords(t *testing.T) {
@@ -19,10 +37,14 @@ func TestAltitudeToWords(t *testing.T) {
 		{10000, "one zero thousand"},
 		{11000, "one one thousand"},
 		{11220, "one one thousand two hundred"},
+		{17900, "one seven thousand niner hundred"},
+		{18000, "flight level one eight zero"},
+		{37000, "flight level three seven zero"},
+		{41000, "flight level four one zero"},
 	}
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%f", test.alt), func(t *testing.T) {
-			actual := strings.Join(altitudeToWords(test.alt), " ")
+			actual := strings.Join(altitudeToWords(test.alt, 18000, StandardPhonetics), " ")
 			if actual != test.exp {
 				t.Errorf("unexpected verbalization: %s", actual)
 			}
@@ -30,6 +52,100 @@ func TestAltitudeToWords(t *testing.T) {
 	}
 }
 
+func TestPhoneticStyle(t *testing.T) {
+	tests := []struct {
+		style string
+		exp   string
+	}{
+		{StandardPhonetics, "november zero one two three four five six seven eight niner point alpha"},
+		{AviationPhonetics, "november zero one two tree four fife six seven eight niner point alpha"},
+		{"", "november zero one two three four five six seven eight niner point alpha"},
+	}
+	for _, test := range tests {
+		t.Run(test.style, func(t *testing.T) {
+			actual := strings.Join(phonetic("N0123456789.A", test.style), " ")
+			if actual != test.exp {
+				t.Errorf("unexpected verbalization: %s", actual)
+			}
+		})
+	}
+
+	if actual := strings.Join(altitudeToWords(5900, 18000, AviationPhonetics), " "); actual != "fife thousand niner hundred" {
+		t.Errorf("unexpected altitude: %s", actual)
+	}
+	if actual := strings.Join(distanceToWords(3.5, ImperialUnits, PreciseDistance, AviationPhonetics), " "); actual != "tree point fife nautical miles" {
+		t.Errorf("unexpected distance: %s", actual)
+	}
+}
+
+func TestRelativeAltitude(t *testing.T) {
+	tests := []struct {
+		mode    string
+		alt     float64
+		display string
+		spoken  string
+	}{
+		{AltitudeModeMSL, 3500, "at 3500ft", "at three thousand five hundred"},
+		{AltitudeModeMSL, 24000, "at 24000ft", "at flight level two four zero"},
+		{AltitudeModeRelative, 3500, "2500ft above you", "two thousand five hundred feet above you"},
+		{AltitudeModeRelative, 24000, "23000ft above you", "two three thousand feet above you"},
+		{AltitudeModeRelative, 1040, "40ft above you", "level with you"},
+		{AltitudeModeRelative, 500, "500ft below you", "five hundred feet below you"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s %f", test.mode, test.alt), func(t *testing.T) {
+			app := &App{AltitudeMode: test.mode, ObserverElevationFt: 1000, TransitionAltitudeFt: 18000, Units: ImperialUnits}
+			if actual := app.displayAltitude(test.alt); actual != test.display {
+				t.Errorf("unexpected display: %s", actual)
+			}
+			if actual := strings.Join(app.spokenAltitude(test.alt), " "); actual != test.spoken {
+				t.Errorf("unexpected verbalization: %s", actual)
+			}
+		})
+	}
+}
+
+func TestMetricSpeech(t *testing.T) {
+	tests := []struct {
+		mode   string
+		alt    float64
+		spoken string
+	}{
+		{AltitudeModeMSL, 3500, "at one thousand meters"},
+		{AltitudeModeMSL, 24000, "at flight level two four zero"},
+		{AltitudeModeRelative, 3500, "seven hundred meters above you"},
+		{AltitudeModeRelative, 1200, "level with you"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s %f", test.mode, test.alt), func(t *testing.T) {
+			app := &App{AltitudeMode: test.mode, ObserverElevationFt: 1000, TransitionAltitudeFt: 18000, Units: MetricUnits}
+			if actual := strings.Join(app.spokenAltitude(test.alt), " "); actual != test.spoken {
+				t.Errorf("unexpected verbalization: %s", actual)
+			}
+		})
+	}
+
+	app := &App{Units: MetricUnits}
+	alt, speed := 3500.0, 250.0
+	pos := &Position{Ident: "UAL1234", Distance: 2, Altitude: &alt, Speed: &speed}
+	text, err := app.renderTemplate(SpeechSink, pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{"three point seven kilometers", "at one thousand meters", "four six three kilometers per hour"} {
+		if !strings.Contains(text, exp) {
+			t.Errorf("expected %q in %q", exp, text)
+		}
+	}
+	if strings.Contains(text, "knots") {
+		t.Errorf("expected no knots in %q", text)
+	}
+	app.Units = ImperialUnits
+	if actual := strings.Join(app.spokenSpeed(speed), " "); actual != "two five zero knots" {
+		t.Errorf("unexpected verbalization: %s", actual)
+	}
+}
+
 func TestIdentToWords(t *testing.T) {
 	tests := []struct {
 		ident string
@@ -43,14 +159,1486 @@ func TestIdentToWords(t *testing.T) {
 		{"FDX12345", "fedex one two three four five"},
 		{"UAL12A", "united one two alpha"},
 		{"N12345", "november one two three four five"},
+		{"N4567", "november 45 67"},
+		{"N123AB", "november 1 23 alpha bravo"},
+		{"N45Z", "november 45 zulu"},
+		{"N1AB", "november one alpha bravo"},
 		{"ZZZ10", "zulu zulu zulu one zero"},
 	}
 	for _, test := range tests {
 		t.Run(test.ident, func(t *testing.T) {
-			actual := strings.Join(identToWords(test.ident), " ")
+			actual := strings.Join((&App{}).identToWords(test.ident), " ")
+			if actual != test.exp {
+				t.Errorf("unexpected verbalization: %s", actual)
+			}
+		})
+	}
+}
+
+func TestFlightName(t *testing.T) {
+	tests := []struct {
+		name    string
+		pos     Position
+		display string
+		spoken  string
+	}{
+		{"ident", Position{FlightID: "UAL641-1", Ident: "UAL641", Reg: "N12345"}, "UAL641", "united 6 41"},
+		{"registration", Position{FlightID: "abc123", Reg: "G-ABCD"}, "G-ABCD", "golf alpha bravo charlie delta"},
+		{"ICAO address", Position{FlightID: "UAL641-1", ICAOHex: "a1b2c3"}, "a1b2c3", "alpha one bravo two charlie three"},
+		{"flight ID", Position{FlightID: "a1b2c3"}, "a1b2c3", "alpha one bravo two charlie three"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := flightName(&test.pos); actual != test.display {
+				t.Errorf("unexpected name: %s", actual)
+			}
+			if actual := strings.Join((&App{}).flightNameToWords(&test.pos), " "); actual != test.spoken {
+				t.Errorf("unexpected verbalization: %s", actual)
+			}
+		})
+	}
+}
+
+func TestNormalizeICAOHex(t *testing.T) {
+	tests := []struct {
+		hex string
+		exp string
+	}{
+		{"A1B2C3", "a1b2c3"},
+		{" ae1234 ", "ae1234"},
+		{"~1a2b3c", ""},
+		{"A1B2", ""},
+		{"ZZZZZZ", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.hex, func(t *testing.T) {
+			if actual := normalizeICAOHex(test.hex); actual != test.exp {
+				t.Errorf("expected %q, got %q", test.exp, actual)
+			}
+		})
+	}
+
+	app := &App{}
+	pos, err := app.newPosition(&firehose.PositionMessage{ID: "A", Hexid: "A1B2C3", Lat: "42", Lon: "-71", Clock: "1000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := app.webhookBody(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"ICAOHex":"a1b2c3"`) {
+		t.Errorf("expected the ICAO address in the payload: %s", body)
+	}
+}
+
+func TestClosestApproach(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name   string
+		pos    Position
+		ok     bool
+		eta    time.Duration
+		distNM float64
+	}{
+		{"head on", Position{Distance: 2, Bearing: 90, Heading: f(270), Speed: f(120)}, true, time.Minute, 0},
+		{"passing abeam", Position{Distance: 2, Bearing: 0, Heading: f(90), Speed: f(120)}, false, 0, 0},
+		{"offset track", Position{Distance: math.Sqrt2, Bearing: 45, Heading: f(180), Speed: f(60)}, true, time.Minute, 1},
+		{"moving away", Position{Distance: 2, Bearing: 90, Heading: f(90), Speed: f(120)}, false, 0, 0},
+		{"no heading", Position{Distance: 2, Bearing: 90, Speed: f(120)}, false, 0, 0},
+		{"no speed", Position{Distance: 2, Bearing: 90, Heading: f(270)}, false, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			eta, dist, ok := closestApproach(&test.pos)
+			if ok != test.ok {
+				t.Fatalf("expected ok=%t, got %t", test.ok, ok)
+			}
+			if (eta - test.eta).Abs() > time.Second {
+				t.Errorf("unexpected eta: %s", eta)
+			}
+			if math.Abs(dist-test.distNM) > 0.01 {
+				t.Errorf("unexpected distance: %f", dist)
+			}
+		})
+	}
+}
+
+func TestFormatClosestApproach(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	app := &App{}
+	pos := &Position{Distance: math.Sqrt2, Bearing: 45, Heading: f(180), Speed: f(60)}
+	if text, ok := app.formatClosestApproach(pos); !ok || text != "closest approach in 1m0s at 1.0nm" {
+		t.Errorf("unexpected closest approach: %q", text)
+	}
+	pos.Heading = f(0)
+	if text, ok := app.formatClosestApproach(pos); ok {
+		t.Errorf("expected no closest approach when moving away, got %q", text)
+	}
+}
+
+func TestDurationToWords(t *testing.T) {
+	tests := []struct {
+		d   time.Duration
+		exp string
+	}{
+		{3 * time.Second, "10 seconds"},
+		{38 * time.Second, "40 seconds"},
+		{58 * time.Second, "1 minute"},
+		{150 * time.Second, "3 minutes"},
+	}
+	for _, test := range tests {
+		t.Run(test.d.String(), func(t *testing.T) {
+			actual := strings.Join(durationToWords(test.d), " ")
+			if actual != test.exp {
+				t.Errorf("unexpected verbalization: %s", actual)
+			}
+		})
+	}
+}
+
+// moveNM returns the point the distance in nautical miles away on the
+// bearing. geo's own MoveNM converts with KNauticalMilePerKM the wrong way
+// round, landing less than a third of the way there.
+func moveNM(from geo.Latlong, bearing, nm float64) geo.Latlong {
+	return from.MoveKM(bearing, geo.NM2KM(nm))
+}
+
+func TestIsInterestingExclusionZones(t *testing.T) {
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		ExclusionZones: []Zone{
+			{Latitude: 42.05, Longitude: -71.0, RadiusNM: 1},
+		},
+	}
+	tests := []struct {
+		name  string
+		point geo.Latlong
+		exp   bool
+	}{
+		{"inside exclusion zone", geo.Latlong{Lat: 42.05, Long: -71.0}, false},
+		{"edge of exclusion zone", moveNM(app.ExclusionZones[0].center(), 90, 0.9), false},
+		{"outside exclusion zone", geo.Latlong{Lat: 41.95, Long: -71.0}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pos := &Position{
+				Point:    test.point,
+				Distance: test.point.DistNM(app.myLocation()),
+			}
+			if actual := app.isInteresting(pos); actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestMagneticBearing(t *testing.T) {
+	tests := []struct {
+		bearing     float64
+		declination float64
+		exp         float64
+	}{
+		{90, 0, 90},
+		{90, -14, 104},
+		{90, 14, 76},
+		{5, 14, 351},
+		{355, -14, 9},
+		{0, 0, 0},
+		{346, -14, 0},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%f/%f", test.bearing, test.declination), func(t *testing.T) {
+			actual := magneticBearing(test.bearing, test.declination)
+			if math.Abs(actual-test.exp) > 1e-9 {
+				t.Errorf("unexpected magnetic bearing: %f", actual)
+			}
+		})
+	}
+}
+
+func TestVerticalState(t *testing.T) {
+	tests := []struct {
+		fpm float64
+		exp string
+	}{
+		{1200, Climbing},
+		{201, Climbing},
+		{200, Level},
+		{0, Level},
+		{-150, Level},
+		{-200, Level},
+		{-201, Descending},
+		{-1200, Descending},
+	}
+	for _, test := range tests {
+		if actual := verticalState(test.fpm); actual != test.exp {
+			t.Errorf("%.0f fpm: expected %s, got %s", test.fpm, test.exp, actual)
+		}
+	}
+}
+
+func TestApproachState(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name    string
+		bearing float64
+		heading *float64
+		exp     string
+		ok      bool
+	}{
+		{"north of us heading south", 0, f(180), Approaching, true},
+		{"north of us heading north", 0, f(0), Departing, true},
+		{"north of us heading southwest", 0, f(225), Approaching, true},
+		{"north of us heading east", 0, f(90), Departing, true},
+		{"east of us heading west", 90, f(270), Approaching, true},
+		{"northwest of us heading southeast", 315, f(135), Approaching, true},
+		{"northwest of us heading north", 315, f(10), Departing, true},
+		{"south of us heading north across 360", 190, f(5), Approaching, true},
+		{"no heading", 0, nil, "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state, ok := approachState(&Position{Bearing: test.bearing, Heading: test.heading})
+			if state != test.exp || ok != test.ok {
+				t.Errorf("expected %q, %t; got %q, %t", test.exp, test.ok, state, ok)
+			}
+		})
+	}
+}
+
+func TestFormatTimeZulu(t *testing.T) {
+	ts := time.Date(2024, 7, 4, 10, 3, 58, 0, time.FixedZone("EDT", -4*60*60))
+	app := &App{Zulu: true}
+	if actual := app.formatTime(ts); actual != "14:03Z" {
+		t.Errorf("unexpected zulu time: %s", actual)
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	ts := time.Date(2024, 7, 4, 14, 3, 58, 0, time.UTC)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("no timezone database: %v", err)
+	}
+	tests := []struct {
+		name     string
+		timezone *time.Location
+		format   string
+		exp      string
+	}{
+		{"default", time.UTC, "", "14:03:58"},
+		{"timezone", tokyo, "", "23:03:58"},
+		{"12 hour", time.UTC, "3:04:05 PM", "2:03:58 PM"},
+		{"with date", tokyo, "2006-01-02 15:04", "2024-07-04 23:03"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &App{Timezone: test.timezone, TimestampFormat: test.format}
+			if actual := app.formatTime(ts); actual != test.exp {
+				t.Errorf("expected %s, got %s", test.exp, actual)
+			}
+		})
+	}
+}
+
+// testPosition builds a position message for a flight at the given point and
+// clock.
+func testPosition(id string, point geo.Latlong, clock int64) *firehose.PositionMessage {
+	return &firehose.PositionMessage{
+		Type:  "position",
+		ID:    id,
+		Ident: id,
+		Lat:   fmt.Sprintf("%f", point.Lat),
+		Lon:   fmt.Sprintf("%f", point.Long),
+		Clock: fmt.Sprintf("%d", clock),
+	}
+}
+
+func TestLenientPositionParsing(t *testing.T) {
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+	}
+	home := app.myLocation()
+
+	tests := []struct {
+		name   string
+		modify func(*firehose.PositionMessage)
+		check  func(*Position) bool
+	}{
+		{"bad heading", func(m *firehose.PositionMessage) { m.Heading = "north"; m.GS = "120" }, func(p *Position) bool {
+			return p.Heading == nil && p.Speed != nil && *p.Speed == 120
+		}},
+		{"bad speed", func(m *firehose.PositionMessage) { m.GS = "fast"; m.Heading = "90" }, func(p *Position) bool {
+			return p.Speed == nil && p.Heading != nil && *p.Heading == 90
+		}},
+		{"bad altitude", func(m *firehose.PositionMessage) { m.Alt = "FL350" }, func(p *Position) bool {
+			return p.Altitude == nil
+		}},
+		{"bad true heading falls back", func(m *firehose.PositionMessage) { m.HeadingTrue = "?"; m.Heading = "180" }, func(p *Position) bool {
+			return p.Heading != nil && *p.Heading == 180
+		}},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id := fmt.Sprintf("F%d", i)
+			msg := testPosition(id, moveNM(home, 0, 2), 1000)
+			test.modify(msg)
+			app.handlePosition(msg)
+			flights := app.trackedFlights()
+			idx := slices.IndexFunc(flights, func(p Position) bool { return p.FlightID == id })
+			if idx < 0 {
+				t.Fatal("expected the position to be tracked")
+			}
+			if !test.check(&flights[idx]) {
+				t.Errorf("unexpected position: %+v", flights[idx])
+			}
+		})
+	}
+
+	msg := testPosition("BAD", moveNM(home, 0, 2), 1000)
+	msg.Lat = "forty-two"
+	if _, err := app.newPosition(msg); err == nil {
+		t.Error("expected an error for a malformed latitude")
+	}
+}
+
+func TestMalformedAltitudeIsUnknown(t *testing.T) {
+	for _, exclude := range []bool{false, true} {
+		t.Run(fmt.Sprintf("exclude=%t", exclude), func(t *testing.T) {
+			app := &App{
+				Latitude:             42.0,
+				Longitude:            -71.0,
+				InterestingRadiusNM:  10,
+				InterestingCeilingFt: 15000,
+				ExcludeUnknownAlt:    exclude,
+			}
+			msg := testPosition("A", moveNM(app.myLocation(), 0, 2), 1000)
+			msg.Alt = "FL350"
+			app.handlePosition(msg)
+			if tracked := len(app.trackedFlights()) == 1; tracked == exclude {
+				t.Errorf("expected a malformed altitude to be treated as unknown, but tracked=%t", tracked)
+			}
+		})
+	}
+}
+
+func TestOnStale(t *testing.T) {
+	var stale []string
+	var closest []float64
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		OnStale: func(last, nearest Position) {
+			stale = append(stale, last.FlightID)
+			closest = append(closest, nearest.Distance)
+		},
+	}
+	home := app.myLocation()
+	app.handlePosition(testPosition("A", moveNM(home, 0, 8), 1000))
+	app.handlePosition(testPosition("A", moveNM(home, 0, 6), 1010))
+	app.handlePosition(testPosition("A", moveNM(home, 0, 7), 1020))
+	app.handlePosition(testPosition("B", moveNM(home, 90, 8), 1020+int64(CleanupAfter.Seconds())+1))
+	app.cleanupStaleFlights()
+
+	if len(stale) != 1 || stale[0] != "A" {
+		t.Fatalf("unexpected stale flights: %v", stale)
+	}
+	if math.Abs(closest[0]-6) > 0.01 {
+		t.Errorf("unexpected closest approach: %f", closest[0])
+	}
+	if _, ok := app.flights["B"]; !ok {
+		t.Errorf("expected flight B to still be tracked")
+	}
+}
+
+func TestOnStaleCallsBack(t *testing.T) {
+	var tracked []int
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+	}
+	// Calling back into the App would deadlock if OnStale ran under its lock.
+	app.OnStale = func(last, closest Position) {
+		tracked = append(tracked, len(app.trackedFlights()))
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		home := app.myLocation()
+		app.handlePosition(testPosition("A", moveNM(home, 0, 8), 1000))
+		app.handlePosition(testPosition("B", moveNM(home, 90, 8), 1000+int64(CleanupAfter.Seconds())+1))
+		app.cleanupStaleFlights()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnStale deadlocked calling back into the App")
+	}
+	if !slices.Equal(tracked, []int{1}) {
+		t.Errorf("unexpected tracked flight counts seen from OnStale: %v", tracked)
+	}
+}
+
+func TestMaxFlights(t *testing.T) {
+	var evicted []string
+	var tracked []int
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		MaxFlights:           3,
+	}
+	// Evicted flights are passed to OnStale, which may call back into the App.
+	app.OnStale = func(last, closest Position) {
+		evicted = append(evicted, last.FlightID)
+		tracked = append(tracked, len(app.trackedFlights()))
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		home := app.myLocation()
+		app.handlePosition(testPosition("A", moveNM(home, 0, 8), 1000))
+		app.handlePosition(testPosition("B", moveNM(home, 90, 8), 1001))
+		app.handlePosition(testPosition("C", moveNM(home, 180, 8), 1002))
+		// An update to A makes B the least recently updated.
+		app.handlePosition(testPosition("A", moveNM(home, 0, 7), 1003))
+		app.handlePosition(testPosition("D", moveNM(home, 270, 8), 1004))
+		app.handlePosition(testPosition("E", moveNM(home, 270, 9), 1005))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnStale deadlocked calling back into the App on eviction")
+	}
+
+	if !slices.Equal(tracked, []int{3, 3}) {
+		t.Errorf("unexpected tracked flight counts seen from OnStale: %v", tracked)
+	}
+	if !slices.Equal(evicted, []string{"B", "C"}) {
+		t.Errorf("expected B then C to be evicted, got %v", evicted)
+	}
+	if len(app.flights) != 3 {
+		t.Errorf("expected 3 tracked flights, got %d", len(app.flights))
+	}
+	for _, id := range []string{"A", "D", "E"} {
+		if _, ok := app.flights[id]; !ok {
+			t.Errorf("expected flight %s to be tracked", id)
+		}
+	}
+}
+
+func TestCleanupDuringQuietPeriod(t *testing.T) {
+	wall := time.Unix(5000, 0)
+	var stale []string
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		OnStale: func(last, closest Position) {
+			stale = append(stale, last.FlightID)
+		},
+		clock: func() time.Time { return wall },
+	}
+	home := app.myLocation()
+	app.handlePosition(testPosition("A", moveNM(home, 0, 8), 1000))
+	app.cleanupStaleFlights()
+	if len(app.flights) != 1 {
+		t.Fatalf("expected flight to be tracked, got %d flights", len(app.flights))
+	}
+
+	// No further messages arrive, but time passes.
+	wall = wall.Add(CleanupAfter + time.Second)
+	app.cleanupStaleFlights()
+	if len(app.flights) != 0 || len(stale) != 1 || stale[0] != "A" {
+		t.Errorf("expected A to be cleaned up, got %d flights and stale %v", len(app.flights), stale)
+	}
+}
+
+// TestConcurrentAccess exercises the flights map from the stream, the cleanup
+// ticker, and the HTTP server at once. Run it with -race.
+func TestConcurrentAccess(t *testing.T) {
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		AlertRadiusNM:        3,
+		// Alerts are only logged, so that the test checks the map and not
+		// the sinks.
+		DryRun: true,
+	}
+	home := app.myLocation()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				app.trackedFlights()
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				app.cleanupStaleFlights()
+			}
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		id := fmt.Sprintf("F%d", i%10)
+		app.handlePosition(testPosition(id, moveNM(home, float64(i), 5-float64(i%5)), 1000+int64(i)))
+	}
+	close(done)
+	wg.Wait()
+
+	if flights := app.trackedFlights(); len(flights) != 10 {
+		t.Errorf("expected 10 tracked flights, got %d", len(flights))
+	}
+}
+
+func TestValidateRectangle(t *testing.T) {
+	tests := []struct {
+		name  string
+		rect  firehose.Rectangle
+		valid bool
+	}{
+		{"valid", firehose.Rectangle{LowLat: 40, LowLon: -71, HiLat: 41, HiLon: -70}, true},
+		{"inverted latitude", firehose.Rectangle{LowLat: 41, LowLon: -71, HiLat: 40, HiLon: -70}, false},
+		{"inverted longitude", firehose.Rectangle{LowLat: 40, LowLon: -70, HiLat: 41, HiLon: -71}, false},
+		{"empty", firehose.Rectangle{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateRectangle(test.rect)
+			if (err == nil) != test.valid {
+				t.Errorf("unexpected validation result: %v", err)
+			}
+		})
+	}
+}
+
+func TestIsInterestingAltitudeBands(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	bands := []AltitudeBand{
+		{MinFt: 0, MaxFt: 3000},
+		{MinFt: 30000, MaxFt: 45000},
+	}
+	tests := []struct {
+		name    string
+		alt     *float64
+		exclude bool
+		exp     bool
+	}{
+		{"low band", f(1500), false, true},
+		{"top of low band", f(3000), false, true},
+		{"between bands", f(15000), false, false},
+		{"high band", f(37000), false, true},
+		{"above all bands", f(51000), false, false},
+		{"unknown included", nil, false, true},
+		{"unknown excluded", nil, true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &App{
+				InterestingCeilingFt: 15000,
+				AltitudeBands:        bands,
+				ExcludeUnknownAlt:    test.exclude,
+			}
+			if actual := app.isInterestingAltitude(test.alt); actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestIsInterestingFloor(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name    string
+		alt     *float64
+		exclude bool
+		exp     bool
+	}{
+		{"below floor", f(12000), false, false},
+		{"at floor", f(30000), false, true},
+		{"cruising", f(37000), false, true},
+		{"above ceiling", f(47000), false, false},
+		{"unknown included", nil, false, true},
+		{"unknown excluded", nil, true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &App{
+				InterestingFloorFt:   30000,
+				InterestingCeilingFt: 45000,
+				ExcludeUnknownAlt:    test.exclude,
+			}
+			if actual := app.isInterestingAltitude(test.alt); actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestIsInterestingSpeed(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name   string
+		speed  *float64
+		dist   float64
+		alt    *float64
+		filter bool
+		exp    bool
+	}{
+		{"in range", f(90), 2, f(1000), false, true},
+		{"at minimum", f(60), 2, f(1000), false, true},
+		{"too slow", f(40), 2, f(1000), false, false},
+		{"too fast", f(450), 2, f(1000), false, false},
+		{"missing included", nil, 2, f(1000), false, true},
+		{"missing filtered", nil, 2, f(1000), true, false},
+		{"in range outside radius", f(90), 20, f(1000), false, false},
+		{"in range above ceiling", f(90), 2, f(20000), false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &App{
+				InterestingRadiusNM:  10,
+				InterestingCeilingFt: 15000,
+				MinSpeedKts:          60,
+				MaxSpeedKts:          200,
+				FilterMissingSpeed:   test.filter,
+			}
+			pos := &Position{Speed: test.speed, Distance: test.dist, Altitude: test.alt}
+			if actual := app.isInteresting(pos); actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestRequireAirport(t *testing.T) {
+	alt := 1000.0
+	tests := []struct {
+		name      string
+		orig, dst string
+		require   bool
+		exp       bool
+	}{
+		{"both airports", "KIAD", "KBOS", true, true},
+		{"origin only", "KIAD", "", true, true},
+		{"destination only", "", "KBOS", true, true},
+		{"no airports", "", "", true, false},
+		{"location instead of airport", "L 42.36, -71.01", "", true, false},
+		{"no airports allowed", "", "", false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &App{InterestingRadiusNM: 10, InterestingCeilingFt: 15000, RequireAirport: test.require}
+			pos := &Position{Origin: test.orig, Destination: test.dst, Distance: 2, Altitude: &alt}
+			if actual := app.isInteresting(pos); actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestIsDuplicateReg(t *testing.T) {
+	app := &App{DedupByReg: true}
+	start := time.Unix(1000, 0)
+	tests := []struct {
+		name string
+		pos  Position
+		exp  bool
+	}{
+		{"first alert", Position{FlightID: "A-1", Reg: "N12345", Timestamp: start}, false},
+		{"same flight again", Position{FlightID: "A-1", Reg: "N12345", Timestamp: start.Add(time.Minute)}, false},
+		{"new flight id", Position{FlightID: "A-2", Reg: "N12345", Timestamp: start.Add(2 * time.Minute)}, true},
+		{"no registration", Position{FlightID: "B-1", Timestamp: start.Add(2 * time.Minute)}, false},
+		{"other registration", Position{FlightID: "C-1", Reg: "N54321", Timestamp: start.Add(2 * time.Minute)}, false},
+		{"after window", Position{FlightID: "A-3", Reg: "N12345", Timestamp: start.Add(time.Minute + RegDedupWindow)}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := app.isDuplicateReg(&test.pos)
+			if actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+			if !actual {
+				app.recordAlertedReg(&test.pos)
+			}
+		})
+	}
+}
+
+func TestShouldAlertOnce(t *testing.T) {
+	app := &App{AlertRadiusNM: 3, AlertOnce: true}
+	flight := &track{last: &Position{Distance: 2.5}}
+	if !app.shouldAlert(flight, &Position{Distance: 2}) {
+		t.Fatalf("expected first approach to alert")
+	}
+	flight.alerted = true
+	if app.shouldAlert(flight, &Position{Distance: 1}) {
+		t.Errorf("expected subsequent approach not to alert")
+	}
+	app.AlertOnce = false
+	if !app.shouldAlert(flight, &Position{Distance: 1}) {
+		t.Errorf("expected subsequent approach to alert without alert-once")
+	}
+}
+
+func TestZeroInterestingRadius(t *testing.T) {
+	box := &firehose.Rectangle{LowLat: 41, LowLon: -72, HiLat: 43, HiLon: -70}
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingCeilingFt: 15000,
+		ObservationBox:       box,
+	}
+	if actual := app.subscriptionBox(); len(actual) != 1 || actual[0] != *box {
+		t.Errorf("expected the explicit observation box, got %+v", actual)
+	}
+	pos := &Position{Distance: 55}
+	if !app.isInteresting(pos) {
+		t.Errorf("expected distant flight to be interesting with zero radius")
+	}
+	app.InterestingRadiusNM = 10
+	if app.isInteresting(pos) {
+		t.Errorf("expected distant flight not to be interesting with a radius")
+	}
+}
+
+func TestCheckConvergence(t *testing.T) {
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		AlertRadiusNM:        3,
+		AlertConvergence:     true,
+		ConvergenceNM:        1,
+	}
+	home := app.myLocation()
+	app.handlePosition(testPosition("A", moveNM(home, 90, 2), 1000))
+	app.handlePosition(testPosition("B", moveNM(home, 270, 2), 1000))
+	app.handlePosition(testPosition("B", moveNM(home, 270, 1), 1010))
+	if len(app.converging) != 0 {
+		t.Fatalf("expected no convergence yet, got %v", app.converging)
+	}
+	app.handlePosition(testPosition("B", moveNM(home, 90, 1.5), 1020))
+	if !app.converging[newFlightPair("A", "B")] {
+		t.Errorf("expected A and B to be converging")
+	}
+}
+
+func TestWarnProximityUnits(t *testing.T) {
+	var b strings.Builder
+	logger, err := newLogger(&b, JSONLogFormat, "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+
+	alt := 400.0
+	app := &App{Units: MetricUnits}
+	app.warnProximity(&Position{FlightID: "N1-1", Ident: "N1", AircraftType: "C172", Distance: 0.4, Bearing: 90, Altitude: &alt})
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(b.String()), &record); err != nil {
+		t.Fatalf("expected a single JSON record, got %q: %v", b.String(), err)
+	}
+	if exp := "PROXIMITY WARNING: N1 (C172) is 0.7km to the east at 122m"; record["msg"] != exp {
+		t.Errorf("expected %q, got %q", exp, record["msg"])
+	}
+
+	b.Reset()
+	app.AltitudeMode, app.ObserverElevationFt = AltitudeModeRelative, 100
+	app.warnProximity(&Position{FlightID: "N1-1", Ident: "N1", AircraftType: "C172", Distance: 0.4, Bearing: 90, Altitude: &alt})
+	if err := json.Unmarshal([]byte(b.String()), &record); err != nil {
+		t.Fatalf("expected a single JSON record, got %q: %v", b.String(), err)
+	}
+	if exp := "PROXIMITY WARNING: N1 (C172) is 0.7km to the east 91m above you"; record["msg"] != exp {
+		t.Errorf("expected %q, got %q", exp, record["msg"])
+	}
+}
+
+func TestIsProximityWarning(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	app := &App{ProximityWarning: true, ProximityRadiusNM: 0.5, ProximityAltitudeFt: 500}
+	tests := []struct {
+		name   string
+		pos    Position
+		warned bool
+		exp    bool
+	}{
+		{"close and low", Position{Distance: 0.3, Altitude: f(400)}, false, true},
+		{"close but high", Position{Distance: 0.3, Altitude: f(2000)}, false, false},
+		{"low but far", Position{Distance: 2, Altitude: f(400)}, false, false},
+		{"unknown altitude", Position{Distance: 0.3}, false, false},
+		{"already warned", Position{Distance: 0.3, Altitude: f(400)}, true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flight := &track{last: &test.pos, warned: test.warned}
+			if actual := app.isProximityWarning(flight, &test.pos); actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+		})
+	}
+
+	// In relative mode the altitude is measured from the observer, so a flight
+	// 400ft above someone 1000ft up is low even though it is at 1400ft.
+	pos := Position{Distance: 0.3, Altitude: f(1400)}
+	flight := &track{last: &pos}
+	if app.isProximityWarning(flight, &pos) {
+		t.Errorf("expected 1400ft MSL to be high")
+	}
+	app.AltitudeMode, app.ObserverElevationFt = AltitudeModeRelative, 1000
+	if !app.isProximityWarning(flight, &pos) {
+		t.Errorf("expected 400ft above the observer to be low")
+	}
+}
+
+func TestDistanceToWords(t *testing.T) {
+	tests := []struct {
+		nm    float64
+		units string
+		style string
+		exp   string
+	}{
+		{0.04, ImperialUnits, PreciseDistance, "zero point zero nautical miles"},
+		{2.5, ImperialUnits, PreciseDistance, "two point five nautical miles"},
+		{12.3, ImperialUnits, PreciseDistance, "one two point three nautical miles"},
+		{0.04, ImperialUnits, FriendlyDistance, "less than a mile"},
+		{0.99, ImperialUnits, FriendlyDistance, "less than a mile"},
+		{1, ImperialUnits, FriendlyDistance, "one point zero nautical miles"},
+		{9.94, ImperialUnits, FriendlyDistance, "niner point niner nautical miles"},
+		{9.96, ImperialUnits, FriendlyDistance, "one zero nautical miles"},
+		{12.3, ImperialUnits, FriendlyDistance, "one two nautical miles"},
+		{2.5, MetricUnits, PreciseDistance, "four point six kilometers"},
+		{0.5, MetricUnits, FriendlyDistance, "less than a kilometer"},
+		{0.6, MetricUnits, FriendlyDistance, "one point one kilometers"},
+		{12.3, MetricUnits, FriendlyDistance, "two three kilometers"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%s/%f", test.units, test.style, test.nm), func(t *testing.T) {
+			actual := strings.Join(distanceToWords(test.nm, test.units, test.style, StandardPhonetics), " ")
+			if actual != test.exp {
+				t.Errorf("unexpected verbalization: %s", actual)
+			}
+		})
+	}
+}
+
+func TestNaturalDistanceToWords(t *testing.T) {
+	tests := []struct {
+		nm  float64
+		exp string
+	}{
+		{0.1, "less than a quarter of a mile"},
+		{0.3, "a quarter of a mile"},
+		{0.5, "half a mile"},
+		{0.8, "three quarters of a mile"},
+		{1.04, "1 mile"},
+		{1.2, "1 and a quarter miles"},
+		{2.5, "2 and a half miles"},
+		{2.8, "2 and three quarters miles"},
+		{2.9, "3 miles"},
+		{4.3, "4 and a half miles"},
+		{7.1, "7 miles"},
+		{12.3, "12 miles"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%f", test.nm), func(t *testing.T) {
+			actual := strings.Join(naturalDistanceToWords(test.nm, ImperialUnits), " ")
+			if actual != test.exp {
+				t.Errorf("unexpected verbalization: %s", actual)
+			}
+		})
+	}
+
+	metric := []struct {
+		nm  float64
+		exp string
+	}{
+		{0.05, "less than a quarter of a kilometer"},
+		{0.27, "half a kilometer"},
+		{0.54, "1 kilometer"},
+		{1.35, "2 and a half kilometers"},
+		{6.6, "12 kilometers"},
+	}
+	for _, test := range metric {
+		t.Run(fmt.Sprintf("metric/%f", test.nm), func(t *testing.T) {
+			actual := strings.Join(naturalDistanceToWords(test.nm, MetricUnits), " ")
 			if actual != test.exp {
 				t.Errorf("unexpected verbalization: %s", actual)
 			}
 		})
 	}
 }
+
+func TestDistanceSpeech(t *testing.T) {
+	pos := &Position{Ident: "N12345", Distance: 2.5, Bearing: 90}
+	tests := []struct {
+		speech string
+		exp    string
+	}{
+		{PhoneticDistanceSpeech, "two point five nautical miles"},
+		{NaturalDistanceSpeech, "2 and a half miles"},
+	}
+	for _, test := range tests {
+		t.Run(test.speech, func(t *testing.T) {
+			app := &App{SpokenDistanceStyle: PreciseDistance, DistanceSpeech: test.speech}
+			if actual, _ := app.renderTemplate(SpeechSink, pos); !strings.Contains(actual, test.exp) {
+				t.Errorf("expected %q in announcement: %s", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestPrintCallsigns(t *testing.T) {
+	var b strings.Builder
+	printCallsigns(&b, map[string]string{"UAL": "united", "AAL": "american", "DAL": "delta"})
+	exp := "AAL\tamerican\nDAL\tdelta\nUAL\tunited\n"
+	if b.String() != exp {
+		t.Errorf("unexpected output: %q", b.String())
+	}
+}
+
+func TestWebhookRedirects(t *testing.T) {
+	for _, follow := range []bool{false, true} {
+		t.Run(fmt.Sprintf("follow=%t", follow), func(t *testing.T) {
+			var received []string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/new", http.StatusFound)
+			})
+			mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received = append(received, r.Method+" "+r.Header.Get("content-type")+" "+string(body))
+			})
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			app := &App{WebhookURL: srv.URL + "/old", WebhookFollowRedirects: follow}
+			body, err := app.webhookBody(&Position{FlightID: "UAL1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			app.sendWebhook(webhookJob{url: app.WebhookURL, contentType: app.webhookContentType(body), body: body})
+
+			if !follow {
+				if len(received) != 0 {
+					t.Errorf("expected redirect not to be followed, got %v", received)
+				}
+				return
+			}
+			if len(received) != 1 || !strings.HasPrefix(received[0], "POST application/json {") {
+				t.Errorf("expected POST to be preserved across redirect, got %v", received)
+			}
+		})
+	}
+}
+
+func TestWebhookBodyObserver(t *testing.T) {
+	pos := &Position{FlightID: "UAL1", Ident: "UAL1"}
+	app := &App{Latitude: 42, Longitude: -71, StationID: "roof"}
+
+	body, err := app.webhookBody(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), "Observer") {
+		t.Errorf("expected no observer by default: %s", body)
+	}
+
+	app.IncludeObserver = true
+	body, err = app.webhookBody(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"Ident":"UAL1"`) || !strings.Contains(string(body), `"Observer":{"StationID":"roof","Latitude":42,"Longitude":-71}`) {
+		t.Errorf("unexpected payload: %s", body)
+	}
+}
+
+func TestDryRunAlert(t *testing.T) {
+	var b strings.Builder
+	logger, err := newLogger(&b, JSONLogFormat, "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+
+	alt := 2500.0
+	app := &App{DryRun: true, Announce: true}
+	app.alert(&Position{FlightID: "UAL1-1", Ident: "UAL1", Distance: 1.5, Bearing: 90, Altitude: &alt})
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(b.String()), &record); err != nil {
+		t.Fatalf("expected a single JSON record, got %q: %v", b.String(), err)
+	}
+	if record["msg"] != "would alert" || record["ident"] != "UAL1" || record["distance_nm"] != 1.5 || record["altitude_ft"] != 2500.0 {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
+
+func TestAlertHysteresis(t *testing.T) {
+	var alerts []int64
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		AlertRadiusNM:        3,
+		AlertHysteresisNM:    1,
+		OnAlert: func(pos Position) {
+			alerts = append(alerts, pos.Timestamp.Unix())
+		},
+	}
+	home := app.myLocation()
+	// Jitter back and forth across the alert radius, then leave the widened
+	// radius and come back.
+	distances := []float64{3.5, 2.9, 3.2, 2.8, 3.6, 2.7, 4.5, 4.2, 2.9}
+	for i, dist := range distances {
+		app.handlePosition(testPosition("A", moveNM(home, 0, dist), int64(1000+i*10)))
+	}
+	expected := []int64{1010, 1080}
+	if !slices.Equal(alerts, expected) {
+		t.Errorf("expected alerts at %v, got %v", expected, alerts)
+	}
+}
+
+func TestDryRunSkipsFlightLog(t *testing.T) {
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		DryRun:               true,
+		DBPath:               filepath.Join(t.TempDir(), "flights.db"),
+		SummaryOutput:        io.Discard,
+	}
+	b, err := json.Marshal(testPosition("A", moveNM(app.myLocation(), 0, 2), 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.ReplayFile = writeReplayFile(t, string(b))
+
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(app.DBPath); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected no flight log in a dry run, got %v", err)
+	}
+}
+
+func TestAlertOn(t *testing.T) {
+	// The flight comes in through the alert radius and back out again.
+	distances := []float64{5, 4, 2.5, 2, 2.5, 4, 5}
+	tests := []struct {
+		alertOn  string
+		expected []string
+	}{
+		{AlertOnApproach, []string{"1020 approach"}},
+		{AlertOnDepart, []string{"1050 depart"}},
+		{AlertOnBoth, []string{"1020 approach", "1050 depart"}},
+	}
+	for _, test := range tests {
+		t.Run(test.alertOn, func(t *testing.T) {
+			var alerts []string
+			app := &App{
+				Latitude:             42.0,
+				Longitude:            -71.0,
+				InterestingRadiusNM:  10,
+				InterestingCeilingFt: 15000,
+				AlertRadiusNM:        3,
+				AlertOnce:            true,
+				AlertOn:              test.alertOn,
+				OnAlert: func(pos Position) {
+					event := AlertOnApproach
+					if pos.Departed {
+						event = AlertOnDepart
+					}
+					alerts = append(alerts, fmt.Sprintf("%d %s", pos.Timestamp.Unix(), event))
+				},
+			}
+			home := app.myLocation()
+			for i, dist := range distances {
+				app.handlePosition(testPosition("A", moveNM(home, 0, dist), int64(1000+i*10)))
+			}
+			if !slices.Equal(alerts, test.expected) {
+				t.Errorf("expected alerts %v, got %v", test.expected, alerts)
+			}
+		})
+	}
+}
+
+func TestStreamTimeout(t *testing.T) {
+	app := &App{StreamTimeout: 20 * time.Millisecond}
+
+	server, client := net.Pipe()
+	defer server.Close()
+	start := time.Now()
+	err := app.readStream(context.Background(), firehose.NewStream(client))
+	if !errors.Is(err, ErrStreamStalled) {
+		t.Errorf("expected the stream to stall, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %s to notice the stalled stream", elapsed)
+	}
+
+	// Canceling is still reported as such rather than as a stall.
+	server, client = net.Pipe()
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := app.readStream(ctx, firehose.NewStream(client)); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancellation, got %v", err)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	app := &App{}
+	var finished bool
+	app.goPending(func() {
+		time.Sleep(10 * time.Millisecond)
+		finished = true
+	})
+	app.drain(time.Second)
+	if !finished {
+		t.Error("expected drain to wait for the pending alert")
+	}
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+	app.goPending(func() { <-stuck })
+	start := time.Now()
+	app.drain(20 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected drain to give up after its timeout, took %s", elapsed)
+	}
+}
+
+func TestAlertCooldown(t *testing.T) {
+	var alerts []time.Time
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		AlertRadiusNM:        3,
+		AlertCooldown:        time.Minute,
+		OnAlert: func(pos Position) {
+			alerts = append(alerts, pos.Timestamp)
+		},
+	}
+	home := app.myLocation()
+	app.handlePosition(testPosition("A", moveNM(home, 0, 2.5), 1000))
+	app.handlePosition(testPosition("A", moveNM(home, 0, 2.0), 1010))
+	app.handlePosition(testPosition("A", moveNM(home, 0, 1.5), 1020))
+	if len(alerts) != 1 {
+		t.Fatalf("expected one alert within the cooldown, got %d", len(alerts))
+	}
+
+	app.handlePosition(testPosition("A", moveNM(home, 0, 1.0), 1070))
+	if len(alerts) != 2 {
+		t.Errorf("expected another alert after the cooldown, got %d", len(alerts))
+	}
+}
+
+func TestOnAlertCallsBack(t *testing.T) {
+	var tracked []int
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		AlertRadiusNM:        3,
+	}
+	// Calling back into the App would deadlock if OnAlert ran under its lock.
+	app.OnAlert = func(pos Position) {
+		tracked = append(tracked, len(app.trackedFlights()))
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		home := app.myLocation()
+		app.handlePosition(testPosition("A", moveNM(home, 0, 2.5), 1000))
+		app.handlePosition(testPosition("A", moveNM(home, 0, 2.0), 1010))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnAlert deadlocked calling back into the App")
+	}
+	if !slices.Equal(tracked, []int{1}) {
+		t.Errorf("unexpected tracked flight counts seen from OnAlert: %v", tracked)
+	}
+}
+
+// A fakeStream yields a scripted sequence of messages, then fails with err if
+// it is set, or otherwise blocks until the context is canceled.
+type fakeStream struct {
+	init     string
+	messages []any
+	err      error
+	closed   bool
+}
+
+func (s *fakeStream) Init(command string) error {
+	s.init = command
+	return nil
+}
+
+func (s *fakeStream) NextMessage(ctx context.Context) (*firehose.Message, error) {
+	if len(s.messages) == 0 && s.err != nil {
+		return nil, s.err
+	}
+	if len(s.messages) == 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	payload := s.messages[0]
+	s.messages = s.messages[1:]
+	return &firehose.Message{Payload: payload}, nil
+}
+
+func (s *fakeStream) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	home := geo.Latlong{Lat: 42.0, Long: -71.0}
+	stream := &fakeStream{messages: []any{
+		*testPosition("A", moveNM(home, 0, 5), 1000),
+		*testPosition("B", moveNM(home, 90, 8), 1005),
+		*testPosition("A", moveNM(home, 0, 2), 1010),
+		*testPosition("C", moveNM(home, 180, 20), 1015),
+		firehose.ErrorMessage{ErrorMessage: "Error: Authentication failed"},
+	}}
+	var alerts []string
+	app := &App{
+		Username:             "user",
+		Latitude:             home.Lat,
+		Longitude:            home.Long,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		AlertRadiusNM:        3,
+		DryRun:               true,
+		OnAlert:              func(pos Position) { alerts = append(alerts, pos.FlightID) },
+		Connect:              func() (FirehoseStream, error) { return stream, nil },
+		SummaryOutput:        io.Discard,
+	}
+	err := app.Run(context.Background())
+	if !errors.Is(err, ErrAuthentication) {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+	if !strings.Contains(stream.init, "username user") {
+		t.Errorf("unexpected init command: %s", stream.init)
+	}
+	if !stream.closed {
+		t.Error("expected the stream to be closed")
+	}
+	if !slices.Equal(alerts, []string{"A"}) {
+		t.Errorf("unexpected alerts: %v", alerts)
+	}
+	for _, id := range []string{"A", "B"} {
+		if _, ok := app.flights[id]; !ok {
+			t.Errorf("expected flight %s to be tracked", id)
+		}
+	}
+	if _, ok := app.flights["C"]; ok {
+		t.Error("expected distant flight not to be tracked")
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	home := geo.Latlong{Lat: 42.0, Long: -71.0}
+	// The first position of a flight doesn't alert, so it is sent twice.
+	stream := &fakeStream{messages: []any{
+		*testPosition("A", moveNM(home, 0, 2), 1000),
+		*testPosition("A", moveNM(home, 0, 1.5), 1010),
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var summary strings.Builder
+	app := &App{
+		Latitude:             home.Lat,
+		Longitude:            home.Long,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		AlertRadiusNM:        3,
+		DryRun:               true,
+		OnAlert:              func(pos Position) { cancel() },
+		Connect:              func() (FirehoseStream, error) { return stream, nil },
+		SummaryOutput:        &summary,
+	}
+	if err := app.Run(ctx); err != nil {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+	if len(app.flights) != 1 {
+		t.Errorf("expected one tracked flight, got %d", len(app.flights))
+	}
+	if exp := "Seen 2 positions of 1 flight, with 1 alert"; !strings.HasPrefix(summary.String(), exp) {
+		t.Errorf("expected the session summary on a clean shutdown, got %q", summary.String())
+	}
+}
+
+func TestRunReconnects(t *testing.T) {
+	defer func(d time.Duration) { firehoseBackoff = d }(firehoseBackoff)
+	firehoseBackoff = time.Millisecond
+
+	home := geo.Latlong{Lat: 42.0, Long: -71.0}
+	// The first stream drops, the first attempt to reconnect fails, and the
+	// second delivers the rest of the flight.
+	dropped := &fakeStream{
+		messages: []any{*testPosition("A", moveNM(home, 0, 5), 1000)},
+		err:      errors.New("connection reset by peer"),
+	}
+	reconnected := &fakeStream{messages: []any{
+		*testPosition("A", moveNM(home, 0, 2), 1010),
+		firehose.ErrorMessage{ErrorMessage: "Error: Authentication failed"},
+	}}
+	var connects int
+	var alerts []string
+	app := &App{
+		Latitude:             home.Lat,
+		Longitude:            home.Long,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		AlertRadiusNM:        3,
+		DryRun:               true,
+		InitRetry:            false,
+		OnAlert:              func(pos Position) { alerts = append(alerts, pos.FlightID) },
+		Connect: func() (FirehoseStream, error) {
+			connects++
+			switch connects {
+			case 1:
+				return dropped, nil
+			case 2:
+				return nil, errors.New("connection refused")
+			}
+			return reconnected, nil
+		},
+		SummaryOutput: io.Discard,
+	}
+	err := app.Run(context.Background())
+	if !errors.Is(err, ErrAuthentication) {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+	if connects != 3 {
+		t.Errorf("expected 3 attempts to connect, got %d", connects)
+	}
+	if !dropped.closed || !reconnected.closed {
+		t.Error("expected both streams to be closed")
+	}
+	if !slices.Equal(alerts, []string{"A"}) {
+		t.Errorf("expected the flight to alert after reconnecting, got %v", alerts)
+	}
+
+	// Without InitRetry, failing to connect in the first place is fatal.
+	connects = 0
+	app.Connect = func() (FirehoseStream, error) {
+		connects++
+		return nil, errors.New("connection refused")
+	}
+	if err := app.Run(context.Background()); err == nil || connects != 1 {
+		t.Errorf("expected the first connection failure to be returned, got %v after %d attempts", err, connects)
+	}
+}
+
+func TestOpenStreamBackoff(t *testing.T) {
+	defer func(d time.Duration) { firehoseBackoff = d }(firehoseBackoff)
+	firehoseBackoff = 5 * time.Millisecond
+
+	stream := &fakeStream{}
+	var attempts []time.Time
+	app := &App{
+		Connect: func() (FirehoseStream, error) {
+			attempts = append(attempts, time.Now())
+			if len(attempts) < 4 {
+				return nil, errors.New("connection refused")
+			}
+			return stream, nil
+		},
+	}
+	s, err := app.openStream(context.Background(), true)
+	if err != nil || s != stream {
+		t.Fatalf("expected the stream once connected, got %v, %v", s, err)
+	}
+	if len(attempts) != 4 {
+		t.Fatalf("expected 4 attempts, got %d", len(attempts))
+	}
+	// Timers never fire early, so each wait is at least the doubled backoff.
+	for i := 1; i < len(attempts); i++ {
+		if wait, backoff := attempts[i].Sub(attempts[i-1]), firehoseBackoff<<(i-1); wait < backoff {
+			t.Errorf("expected attempt %d to wait at least %s, waited %s", i+1, backoff, wait)
+		}
+	}
+
+	// Without retrying, the first failure is returned.
+	attempts = nil
+	if _, err := app.openStream(context.Background(), false); err == nil || len(attempts) != 1 {
+		t.Errorf("expected the first failure, got %v after %d attempts", err, len(attempts))
+	}
+
+	// Retrying stops once the context is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	app.Connect = func() (FirehoseStream, error) {
+		cancel()
+		return nil, errors.New("connection refused")
+	}
+	if _, err := app.openStream(ctx, true); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the context's error, got %v", err)
+	}
+}
+
+func TestIsAuthError(t *testing.T) {
+	tests := []struct {
+		msg string
+		exp bool
+	}{
+		{"Error: Authentication failed", true},
+		{"Not AUTHORIZED", true},
+		{"Error: Unauthorized", true},
+		{"invalid credentials supplied", true},
+		{"Error: invalid command", false},
+		{"rate limit exceeded", false},
+		{"oauth proxy timeout", false},
+		{"unknown author field", false},
+	}
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			if actual := isAuthError(test.msg); actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	positionsReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "overhead_positions_received_total",
+		Help: "Positions received from the source.",
+	})
+	positionsInteresting = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "overhead_positions_interesting_total",
+		Help: "Received positions which passed the interesting-flight checks.",
+	})
+	malformedPositions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "overhead_positions_malformed_total",
+		Help: "Position messages dropped because their location or time could not be parsed.",
+	})
+	malformedFields = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "overhead_position_fields_malformed_total",
+		Help: "Optional position fields ignored because they could not be parsed.",
+	})
+	alertsFired = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "overhead_alerts_total",
+		Help: "Alerts fired for flights.",
+	})
+	webhooksSent = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "overhead_webhooks_sent_total",
+		Help: "Webhooks sent successfully.",
+	})
+	webhooksFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "overhead_webhooks_failed_total",
+		Help: "Webhooks which could not be sent, even after retrying.",
+	})
+	webhooksDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "overhead_webhooks_dropped_total",
+		Help: "Webhooks dropped without being sent because the queue was full.",
+	})
+	flightsTracked = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "overhead_flights_tracked",
+		Help: "Flights currently being tracked.",
+	})
+)
+
+// serveMetrics serves Prometheus metrics on the listener until the context is
+// canceled.
+func serveMetrics(ctx context.Context, l net.Listener) {
+	serve(ctx, l, promhttp.Handler(), "metrics")
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestWebhookMetrics(t *testing.T) {
+	// The counters are shared by the whole package, so only their changes
+	// are checked.
+	counts := func() map[string]float64 {
+		m := make(map[string]float64)
+		for name, c := range map[string]prometheus.Counter{
+			"sent":    webhooksSent,
+			"failed":  webhooksFailed,
+			"dropped": webhooksDropped,
+		} {
+			m[name] = testutil.ToFloat64(c)
+		}
+		return m
+	}
+	before := counts()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	app := &App{webhooks: make(chan webhookJob, 1)}
+	app.sendWebhook(webhookJob{url: srv.URL, body: []byte("{}")})
+	app.sendWebhook(webhookJob{url: srv.URL + "/fail", body: []byte("{}")})
+	app.queueJob(webhookJob{url: srv.URL, body: []byte("1")})
+	app.queueJob(webhookJob{url: srv.URL, body: []byte("2")})
+
+	after := counts()
+	for name, exp := range map[string]float64{"sent": 1, "failed": 1, "dropped": 1} {
+		if got := after[name] - before[name]; got != exp {
+			t.Errorf("expected %v more webhooks %s, got %v", exp, name, got)
+		}
+	}
+}
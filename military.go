@@ -0,0 +1,119 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Military detection is best-effort: Firehose doesn't always report the
+// transponder's hex code or squawk, and military flights don't always fly
+// under a recognizable callsign. A flight is considered likely military if any
+// one of the indicators we do have matches.
+
+// hexRange is an inclusive range of 24-bit ICAO transponder addresses.
+type hexRange struct {
+	lo, hi uint32
+}
+
+// militaryHexRanges are blocks of ICAO addresses allocated to military
+// aircraft.
+var militaryHexRanges = []hexRange{
+	{0xADF7C8, 0xAFFFFF}, // United States
+	{0x3A8000, 0x3BFFFF}, // France
+	{0x3EA000, 0x3EBFFF}, // Germany
+	{0x3F4000, 0x3FBFFF}, // Germany
+	{0x43C000, 0x43CFFF}, // United Kingdom
+	{0x7CF800, 0x7CFAFF}, // Australia
+	{0xC20000, 0xC3FFFF}, // Canada
+}
+
+// squawkRange is an inclusive range of transponder codes.
+type squawkRange struct {
+	lo, hi string
+}
+
+// militarySquawks are the US code blocks reserved for military use.
+var militarySquawks = []squawkRange{
+	{"4400", "4477"}, // high-altitude military operations
+	{"5000", "5077"}, // NORAD
+	{"5100", "5377"}, // Department of Defense
+	{"7777", "7777"}, // military interceptor operations
+}
+
+var squawkRegex = regexp.MustCompile("^[0-7]{4}$")
+
+// militaryCallsigns are the prefixes of callsigns flown by military aircraft.
+// More can be added with the military-callsigns setting, which is also the way
+// to pick up the tactical callsigns of local units: those are just words, like
+// plenty of civil callsigns, so only a few well-known ones are listed here.
+var militaryCallsigns = []string{
+	"ARMY",  // US Army
+	"ASCOT", // Royal Air Force
+	"CFC",   // Canadian Forces
+	"CNV",   // US Navy
+	"COBRA", // US tactical
+	"CTM",   // French Air and Space Force
+	"EVAC",  // US Air Force aeromedical evacuation
+	"GAF",   // German Air Force
+	"IAM",   // Italian Air Force
+	"NAVY",  // US Navy
+	"PAT",   // US Army priority air transport
+	"RCH",   // US Air Force Air Mobility Command (Reach)
+	"RRR",   // Royal Air Force
+	"SAM",   // US Air Force special air missions
+	"VIPER", // US tactical
+}
+
+// isLikelyMilitary guesses whether the position is from a military flight.
+func (a *App) isLikelyMilitary(pos *Position) bool {
+	return isMilitaryHex(pos.ICAOHex) || isMilitarySquawk(pos.Squawk) || a.isMilitaryCallsign(pos.Ident)
+}
+
+// isMilitaryHex reports whether an ICAO address falls in a block allocated to
+// military aircraft. Addresses dump1090 marks with a ~ aren't ICAO ones, so
+// never are.
+func isMilitaryHex(hex string) bool {
+	addr, err := strconv.ParseUint(normalizeICAOHex(hex), 16, 24)
+	if err != nil {
+		return false
+	}
+	for _, r := range militaryHexRanges {
+		if uint32(addr) >= r.lo && uint32(addr) <= r.hi {
+			return true
+		}
+	}
+	return false
+}
+
+func isMilitarySquawk(squawk string) bool {
+	if !squawkRegex.MatchString(squawk) {
+		return false
+	}
+	// Codes are fixed-width octal, so they can be compared as strings.
+	for _, r := range militarySquawks {
+		if squawk >= r.lo && squawk <= r.hi {
+			return true
+		}
+	}
+	return false
+}
+
+// isMilitaryCallsign reports whether the ident starts with a military callsign
+// prefix followed by a flight number.
+func (a *App) isMilitaryCallsign(ident string) bool {
+	ident = strings.ToUpper(strings.TrimSpace(ident))
+	for _, prefixes := range [][]string{militaryCallsigns, a.MilitaryCallsigns} {
+		for _, prefix := range prefixes {
+			prefix = strings.ToUpper(prefix)
+			if len(ident) > len(prefix) && strings.HasPrefix(ident, prefix) && isDigit(ident[len(prefix)]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestIsLikelyMilitary(t *testing.T) {
+	app := &App{MilitaryCallsigns: []string{"topcat"}}
+	cases := []struct {
+		name     string
+		pos      Position
+		military bool
+	}{
+		{"airline", Position{Ident: "UAL641", ICAOHex: "A1B2C3", Squawk: "1200"}, false},
+		{"general aviation", Position{Ident: "N123AB"}, false},
+		{"US military hex", Position{Ident: "UAL641", ICAOHex: "AE1234"}, true},
+		{"lowercase hex", Position{ICAOHex: "43c0ff"}, true},
+		{"invalid hex", Position{ICAOHex: "ZZZZZZ"}, false},
+		{"non-ICAO address", Position{ICAOHex: "~ae1234"}, false},
+		{"NORAD squawk", Position{Squawk: "5021"}, true},
+		{"interceptor squawk", Position{Squawk: "7777"}, true},
+		{"VFR squawk", Position{Squawk: "1200"}, false},
+		{"non-octal squawk", Position{Squawk: "5080"}, false},
+		{"reach", Position{Ident: "RCH871"}, true},
+		{"prefix without number", Position{Ident: "SAMSON"}, false},
+		{"tactical", Position{Ident: "VIPER1"}, true},
+		{"civil word callsign", Position{Ident: "SWIFT1"}, false},
+		{"two digit flight number", Position{Ident: "DLH12"}, false},
+		{"configured prefix", Position{Ident: "TOPCAT21"}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := app.isLikelyMilitary(&c.pos); actual != c.military {
+				t.Errorf("expected %v but got %v", c.military, actual)
+			}
+		})
+	}
+}
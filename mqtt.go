@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	// MQTTTimeout bounds how long we wait to connect to the broker or for it to
+	// acknowledge a publish.
+	MQTTTimeout = 10 * time.Second
+	// MQTTQoS is the MQTT quality of service alerts are published with, at
+	// least once.
+	MQTTQoS = 1
+)
+
+// connectMQTT connects to the MQTT broker, if one is configured. The client
+// reconnects by itself if the connection is later lost.
+func (a *App) connectMQTT() error {
+	if a.MQTTBroker == "" {
+		return nil
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(a.MQTTBroker).
+		SetClientID(a.mqttClientID()).
+		SetUsername(a.MQTTUsername).
+		SetPassword(a.MQTTPassword).
+		SetConnectTimeout(MQTTTimeout).
+		SetAutoReconnect(true)
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(MQTTTimeout) {
+		return fmt.Errorf("timed out connecting to MQTT broker %s", a.MQTTBroker)
+	} else if err := token.Error(); err != nil {
+		return fmt.Errorf("could not connect to MQTT broker %s: %w", a.MQTTBroker, err)
+	}
+	a.mqtt = client
+	return nil
+}
+
+// mqttClientID returns the configured MQTT client ID, or otherwise one made
+// from the hostname so that several stations sharing a broker don't keep
+// disconnecting each other.
+func (a *App) mqttClientID() string {
+	if a.MQTTClientID != "" {
+		return a.MQTTClientID
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "overhead"
+	}
+	return "overhead-" + hostname
+}
+
+// publishMQTT publishes the position to the MQTT topic using the same JSON
+// payload as the webhook.
+func (a *App) publishMQTT(pos *Position) {
+	if a.mqtt == nil {
+		return
+	}
+	body, err := json.Marshal(a.newWebhookPayload(pos))
+	if err != nil {
+		slog.Error("could not marshal MQTT payload", "flight_id", pos.FlightID, "error", err)
+		return
+	}
+	token := a.mqtt.Publish(a.MQTTTopic, MQTTQoS, false, body)
+	if !token.WaitTimeout(MQTTTimeout) {
+		slog.Warn("timed out publishing to MQTT", "topic", a.MQTTTopic, "flight_id", pos.FlightID)
+	} else if err := token.Error(); err != nil {
+		slog.Error("could not publish to MQTT", "topic", a.MQTTTopic, "flight_id", pos.FlightID, "error", err)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMQTTClientID(t *testing.T) {
+	app := &App{}
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+	if actual := app.mqttClientID(); actual != "overhead-"+hostname {
+		t.Errorf("expected the default client ID to include the hostname, got %s", actual)
+	}
+
+	app.MQTTClientID = "roof"
+	if actual := app.mqttClientID(); actual != "roof" {
+		t.Errorf("expected the configured client ID, got %s", actual)
+	}
+}
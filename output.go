@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Formats for alerts written to stdout.
+const (
+	// TextOutput is a human-readable line per alert.
+	TextOutput = "text"
+	// JSONLinesOutput is a JSON object per line per alert, for piping into
+	// other programs.
+	JSONLinesOutput = "jsonl"
+)
+
+// Types of the JSON Lines records written to stdout.
+const (
+	// AlertRecord is written for each alert.
+	AlertRecord = "alert"
+	// SessionRecord is the summary of the session written on exit.
+	SessionRecord = "session"
+)
+
+func validateOutputFormat(format string) error {
+	switch format {
+	case TextOutput, JSONLinesOutput:
+		return nil
+	}
+	return fmt.Errorf("unknown output-format %q; must be %s or %s", format, TextOutput, JSONLinesOutput)
+}
+
+// alertLine is the JSON written for each alert in JSONLinesOutput.
+type alertLine struct {
+	// Type distinguishes alerts from any other records in the output.
+	Type string `json:"type"`
+	*Position
+	// Direction names the compass direction of Bearing.
+	Direction string
+}
+
+// writeJSONLine writes the position to w as a single line of JSON.
+func (a *App) writeJSONLine(w io.Writer, pos *Position) error {
+	b, err := json.Marshal(alertLine{Type: AlertRecord, Position: pos, Direction: a.direction(pos.Bearing)})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// printJSONLine writes the position to stdout as a line of JSON.
+func (a *App) printJSONLine(pos *Position) {
+	if err := a.writeJSONLine(os.Stdout, pos); err != nil {
+		slog.Error("could not write alert", "flight_id", pos.FlightID, "error", err)
+	}
+}
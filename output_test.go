@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJSONLine(t *testing.T) {
+	app := &App{CompassPoints: 8}
+	alt := 3500.0
+	var b bytes.Buffer
+	for _, ident := range []string{"UAL641", "DAL12"} {
+		pos := &Position{
+			FlightID:  ident + "-1",
+			Ident:     ident,
+			Altitude:  &alt,
+			Timestamp: time.Unix(1720083075, 0),
+			Distance:  2.4,
+			Bearing:   45,
+		}
+		if err := app.writeJSONLine(&b, pos); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", b.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["type"] != AlertRecord || record["Ident"] != "DAL12" || record["Distance"] != 2.4 || record["Bearing"] != 45.0 || record["Direction"] != "northeast" || record["Altitude"] != 3500.0 {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// A PassSummary records a flight's pass through the area we watch.
+type PassSummary struct {
+	// Event is always "pass", to distinguish summaries from position payloads.
+	Event     string
+	FlightID  string
+	Ident     string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Closest is the position at which the flight was nearest to us.
+	Closest *Position
+}
+
+func newPassSummary(flight *track) PassSummary {
+	return PassSummary{
+		Event:     "pass",
+		FlightID:  flight.last.FlightID,
+		Ident:     flightName(flight.last),
+		FirstSeen: flight.first,
+		LastSeen:  flight.last.Timestamp,
+		Closest:   flight.closest,
+	}
+}
+
+// formatPassSummary formats the summary for logging in our units and time
+// zone, e.g. "N12345 overhead 14:01–14:04, closest 0.8nm to the north".
+func (a *App) formatPassSummary(p PassSummary) string {
+	return fmt.Sprintf("%s overhead %s–%s, closest %s to the %s",
+		p.Ident, a.formatShortTime(p.FirstSeen), a.formatShortTime(p.LastSeen),
+		formatDistance(p.Closest.Distance, a.Units, a.DistancePrecision), cardinalDirection(p.Closest.Bearing))
+}
+
+// passSummaryToWords verbalizes the summary for announcing, e.g. "united 12 34
+// overhead from one four zero one to one four zero four , closest zero point
+// eight nautical miles to the north".
+func (a *App) passSummaryToWords(p PassSummary) []string {
+	words := a.flightNameToWords(&Position{FlightID: p.FlightID, Ident: p.Ident})
+	words = append(words, "overhead from")
+	words = append(words, a.timeOfDayToWords(p.FirstSeen)...)
+	words = append(words, "to")
+	words = append(words, a.timeOfDayToWords(p.LastSeen)...)
+	words = append(words, ",", "closest")
+	words = append(words, a.spokenDistance(p.Closest.Distance)...)
+	return append(words, "to the", a.spokenDirection(p.Closest.Bearing))
+}
+
+// timeOfDayToWords speaks the hour and minute of a time, in UTC if Zulu is set
+// and otherwise in Timezone.
+func (a *App) timeOfDayToWords(t time.Time) []string {
+	if a.Zulu {
+		return append(phonetic(t.UTC().Format("1504"), a.PhoneticStyle), "zulu")
+	}
+	if a.Timezone != nil {
+		t = t.In(a.Timezone)
+	}
+	return phonetic(t.Format("1504"), a.PhoneticStyle)
+}
+
+func (a *App) summarizePass(flight *track) {
+	summary := newPassSummary(flight)
+	slog.Info(a.formatPassSummary(summary), "flight_id", summary.FlightID, "closest_nm", summary.Closest.Distance)
+	if a.canAnnounce(summary.LastSeen) {
+		a.speak(strings.Join(a.passSummaryToWords(summary), " "))
+	}
+
+	if !a.PassSummaryWebhook || a.WebhookURL == "" {
+		return
+	}
+	body, err := json.Marshal(summary)
+	if err != nil {
+		slog.Error("could not marshal pass summary", "flight_id", summary.FlightID, "error", err)
+		return
+	}
+	a.queueWebhook("", body)
+}
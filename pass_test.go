@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPassSummary(t *testing.T) {
+	first := time.Date(2024, 7, 4, 14, 1, 30, 0, time.UTC)
+	flight := &track{
+		first:   first,
+		last:    &Position{FlightID: "N12345-1", Ident: "N12345", Timestamp: first.Add(3 * time.Minute), Distance: 2},
+		closest: &Position{Distance: 0.8, Bearing: 10},
+	}
+	summary := newPassSummary(flight)
+	app := &App{Timezone: time.UTC}
+	exp := "N12345 overhead 14:01–14:04, closest 0.8nm to the north"
+	if actual := app.formatPassSummary(summary); actual != exp {
+		t.Errorf("unexpected summary: %s", actual)
+	}
+	app.Units = MetricUnits
+	exp = "N12345 overhead 14:01–14:04, closest 1.5km to the north"
+	if actual := app.formatPassSummary(summary); actual != exp {
+		t.Errorf("unexpected metric summary: %s", actual)
+	}
+}
+
+func TestPassSummaryName(t *testing.T) {
+	first := time.Date(2024, 7, 4, 14, 1, 30, 0, time.UTC)
+	flight := &track{
+		first:   first,
+		last:    &Position{FlightID: "N12345-1", Reg: "N12345", Timestamp: first.Add(3 * time.Minute)},
+		closest: &Position{Distance: 0.8, Bearing: 10},
+	}
+	app := &App{Timezone: time.UTC}
+	exp := "N12345 overhead 14:01–14:04, closest 0.8nm to the north"
+	if actual := app.formatPassSummary(newPassSummary(flight)); actual != exp {
+		t.Errorf("expected the registration in place of a blank ident, got %s", actual)
+	}
+}
+
+func TestPassSummaryTimes(t *testing.T) {
+	first := time.Date(2024, 7, 4, 14, 1, 30, 0, time.UTC)
+	flight := &track{
+		first:   first,
+		last:    &Position{FlightID: "UAL1234-1", Ident: "UAL1234", Timestamp: first.Add(3 * time.Minute)},
+		closest: &Position{Distance: 0.8, Bearing: 10},
+	}
+	summary := newPassSummary(flight)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("no timezone database: %v", err)
+	}
+	tests := []struct {
+		name   string
+		app    *App
+		exp    string
+		spoken string
+	}{
+		{
+			"zulu", &App{Zulu: true, Timezone: tokyo},
+			"UAL1234 overhead 14:01Z–14:04Z, closest 0.8nm to the north",
+			"united 12 34 overhead from one four zero one zulu to one four zero four zulu , closest zero point eight nautical miles to the north",
+		},
+		{
+			"timezone", &App{Timezone: tokyo},
+			"UAL1234 overhead 23:01–23:04, closest 0.8nm to the north",
+			"united 12 34 overhead from two three zero one to two three zero four , closest zero point eight nautical miles to the north",
+		},
+		{
+			"timestamp format", &App{Timezone: tokyo, TimestampFormat: "3:04 PM"},
+			"UAL1234 overhead 11:01 PM–11:04 PM, closest 0.8nm to the north",
+			"united 12 34 overhead from two three zero one to two three zero four , closest zero point eight nautical miles to the north",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := test.app.formatPassSummary(summary); actual != test.exp {
+				t.Errorf("unexpected summary: %s", actual)
+			}
+			if actual := strings.Join(test.app.passSummaryToWords(summary), " "); actual != test.spoken {
+				t.Errorf("unexpected announcement: %s", actual)
+			}
+		})
+	}
+}
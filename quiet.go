@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuietHours is a daily window during which announcements are not spoken.
+// Whether a flight falls in the window is decided by its position's
+// Timestamp rather than the wall clock, so that the outcome doesn't depend on
+// how long a position took to reach us and so that it is deterministic in
+// tests.
+type QuietHours struct {
+	// Start and End are offsets from midnight. If End is before Start, the
+	// window crosses midnight.
+	Start    time.Duration
+	End      time.Duration
+	Location *time.Location
+}
+
+// parseQuietHours parses start and end times of the form 22:00 in the named
+// time zone, or local time if the zone is empty.
+func parseQuietHours(start, end, zone string) (*QuietHours, error) {
+	q := &QuietHours{Location: time.Local}
+	var err error
+	if q.Start, err = parseTimeOfDay(start); err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	if q.End, err = parseTimeOfDay(end); err != nil {
+		return nil, fmt.Errorf("end: %w", err)
+	}
+	if q.Start == q.End {
+		return nil, fmt.Errorf("start and end must differ")
+	}
+	if zone != "" {
+		if q.Location, err = time.LoadLocation(zone); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time like 22:00", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether t falls within the quiet hours. The start is
+// inclusive and the end exclusive. A nil QuietHours contains no times.
+func (q *QuietHours) Contains(t time.Time) bool {
+	if q == nil {
+		return false
+	}
+	t = t.In(q.Location)
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if q.Start < q.End {
+		return offset >= q.Start && offset < q.End
+	}
+	return offset >= q.Start || offset < q.End
+}
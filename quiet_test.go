@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHours(t *testing.T) {
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		at    string
+		exp   bool
+	}{
+		{"overnight late", "22:00", "07:00", "23:30", true},
+		{"overnight early", "22:00", "07:00", "03:00", true},
+		{"overnight start", "22:00", "07:00", "22:00", true},
+		{"overnight end", "22:00", "07:00", "07:00", false},
+		{"overnight daytime", "22:00", "07:00", "12:00", false},
+		{"daytime inside", "12:00", "14:00", "13:15", true},
+		{"daytime before", "12:00", "14:00", "11:59", false},
+		{"daytime after", "12:00", "14:00", "14:01", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := parseQuietHours(test.start, test.end, "America/New_York")
+			if err != nil {
+				t.Fatal(err)
+			}
+			at, err := time.ParseInLocation("2006-01-02 15:04", "2024-07-04 "+test.at, q.Location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := q.Contains(at); actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+			// The time zone matters, not the zone of the timestamp.
+			if actual := q.Contains(at.UTC()); actual != test.exp {
+				t.Errorf("expected %t in UTC, got %t", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestParseQuietHours(t *testing.T) {
+	for _, bad := range [][3]string{
+		{"10pm", "07:00", ""},
+		{"22:00", "", ""},
+		{"22:00", "22:00", ""},
+		{"22:00", "07:00", "Mars/Olympus_Mons"},
+	} {
+		if _, err := parseQuietHours(bad[0], bad[1], bad[2]); err == nil {
+			t.Errorf("expected %v to be invalid", bad)
+		}
+	}
+}
+
+func TestCanAnnounce(t *testing.T) {
+	q, err := parseQuietHours("22:00", "07:00", "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &App{Announce: true, QuietHours: q}
+	if app.canAnnounce(time.Date(2024, 7, 4, 3, 0, 0, 0, time.UTC)) {
+		t.Error("expected announcements to be suppressed during quiet hours")
+	}
+	if !app.canAnnounce(time.Date(2024, 7, 4, 12, 0, 0, 0, time.UTC)) {
+		t.Error("expected announcements outside quiet hours")
+	}
+	app.DryRun = true
+	if app.canAnnounce(time.Date(2024, 7, 4, 12, 0, 0, 0, time.UTC)) {
+		t.Error("expected announcements to be suppressed in a dry run")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import "strings"
+
+// A registrationPrefix identifies the country an aircraft is registered in.
+type registrationPrefix struct {
+	Country string
+	// Airports are the ICAO location indicator prefixes of the country's
+	// airports, so that domestic flights need not be pointed out.
+	Airports []string
+}
+
+// registrationPrefixes maps the nationality marks that begin registrations to
+// the countries they belong to.
+var registrationPrefixes = map[string]registrationPrefix{
+	"4X": {"Israel", []string{"LL"}},
+	"9M": {"Malaysia", []string{"WM", "WB"}},
+	"9V": {"Singapore", []string{"WS"}},
+	"A6": {"United Arab Emirates", []string{"OM"}},
+	"A7": {"Qatar", []string{"OT"}},
+	"B":  {"China", []string{"Z"}},
+	"C":  {"Canada", []string{"C"}},
+	"CC": {"Chile", []string{"SC"}},
+	"CS": {"Portugal", []string{"LP"}},
+	"D":  {"Germany", []string{"ED", "ET"}},
+	"EC": {"Spain", []string{"LE", "GC"}},
+	"EI": {"Ireland", []string{"EI"}},
+	"F":  {"France", []string{"LF"}},
+	"G":  {"United Kingdom", []string{"EG"}},
+	"HB": {"Switzerland", []string{"LS"}},
+	"HK": {"Colombia", []string{"SK"}},
+	"HL": {"South Korea", []string{"RK"}},
+	"HS": {"Thailand", []string{"VT"}},
+	"HZ": {"Saudi Arabia", []string{"OE"}},
+	"I":  {"Italy", []string{"LI"}},
+	"JA": {"Japan", []string{"RJ", "RO"}},
+	"LN": {"Norway", []string{"EN"}},
+	"LV": {"Argentina", []string{"SA"}},
+	"N":  {"United States", []string{"K", "PA", "PH"}},
+	"OB": {"Peru", []string{"SP"}},
+	"OE": {"Austria", []string{"LO"}},
+	"OH": {"Finland", []string{"EF"}},
+	"OK": {"Czech Republic", []string{"LK"}},
+	"OO": {"Belgium", []string{"EB"}},
+	"OY": {"Denmark", []string{"EK"}},
+	"PH": {"Netherlands", []string{"EH"}},
+	"PP": {"Brazil", []string{"SB", "SD", "SN", "SW"}},
+	"PR": {"Brazil", []string{"SB", "SD", "SN", "SW"}},
+	"PS": {"Brazil", []string{"SB", "SD", "SN", "SW"}},
+	"PT": {"Brazil", []string{"SB", "SD", "SN", "SW"}},
+	"RA": {"Russia", []string{"U"}},
+	"SE": {"Sweden", []string{"ES"}},
+	"SP": {"Poland", []string{"EP"}},
+	"SU": {"Egypt", []string{"HE"}},
+	"SX": {"Greece", []string{"LG"}},
+	"TC": {"Turkey", []string{"LT"}},
+	"TF": {"Iceland", []string{"BI"}},
+	"UR": {"Ukraine", []string{"UK"}},
+	"VH": {"Australia", []string{"Y"}},
+	"VT": {"India", []string{"VA", "VE", "VI", "VO"}},
+	"XA": {"Mexico", []string{"MM"}},
+	"XB": {"Mexico", []string{"MM"}},
+	"XC": {"Mexico", []string{"MM"}},
+	"ZK": {"New Zealand", []string{"NZ"}},
+	"ZS": {"South Africa", []string{"FA"}},
+}
+
+// lookupRegistration finds the nationality mark of a registration. A
+// dash-separated registration like D-AIMA is looked up by the part before the
+// dash; a run-on one like N12345 or JA743J by its longest known prefix.
+func lookupRegistration(reg string) (registrationPrefix, bool) {
+	reg = strings.ToUpper(strings.TrimSpace(reg))
+	if mark, _, ok := strings.Cut(reg, "-"); ok {
+		prefix, ok := registrationPrefixes[mark]
+		return prefix, ok
+	}
+	for n := min(2, len(reg)); n > 0; n-- {
+		if prefix, ok := registrationPrefixes[reg[:n]]; ok {
+			return prefix, true
+		}
+	}
+	return registrationPrefix{}, false
+}
+
+// registrationCountry returns the country the position's aircraft is
+// registered in, unless that is obvious because the flight is going to or from
+// one of its airports.
+func registrationCountry(pos *Position) (string, bool) {
+	prefix, ok := lookupRegistration(pos.Reg)
+	if !ok {
+		return "", false
+	}
+	for _, airport := range []string{pos.Origin, pos.Destination} {
+		for _, p := range prefix.Airports {
+			if strings.HasPrefix(strings.ToUpper(airport), p) {
+				return "", false
+			}
+		}
+	}
+	return prefix.Country, true
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestLookupRegistration(t *testing.T) {
+	tests := []struct {
+		reg string
+		exp string
+	}{
+		{"N12345", "United States"},
+		{"G-EUPT", "United Kingdom"},
+		{"GEUPT", "United Kingdom"},
+		{"D-AIMA", "Germany"},
+		{"C-FJZS", "Canada"},
+		{"CFJZS", "Canada"},
+		{"CC-BGA", "Chile"},
+		{"VH-OQA", "Australia"},
+		{"JA743J", "Japan"},
+		{"ja743j", "Japan"},
+		{"9V-SKA", "Singapore"},
+		{"PH-BVA", "Netherlands"},
+	}
+	for _, test := range tests {
+		t.Run(test.reg, func(t *testing.T) {
+			prefix, ok := lookupRegistration(test.reg)
+			if !ok {
+				t.Fatal("expected a country")
+			}
+			if prefix.Country != test.exp {
+				t.Errorf("expected %s, got %s", test.exp, prefix.Country)
+			}
+		})
+	}
+
+	for _, reg := range []string{"", "QQ-ABC", "1234"} {
+		if prefix, ok := lookupRegistration(reg); ok {
+			t.Errorf("%q: expected no country, got %s", reg, prefix.Country)
+		}
+	}
+}
+
+func TestRegistrationCountry(t *testing.T) {
+	tests := []struct {
+		name string
+		pos  Position
+		exp  string
+	}{
+		{"foreign", Position{Reg: "D-AIMA", Origin: "KJFK", Destination: "KBOS"}, "Germany"},
+		{"from home", Position{Reg: "D-AIMA", Origin: "EDDM", Destination: "KBOS"}, ""},
+		{"to home", Position{Reg: "G-EUPT", Origin: "KBOS", Destination: "EGLL"}, ""},
+		{"domestic", Position{Reg: "N12345", Origin: "KBOS"}, ""},
+		{"no route", Position{Reg: "N12345"}, "United States"},
+		{"no registration", Position{Origin: "KBOS"}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual, _ := registrationCountry(&test.pos); actual != test.exp {
+				t.Errorf("expected %q, got %q", test.exp, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/benburwell/firehose"
+)
+
+// replay feeds position messages from ReplayFile through the same pipeline as
+// the live stream, returning once the whole file has been read. The file holds
+// one JSON position message per line, as Firehose sends them. If ReplayRealtime
+// is set, we sleep between messages according to their clocks to reproduce the
+// original timing.
+func (a *App) replay(ctx context.Context) error {
+	f, err := os.Open(a.ReplayFile)
+	if err != nil {
+		return fmt.Errorf("could not open replay file: %w", err)
+	}
+	defer f.Close()
+	a.setConnected(true)
+	defer a.setConnected(false)
+
+	var prevClock int64
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		a.markReceived()
+		var msg firehose.PositionMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			slog.Warn("skipping malformed replay line", "file", a.ReplayFile, "line", n, "error", err)
+			continue
+		}
+		if msg.Type != "" && msg.Type != "position" {
+			continue
+		}
+		if a.ReplayRealtime {
+			clock, err := strconv.ParseInt(msg.Clock, 10, 64)
+			if err == nil {
+				if prevClock != 0 && clock > prevClock {
+					if err := sleep(ctx, time.Duration(clock-prevClock)*time.Second); err != nil {
+						return err
+					}
+				}
+				prevClock = clock
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		a.handlePosition(&msg)
+		a.cleanupStaleFlights()
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read replay file: %w", err)
+	}
+	return nil
+}
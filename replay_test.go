@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeReplayFile(t *testing.T, lines ...string) string {
+	path := filepath.Join(t.TempDir(), "replay.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReplay(t *testing.T) {
+	var alerted []string
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		AlertRadiusNM:        3,
+		AlertOnce:            true,
+		OnAlert: func(pos Position) {
+			alerted = append(alerted, pos.FlightID)
+		},
+		SummaryOutput: io.Discard,
+	}
+	home := app.myLocation()
+	var lines []string
+	for i, dist := range []float64{6, 4, 2, 1} {
+		b, err := json.Marshal(testPosition("A", moveNM(home, 0, dist), 1000+int64(i)*10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(b))
+		if i == 1 {
+			lines = append(lines, "", "not json", `{"type":"keepalive"}`)
+		}
+	}
+	app.ReplayFile = writeReplayFile(t, lines...)
+
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(alerted) != 1 || alerted[0] != "A" {
+		t.Errorf("expected a single alert for A, got %v", alerted)
+	}
+}
+
+func TestReplayRealtimeCanceled(t *testing.T) {
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		ReplayRealtime:       true,
+		SummaryOutput:        io.Discard,
+	}
+	home := app.myLocation()
+	first, _ := json.Marshal(testPosition("A", moveNM(home, 0, 6), 1000))
+	second, _ := json.Marshal(testPosition("A", moveNM(home, 0, 5), 4600))
+	app.ReplayFile = writeReplayFile(t, string(first), string(second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if err := app.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected replay to stop when canceled, took %s", elapsed)
+	}
+}
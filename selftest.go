@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// selfTestPhrase is spoken by the self test. It includes a phonetic tail
+// number to check that announcements will be intelligible.
+func (a *App) selfTestPhrase() string {
+	return strings.Join(append([]string{"overhead self test", ","}, phonetic("N359AB", a.PhoneticStyle)...), " ")
+}
+
+// selfTest speaks a canned phrase, so that text-to-speech can be checked
+// before connecting to Firehose. Announce and quiet hours are ignored.
+func (a *App) selfTest() error {
+	if err := a.runSpeech(a.selfTestPhrase()); err != nil {
+		return fmt.Errorf("could not speak with %s: %w", a.TTSCommand, err)
+	}
+	return nil
+}
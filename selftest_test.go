@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSelfTest(t *testing.T) {
+	app := &App{TTSCommand: "true", TTSTimeout: time.Second, TTSRate: DefaultTTSRate}
+	if err := app.selfTest(); err != nil {
+		t.Errorf("expected the self test to pass, got %v", err)
+	}
+
+	app.TTSCommand = "overhead-missing-tts"
+	if err := app.selfTest(); !errors.Is(err, errNoTTSCommand) {
+		t.Errorf("expected a missing command error, got %v", err)
+	}
+
+	app.TTSCommand = "false"
+	if err := app.selfTest(); err == nil {
+		t.Error("expected a failing command to fail the self test")
+	}
+}
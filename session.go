@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// A session tallies what we saw while running, for the summary on exit. It is
+// guarded by the App's mu.
+type session struct {
+	started   time.Time
+	positions int
+	flights   map[string]bool
+	alerts    int
+	closest   *Position
+}
+
+// A SessionSummary reports what was seen between starting and stopping.
+type SessionSummary struct {
+	// Type is always SessionRecord, to distinguish summaries from alerts.
+	Type    string `json:"type"`
+	Started time.Time
+	Ended   time.Time
+	// Positions counts every position received, interesting or not, and
+	// Flights the distinct interesting flights.
+	Positions int
+	Flights   int
+	Alerts    int
+	// Closest is the nearest any interesting flight came to us, if any.
+	Closest *Position `json:",omitempty"`
+}
+
+// formatSessionSummary formats the summary for the terminal in our units, e.g.
+//
+//	Seen 1520 positions of 12 flights, with 1 alert, in 1h2m0s
+//	Closest approach: N12345 at 0.4nm to the north
+func (a *App) formatSessionSummary(s SessionSummary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Seen %s of %s, with %s, in %s",
+		plural(s.Positions, "position"), plural(s.Flights, "flight"), plural(s.Alerts, "alert"),
+		s.Ended.Sub(s.Started).Round(time.Second))
+	if s.Closest != nil {
+		fmt.Fprintf(&b, "\nClosest approach: %s at %s to the %s",
+			flightName(s.Closest), formatDistance(s.Closest.Distance, a.Units, a.DistancePrecision),
+			cardinalDirection(s.Closest.Bearing))
+	}
+	return b.String()
+}
+
+// plural formats a count of things, e.g. "1 flight" or "2 flights".
+func plural(n int, thing string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, thing)
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
+
+// countPosition tallies a position in the session, which the caller must have
+// locked. Only interesting positions count towards flights and the closest
+// approach.
+func (s *session) countPosition(pos *Position, interesting bool) {
+	s.positions++
+	if !interesting {
+		return
+	}
+	if s.flights == nil {
+		s.flights = make(map[string]bool)
+	}
+	s.flights[pos.FlightID] = true
+	if s.closest == nil || pos.Distance < s.closest.Distance {
+		s.closest = pos
+	}
+}
+
+// sessionSummary summarizes the session up to now.
+func (a *App) sessionSummary() SessionSummary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return SessionSummary{
+		Type:      SessionRecord,
+		Started:   a.session.started,
+		Ended:     a.wallClock(),
+		Positions: a.session.positions,
+		Flights:   len(a.session.flights),
+		Alerts:    a.session.alerts,
+		Closest:   a.session.closest,
+	}
+}
+
+// printSessionSummary prints the summary of the session to SummaryOutput in
+// the output format, and writes it to SummaryFile as JSON if set.
+func (a *App) printSessionSummary() {
+	summary := a.sessionSummary()
+	w := a.SummaryOutput
+	if w == nil {
+		w = os.Stdout
+	}
+	if a.OutputFormat == JSONLinesOutput {
+		b, err := json.Marshal(summary)
+		if err != nil {
+			slog.Error("could not marshal session summary", "error", err)
+		} else {
+			fmt.Fprintln(w, string(b))
+		}
+	} else {
+		fmt.Fprintln(w, a.formatSessionSummary(summary))
+	}
+	if a.SummaryFile == "" {
+		return
+	}
+	if err := writeSessionSummary(a.SummaryFile, summary); err != nil {
+		slog.Error("could not write session summary", "path", a.SummaryFile, "error", err)
+	}
+}
+
+func writeSessionSummary(path string, summary SessionSummary) error {
+	b, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
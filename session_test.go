@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionSummary(t *testing.T) {
+	start := time.Date(2024, 7, 4, 12, 0, 0, 0, time.UTC)
+	now := start
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		AlertRadiusNM:        3,
+		AlertOnce:            true,
+		DryRun:               true,
+		clock:                func() time.Time { return now },
+	}
+	app.session.started = start
+	home := app.myLocation()
+	app.handlePosition(testPosition("A", moveNM(home, 0, 5), 1000))
+	app.handlePosition(testPosition("A", moveNM(home, 0, 2), 1010))
+	app.handlePosition(testPosition("A", moveNM(home, 0, 0.5), 1020))
+	app.handlePosition(testPosition("B", moveNM(home, 90, 8), 1030))
+	app.handlePosition(testPosition("C", moveNM(home, 180, 20), 1040))
+	now = start.Add(90 * time.Minute)
+
+	summary := app.sessionSummary()
+	if summary.Positions != 5 || summary.Flights != 2 || summary.Alerts != 1 {
+		t.Errorf("unexpected counts: %+v", summary)
+	}
+	if summary.Closest == nil || summary.Closest.FlightID != "A" {
+		t.Fatalf("expected A to be closest, got %+v", summary.Closest)
+	}
+	exp := "Seen 5 positions of 2 flights, with 1 alert, in 1h30m0s\nClosest approach: A at 0.5nm to the north"
+	if actual := app.formatSessionSummary(summary); actual != exp {
+		t.Errorf("unexpected summary:\n%s", actual)
+	}
+	app.Units = MetricUnits
+	exp = "Seen 5 positions of 2 flights, with 1 alert, in 1h30m0s\nClosest approach: A at 0.9km to the north"
+	if actual := app.formatSessionSummary(summary); actual != exp {
+		t.Errorf("unexpected metric summary:\n%s", actual)
+	}
+
+	path := filepath.Join(t.TempDir(), "summary.json")
+	if err := writeSessionSummary(path, summary); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written SessionSummary
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"type": "session"`) {
+		t.Errorf("expected the record type in the summary file: %s", data)
+	}
+	if written.Type != SessionRecord || written.Flights != 2 || written.Closest.FlightID != "A" {
+		t.Errorf("unexpected summary file: %s", data)
+	}
+}
+
+func TestEmptySessionSummary(t *testing.T) {
+	now := time.Date(2024, 7, 4, 12, 0, 0, 0, time.UTC)
+	app := &App{clock: func() time.Time { return now }}
+	app.session.started = now
+	exp := "Seen 0 positions of 0 flights, with 0 alerts, in 0s"
+	if actual := app.formatSessionSummary(app.sessionSummary()); actual != exp {
+		t.Errorf("unexpected summary: %s", actual)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// slackMessage is a message for a Slack incoming webhook.
+// https://api.slack.com/messaging/webhooks
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// slackMessage describes the position in Slack's mrkdwn, with the ident
+// linking to the flight on FlightAware.
+func (a *App) slackMessage(pos *Position) slackMessage {
+	var text strings.Builder
+	fmt.Fprintf(&text, "<%s|%s>", flightAwareLink(pos.FlightID), slackEscape(flightName(pos)))
+	if pos.AircraftType != "" {
+		fmt.Fprintf(&text, " (%s)", slackEscape(a.aircraftTypeName(pos.AircraftType)))
+	}
+	fmt.Fprintf(&text, " is %s to the %s", formatDistance(pos.Distance, a.Units, a.DistancePrecision), a.direction(pos.Bearing))
+	if pos.Altitude != nil {
+		fmt.Fprintf(&text, " at %s", formatAltitude(*pos.Altitude, a.Units))
+	}
+	if pos.Departed {
+		text.WriteString(", departing the area")
+	}
+	return slackMessage{Text: text.String()}
+}
+
+// slackEscape escapes the characters Slack treats as control characters.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// postSlack sends the position to the Slack webhook, if one is configured.
+func (a *App) postSlack(pos *Position) {
+	if a.SlackWebhookURL == "" {
+		return
+	}
+	a.queueMessage("Slack", a.SlackWebhookURL, pos.FlightID, a.slackMessage(pos))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPostSlack(t *testing.T) {
+	received := make(chan slackMessage, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var msg slackMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Errorf("invalid message %q: %v", body, err)
+		}
+		received <- msg
+	}))
+	defer srv.Close()
+
+	alt := 3500.0
+	app := &App{SlackWebhookURL: srv.URL, CompassPoints: 8}
+	app.startWebhooks()
+	defer app.stopWebhooks(time.Second)
+	app.postSlack(&Position{
+		FlightID:     "UAL641-1720083075-fa-2029p",
+		Ident:        "UAL641",
+		AircraftType: "B738",
+		Distance:     2.4,
+		Bearing:      45,
+		Altitude:     &alt,
+	})
+
+	expected := "<https://www.flightaware.com/live/flight/id/UAL641-1720083075-fa-2029p|UAL641> (Boeing 737-800) is 2.4nm to the northeast at 3500ft"
+	if msg := <-received; msg.Text != expected {
+		t.Errorf("unexpected text: %s", msg.Text)
+	}
+}
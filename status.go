@@ -0,0 +1,25 @@
+package main
+
+import (
+	"log/slog"
+
+	"overhead/internal/statusfile"
+)
+
+// updateStatus writes the position to the status file, if one is configured,
+// or an empty object if pos is nil. The caller must hold a.mu, so that alerts
+// and flights being forgotten update it in order.
+func (a *App) updateStatus(pos *Position) {
+	if a.StatusFile == "" {
+		return
+	}
+	var status any = struct{}{}
+	a.statusFlightID = ""
+	if pos != nil {
+		status = pos
+		a.statusFlightID = pos.FlightID
+	}
+	if err := statusfile.Write(a.StatusFile, status); err != nil {
+		slog.Error("could not write status file", "path", a.StatusFile, "error", err)
+	}
+}
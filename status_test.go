@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestStatusFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "status.json")
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		AlertRadiusNM:        3,
+		StatusFile:           path,
+	}
+	read := func() map[string]any {
+		t.Helper()
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var status map[string]any
+		if err := json.Unmarshal(b, &status); err != nil {
+			t.Fatalf("invalid status %q: %v", b, err)
+		}
+		return status
+	}
+
+	home := app.myLocation()
+	app.handlePosition(testPosition("A", moveNM(home, 0, 2.5), 1000))
+	app.handlePosition(testPosition("A", moveNM(home, 0, 2.0), 1010))
+	if status := read(); status["FlightID"] != "A" {
+		t.Errorf("expected flight A in status, got %v", status)
+	}
+
+	// Once the flight is forgotten, the status is cleared.
+	app.handlePosition(testPosition("B", moveNM(home, 180, 8), 1010+int64(CleanupAfter.Seconds())+1))
+	app.cleanupStaleFlights()
+	if status := read(); len(status) != 0 {
+		t.Errorf("expected an empty status, got %v", status)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the status file to remain, got %v", entries)
+	}
+}
+
+// TestStatusFileConcurrentCleanup alerts while flights are being forgotten by
+// the cleanup. Run it with -race.
+func TestStatusFileConcurrentCleanup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status.json")
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		AlertRadiusNM:        3,
+		StatusFile:           path,
+		OnAlert:              func(Position) {},
+	}
+	home := app.myLocation()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				app.cleanupStaleFlights()
+			}
+		}
+	}()
+	// Each flight alerts on its second position, and is stale by the time
+	// the next flight appears.
+	clock := int64(1000)
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("F%d", i)
+		app.handlePosition(testPosition(id, moveNM(home, 0, 2.5), clock))
+		app.handlePosition(testPosition(id, moveNM(home, 0, 2.0), clock+10))
+		clock += int64(CleanupAfter.Seconds()) + 20
+	}
+	close(done)
+	wg.Wait()
+	app.drain(ShutdownTimeout)
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status map[string]any
+	if err := json.Unmarshal(b, &status); err != nil {
+		t.Fatalf("invalid status %q: %v", b, err)
+	}
+	if status["FlightID"] != "F49" {
+		t.Errorf("expected the last flight in the status, got %v", status)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// StreamClientBuffer is how many positions may be waiting to be sent to a
+	// stream client before it is considered too slow and disconnected.
+	StreamClientBuffer = 64
+	// StreamWriteTimeout bounds each write to a stream client.
+	StreamWriteTimeout = 10 * time.Second
+)
+
+// A streamHub broadcasts positions to the clients of the WebSocket stream.
+type streamHub struct {
+	mu      sync.Mutex
+	clients map[*streamClient]bool
+	closed  bool
+}
+
+// A streamClient receives broadcast messages on send, which is closed when the
+// client is unsubscribed.
+type streamClient struct {
+	send chan []byte
+}
+
+func newStreamHub() *streamHub {
+	return &streamHub{clients: make(map[*streamClient]bool)}
+}
+
+// subscribe adds a client to the hub. It returns nil if the hub is closed.
+func (h *streamHub) subscribe() *streamClient {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	c := &streamClient{send: make(chan []byte, StreamClientBuffer)}
+	h.clients[c] = true
+	return c
+}
+
+// unsubscribe removes a client from the hub, if it is still subscribed.
+func (h *streamHub) unsubscribe(c *streamClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(c)
+}
+
+func (h *streamHub) remove(c *streamClient) {
+	if h.clients[c] {
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
+
+// broadcast sends the message to every client without blocking. Clients which
+// have fallen too far behind are dropped.
+func (h *streamHub) broadcast(msg []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		select {
+		case c.send <- msg:
+		default:
+			slog.Warn("stream client is too slow; disconnecting")
+			h.remove(c)
+		}
+	}
+}
+
+// close unsubscribes every client and stops any more from subscribing.
+func (h *streamHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for c := range h.clients {
+		h.remove(c)
+	}
+}
+
+// publishPosition sends the position to stream clients, if the stream is
+// enabled.
+func (a *App) publishPosition(pos *Position) {
+	if a.stream == nil {
+		return
+	}
+	msg, err := json.Marshal(pos)
+	if err != nil {
+		slog.Error("could not marshal stream position", "flight_id", pos.FlightID, "error", err)
+		return
+	}
+	a.stream.broadcast(msg)
+}
+
+var streamUpgrader = websocket.Upgrader{
+	// The stream is read-only public data, so any page may use it.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// handleStream upgrades the request to a WebSocket and sends it each
+// interesting position as JSON until the client disconnects or the context is
+// canceled.
+func (a *App) handleStream(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := streamUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			slog.Warn("could not upgrade stream connection", "error", err)
+			return
+		}
+		defer conn.Close()
+
+		client := a.stream.subscribe()
+		if client == nil {
+			return
+		}
+		defer a.stream.unsubscribe(client)
+
+		// We don't expect anything from the client, but have to read to notice
+		// when it goes away.
+		gone := make(chan struct{})
+		go func() {
+			defer close(gone)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-gone:
+				return
+			case msg, ok := <-client.send:
+				if !ok {
+					return
+				}
+				conn.SetWriteDeadline(time.Now().Add(StreamWriteTimeout))
+				if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
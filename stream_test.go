@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStreamHub(t *testing.T) {
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		stream:               newStreamHub(),
+	}
+	fast := app.stream.subscribe()
+	slow := app.stream.subscribe()
+
+	home := app.myLocation()
+	app.handlePosition(testPosition("A", moveNM(home, 90, 2), 1000))
+	// Positions which aren't interesting aren't streamed.
+	app.handlePosition(testPosition("B", moveNM(home, 90, 20), 1000))
+
+	var pos Position
+	if err := json.Unmarshal(<-fast.send, &pos); err != nil {
+		t.Fatal(err)
+	}
+	if pos.FlightID != "A" || pos.Distance < 1.99 || pos.Distance > 2.01 || pos.Bearing < 89 || pos.Bearing > 91 {
+		t.Errorf("unexpected position: %+v", pos)
+	}
+	if len(fast.send) != 0 {
+		t.Errorf("expected only the interesting position to be streamed")
+	}
+
+	// Fill up the slow client, which is dropped rather than blocking.
+	for i := 0; i < StreamClientBuffer; i++ {
+		app.publishPosition(&pos)
+		<-fast.send
+	}
+	if _, ok := app.stream.clients[slow]; ok {
+		t.Error("expected the slow client to be dropped")
+	}
+	if _, ok := app.stream.clients[fast]; !ok {
+		t.Error("expected the fast client to still be subscribed")
+	}
+
+	app.stream.close()
+	if _, ok := <-fast.send; ok {
+		t.Error("expected closing the hub to close client channels")
+	}
+	if app.stream.subscribe() != nil {
+		t.Error("expected no subscriptions after closing")
+	}
+}
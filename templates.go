@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"overhead/internal/unit"
+)
+
+// Sinks which can have their alert format overridden with a template.
+const (
+	TerminalSink = "terminal"
+	WebhookSink  = "webhook"
+	SpeechSink   = "speech"
+)
+
+// Templates holds the compiled alert template for each sink.
+type Templates map[string]*template.Template
+
+// DefaultTemplates are the templates for sinks which don't have one
+// configured, giving the built-in alert formats. They are a starting point for
+// writing your own, and can be listed with --list-templates.
+var DefaultTemplates = map[string]string{
+	TerminalSink: `[{{.Time}}] {{paintIdent .Position}}
+{{- with .AircraftType}} ({{typeName .}}){{end}}
+{{- with country .Position}} ({{.}}){{end}} from {{airport .Origin}}
+{{- with .Destination}} to {{airport .}}{{end}} is {{paintDistance .Distance}} to the {{direction .Bearing}}
+{{- with bearings .Bearing}} ({{.}}){{end}}
+{{- with .Altitude}} {{displayAltitude (deref .)}}{{end}}
+{{- with .VerticalRate}} ({{formatVerticalRate (deref .)}}){{end}}
+{{- with .Speed}} {{with $.Heading}}{{cardinal (deref .)}}bound{{else}}travelling{{end}} at {{formatSpeed (deref .)}}{{end}}
+{{- if .Departed}}, departing the area{{else}}{{with approach .Position}}, {{.}}{{end}}{{end}}
+{{- with closestApproach .Position}}
+           {{.}}{{end}}
+           {{dim .Link}}`,
+	WebhookSink: `{{json (payload .Position)}}`,
+	SpeechSink: `{{with spokenTime .Timestamp}}{{.}} zulu , {{end}}{{spokenName .Position}}
+{{- with spokenType .AircraftType}} , {{.}} ,{{end}} is {{spokenDistance .Distance}} to the {{spokenDirection .Bearing}} ,
+{{- with spokenBearings .Bearing}} {{.}} ,{{end}}
+{{- with .Altitude}} {{spokenAltitude (deref .)}} ,{{end}}
+{{- with .VerticalRate}} {{vertical (deref .)}} ,{{end}}
+{{- with .Heading}} {{cardinal (deref .)}} bound ,{{end}}
+{{- with .Speed}} {{spokenSpeed (deref .)}}{{end}}
+{{- if .Departed}} , departing the area{{else}}{{with spokenETA .Position}} , overhead in about {{.}}{{end}}{{end}}`,
+}
+
+// templateFuncs returns the functions available to every alert template. Those
+// which depend on settings, such as showing both bearings, follow the App's.
+func (a *App) templateFuncs() template.FuncMap {
+	words := func(w []string) string { return strings.Join(w, " ") }
+	return template.FuncMap{
+		"cardinal": cardinalDirection,
+		"phonetic": func(s string) string { return words(phonetic(s, a.PhoneticStyle)) },
+		"callsign": func(ident string) string { return words(a.identToWords(ident)) },
+		"altitude": func(alt float64) string {
+			return words(altitudeToWords(alt, a.TransitionAltitudeFt, a.PhoneticStyle))
+		},
+		"deref": func(v *float64) float64 {
+			if v == nil {
+				return 0
+			}
+			return *v
+		},
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"name":     func(p Position) string { return flightName(&p) },
+		"payload":  func(p Position) WebhookPayload { return a.newWebhookPayload(&p) },
+		"typeName": a.aircraftTypeName,
+		"airport":  a.airportName,
+		"country": func(p Position) string {
+			country, _ := registrationCountry(&p)
+			return country
+		},
+		"direction": a.direction,
+		"bearings": func(bearing float64) string {
+			if !a.ShowBothBearings {
+				return ""
+			}
+			mag := magneticBearing(bearing, a.MagneticDeclination)
+			return fmt.Sprintf("%s°T/%s°M", unit.FormatBearing(bearing), unit.FormatBearing(mag))
+		},
+		"formatDistance":  func(nm float64) string { return formatDistance(nm, a.Units, a.DistancePrecision) },
+		"formatAltitude":  func(ft float64) string { return formatAltitude(ft, a.Units) },
+		"displayAltitude": a.displayAltitude,
+		"formatSpeed":     func(kts float64) string { return formatSpeed(kts, a.Units) },
+		"formatVerticalRate": func(fpm float64) string {
+			state := verticalState(fpm)
+			if state != Level {
+				state += " " + formatVerticalRate(math.Abs(fpm), a.Units)
+			}
+			return state
+		},
+		"vertical": verticalState,
+		"approach": func(p Position) string {
+			state, _ := approachState(&p)
+			return state
+		},
+		"closestApproach": func(p Position) string {
+			approach, _ := a.formatClosestApproach(&p)
+			return approach
+		},
+		"paintIdent": func(p Position) string { return a.paint(flightName(&p), identStyle(&p)) },
+		"paintDistance": func(nm float64) string {
+			return a.paint(formatDistance(nm, a.Units, a.DistancePrecision), distanceStyle(nm))
+		},
+		"dim": func(s string) string { return a.paint(s, ansiDim) },
+
+		// For speech.
+		"spokenTime": func(t time.Time) string {
+			if !a.Zulu {
+				return ""
+			}
+			return words(phonetic(t.UTC().Format("1504"), a.PhoneticStyle))
+		},
+		"spokenName":     func(p Position) string { return words(a.flightNameToWords(&p)) },
+		"spokenAltitude": func(alt float64) string { return words(a.spokenAltitude(alt)) },
+		"spokenSpeed":    func(kts float64) string { return words(a.spokenSpeed(kts)) },
+		"spokenDistance": func(nm float64) string { return words(a.spokenDistance(nm)) },
+		"spokenType": func(aircraftType string) string {
+			if !a.AnnounceTypeNames || aircraftType == "" {
+				return ""
+			}
+			return a.aircraftTypeName(aircraftType)
+		},
+		"spokenDirection": a.spokenDirection,
+		"spokenBearings": func(bearing float64) string {
+			if !a.ShowBothBearings {
+				return ""
+			}
+			mag := magneticBearing(bearing, a.MagneticDeclination)
+			w := append([]string{"bearing"}, phonetic(unit.FormatBearing(bearing), a.PhoneticStyle)...)
+			w = append(w, "true", ",")
+			w = append(w, phonetic(unit.FormatBearing(mag), a.PhoneticStyle)...)
+			return words(append(w, "magnetic"))
+		},
+		"spokenETA": func(p Position) string {
+			if !a.AnnounceETA {
+				return ""
+			}
+			if eta, _, ok := closestApproach(&p); ok && eta <= MaxAnnouncedETA {
+				return words(durationToWords(eta))
+			}
+			return ""
+		},
+	}
+}
+
+// alertFields are the values an alert template is executed against.
+type alertFields struct {
+	Position
+	// Time is the position's timestamp formatted for display.
+	Time string
+	// Link is the FlightAware URL for the flight.
+	Link string
+}
+
+// parseTemplates compiles the configured templates, keyed by sink name, using
+// the default template for any sink without one. Each template is also
+// executed against an empty position so that references to fields which don't
+// exist are caught at startup rather than on the first alert.
+func parseTemplates(sources map[string]string, funcs template.FuncMap) (Templates, error) {
+	for sink := range sources {
+		if _, ok := DefaultTemplates[sink]; !ok {
+			return nil, fmt.Errorf("unknown template sink %q", sink)
+		}
+	}
+	templates := make(Templates)
+	for sink, src := range DefaultTemplates {
+		if configured, ok := sources[sink]; ok {
+			src = configured
+		}
+		tmpl, err := compileTemplate(sink, src, funcs)
+		if err != nil {
+			return nil, err
+		}
+		templates[sink] = tmpl
+	}
+	return templates, nil
+}
+
+// compileTemplate parses an alert template and checks it against an empty
+// position.
+func compileTemplate(name, src string, funcs template.FuncMap) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s template: %w", name, err)
+	}
+	fields := alertFields{Position: Position{Timestamp: time.Unix(0, 0)}}
+	if err := tmpl.Execute(io.Discard, fields); err != nil {
+		return nil, fmt.Errorf("%s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// TypeTemplates holds alert templates for particular aircraft types, which
+// override the terminal and speech formats for matching flights. They are
+// keyed by type designator, or by a prefix followed by * to match any type
+// starting with it.
+type TypeTemplates map[string]*template.Template
+
+// parseTypeTemplates compiles the templates for each aircraft type pattern.
+func parseTypeTemplates(sources map[string]string, funcs template.FuncMap) (TypeTemplates, error) {
+	templates := make(TypeTemplates)
+	for pattern, src := range sources {
+		// Config keys may have been lowercased, but type designators are
+		// upper case.
+		pattern = strings.ToUpper(strings.TrimSpace(pattern))
+		tmpl, err := compileTemplate(pattern, src, funcs)
+		if err != nil {
+			return nil, err
+		}
+		templates[pattern] = tmpl
+	}
+	return templates, nil
+}
+
+// match finds the template for an aircraft type. An exact designator wins over
+// a prefix, and a longer prefix over a shorter one.
+func (t TypeTemplates) match(aircraftType string) *template.Template {
+	if aircraftType == "" {
+		return nil
+	}
+	aircraftType = strings.ToUpper(aircraftType)
+	if tmpl, ok := t[aircraftType]; ok {
+		return tmpl
+	}
+	var best *template.Template
+	bestLen := -1
+	for pattern, tmpl := range t {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && strings.HasPrefix(aircraftType, prefix) && len(prefix) > bestLen {
+			best, bestLen = tmpl, len(prefix)
+		}
+	}
+	return best
+}
+
+// readTemplateSource returns the template in the named file, or the value
+// itself as an inline template. A value which looks like a path, because it
+// contains a path separator or ends in .tmpl, must name a file that exists.
+// Otherwise the value is only read as a file if there is one, and a value
+// containing template actions is always inline.
+func readTemplateSource(v string) (string, error) {
+	if strings.Contains(v, "{{") {
+		return v, nil
+	}
+	isPath := strings.ContainsRune(v, '/') || strings.ContainsRune(v, filepath.Separator) || strings.HasSuffix(v, ".tmpl")
+	b, err := os.ReadFile(v)
+	if !isPath && (errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENAMETOOLONG)) {
+		return v, nil
+	} else if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// defaultTemplate returns the compiled default template for a sink, for when
+// the App's Templates weren't set up by parseTemplates.
+func (a *App) defaultTemplate(sink string) *template.Template {
+	a.defaultTemplatesOnce.Do(func() {
+		templates, err := parseTemplates(nil, a.templateFuncs())
+		if err != nil {
+			panic(fmt.Sprintf("invalid default templates: %v", err))
+		}
+		a.defaultTemplates = templates
+	})
+	return a.defaultTemplates[sink]
+}
+
+// renderTemplate renders the position using the sink's template. For the
+// terminal and speech, a template for the flight's aircraft type takes
+// precedence.
+func (a *App) renderTemplate(sink string, pos *Position) (string, error) {
+	tmpl := a.Templates[sink]
+	if sink == TerminalSink || sink == SpeechSink {
+		if typeTmpl := a.TypeTemplates.match(pos.AircraftType); typeTmpl != nil {
+			tmpl = typeTmpl
+		}
+	}
+	if tmpl == nil {
+		tmpl = a.defaultTemplate(sink)
+	}
+	fields := alertFields{
+		Position: *pos,
+		Time:     a.formatTime(pos.Timestamp),
+		Link:     flightAwareLink(pos.FlightID),
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, fields); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// printTemplates writes the templates as a [templates] table which can be
+// pasted into the config file and edited.
+func printTemplates(w io.Writer, templates map[string]string) {
+	sinks := make([]string, 0, len(templates))
+	for sink := range templates {
+		sinks = append(sinks, sink)
+	}
+	sort.Strings(sinks)
+	fmt.Fprintln(w, "[templates]")
+	for _, sink := range sinks {
+		fmt.Fprintf(w, "%s = '''\n%s'''\n", sink, templates[sink])
+	}
+}
+
+func flightAwareLink(flightID string) string {
+	return "https://www.flightaware.com/live/flight/id/" + flightID
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTemplates(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   map[string]string
+		valid bool
+	}{
+		{"none", nil, true},
+		{"terminal", map[string]string{"terminal": "{{.Ident}} {{cardinal .Bearing}}"}, true},
+		{"unknown sink", map[string]string{"pager": "{{.Ident}}"}, false},
+		{"syntax error", map[string]string{"speech": "{{.Ident"}, false},
+		{"unknown field", map[string]string{"webhook": "{{.Tail}}"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseTemplates(test.src, (&App{}).templateFuncs())
+			if (err == nil) != test.valid {
+				t.Errorf("unexpected result: %v", err)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	app := &App{}
+	templates, err := parseTemplates(map[string]string{
+		"speech": "{{callsign .Ident}} {{with .Altitude}}at {{altitude (deref .)}}{{end}}",
+	}, app.templateFuncs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	alt := 2300.0
+	app.Templates = templates
+	pos := &Position{Ident: "UAL1234", Altitude: &alt, Timestamp: time.Unix(0, 0)}
+
+	text, err := app.renderTemplate(SpeechSink, pos)
+	if err != nil {
+		t.Fatalf("expected template to render: %v", err)
+	}
+	if text != "united 12 34 at two thousand three hundred" {
+		t.Errorf("unexpected rendering: %s", text)
+	}
+
+	// The terminal has no template configured, so it uses the default.
+	if text, err := app.renderTemplate(TerminalSink, pos); err != nil || !strings.Contains(text, "UAL1234 from  is 0.0nm to the north at 2300ft") {
+		t.Errorf("expected the default terminal template, got %q: %v", text, err)
+	}
+}
+
+func TestAltitudeTemplates(t *testing.T) {
+	app := &App{AltitudeMode: AltitudeModeRelative, ObserverElevationFt: 300, Units: ImperialUnits}
+	templates, err := parseTemplates(map[string]string{
+		"terminal": "{{with .Altitude}}at {{formatAltitude (deref .)}}, {{displayAltitude (deref .)}}{{end}}",
+	}, app.templateFuncs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	alt := 2300.0
+	app.Templates = templates
+	text, err := app.renderTemplate(TerminalSink, &Position{Altitude: &alt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "at 2300ft, 2000ft above you" {
+		t.Errorf("unexpected rendering: %s", text)
+	}
+}
+
+func TestPrintTemplates(t *testing.T) {
+	var b strings.Builder
+	printTemplates(&b, map[string]string{"webhook": "{{json .}}", "speech": "{{.Ident}}\n"})
+	exp := "[templates]\nspeech = '''\n{{.Ident}}\n'''\nwebhook = '''\n{{json .}}'''\n"
+	if b.String() != exp {
+		t.Errorf("unexpected output: %q", b.String())
+	}
+}
+
+func TestTypeTemplates(t *testing.T) {
+	app := &App{}
+	var err error
+	app.Templates, err = parseTemplates(map[string]string{"speech": "{{callsign .Ident}}"}, app.templateFuncs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.TypeTemplates, err = parseTypeTemplates(map[string]string{
+		"c172": "Cessna overhead",
+		"P5*":  "warbird {{.AircraftType}}",
+		"P51*": "Mustang {{.Ident}}",
+	}, app.templateFuncs())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		aircraftType string
+		sink         string
+		exp          string
+	}{
+		{"C172", SpeechSink, "Cessna overhead"},
+		{"C172", TerminalSink, "Cessna overhead"},
+		{"P51D", SpeechSink, "Mustang N5420V"},
+		{"P55", SpeechSink, "warbird P55"},
+		{"B738", SpeechSink, "november 54 20 victor"},
+		{"B738", TerminalSink, "[00:00:00] N5420V (Boeing 737-800) from  is 0.0nm to the north\n           https://www.flightaware.com/live/flight/id/"},
+		{"", SpeechSink, "november 54 20 victor"},
+	}
+	for _, test := range tests {
+		t.Run(test.aircraftType+" "+test.sink, func(t *testing.T) {
+			pos := &Position{Ident: "N5420V", AircraftType: test.aircraftType, Timestamp: time.Unix(0, 0)}
+			text, err := app.renderTemplate(test.sink, pos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text != test.exp {
+				t.Errorf("expected %q, got %q", test.exp, text)
+			}
+		})
+	}
+
+	if _, err := parseTypeTemplates(map[string]string{"C172": "{{.Tail}}"}, app.templateFuncs()); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestReadTemplateSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhook.tmpl")
+	if err := os.WriteFile(path, []byte(`{"ident":"{{.Ident}}"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if src, err := readTemplateSource(path); err != nil || src != `{"ident":"{{.Ident}}"}` {
+		t.Errorf("expected file contents, got %q, %v", src, err)
+	}
+	inline := `{"ident":"{{.Ident}}","distance":{{printf "%.1f" .Distance}}}`
+	if src, err := readTemplateSource(inline); err != nil || src != inline {
+		t.Errorf("expected inline template, got %q, %v", src, err)
+	}
+	if src, err := readTemplateSource("ident only"); err != nil || src != "ident only" {
+		t.Errorf("expected inline template, got %q, %v", src, err)
+	}
+	// A mistyped path is an error rather than being sent as the body.
+	for _, missing := range []string{filepath.Join(t.TempDir(), "missing"), "webhook.tmpl"} {
+		if src, err := readTemplateSource(missing); err == nil {
+			t.Errorf("expected an error for missing file %s, got %q", missing, src)
+		}
+	}
+}
+
+func TestWebhookContentType(t *testing.T) {
+	app := &App{}
+	if ct := app.webhookContentType([]byte(`{"ident":"UAL1"}`)); ct != "application/json" {
+		t.Errorf("expected JSON content type, got %q", ct)
+	}
+	if ct := app.webhookContentType([]byte("UAL1 is 1.2nm to the north")); ct != "text/plain; charset=utf-8" {
+		t.Errorf("expected plain text content type, got %q", ct)
+	}
+	app.WebhookContentType = "application/x-www-form-urlencoded"
+	if ct := app.webhookContentType([]byte("ident=UAL1")); ct != app.WebhookContentType {
+		t.Errorf("expected configured content type, got %q", ct)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+// trail is a ring buffer of a flight's most recent positions.
+type trail struct {
+	positions []*Position
+	// next is the index at which the next position will be stored.
+	next int
+	full bool
+}
+
+// newTrail makes a trail holding up to n positions, and at least one.
+func newTrail(n int) *trail {
+	return &trail{positions: make([]*Position, max(n, 1))}
+}
+
+// add records a position, replacing the oldest once the trail is full.
+func (t *trail) add(pos *Position) {
+	t.positions[t.next] = pos
+	t.next = (t.next + 1) % len(t.positions)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// points copies the positions in the trail, oldest first.
+func (t *trail) points() []Position {
+	var points []Position
+	if t.full {
+		for _, pos := range t.positions[t.next:] {
+			points = append(points, *pos)
+		}
+	}
+	for _, pos := range t.positions[:t.next] {
+		points = append(points, *pos)
+	}
+	return points
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTrail(t *testing.T) {
+	ids := func(tr *trail) []string {
+		var ids []string
+		for _, pos := range tr.points() {
+			ids = append(ids, pos.Ident)
+		}
+		return ids
+	}
+
+	tr := newTrail(3)
+	if points := tr.points(); len(points) != 0 {
+		t.Errorf("expected an empty trail, got %v", points)
+	}
+	for _, ident := range []string{"A", "B"} {
+		tr.add(&Position{Ident: ident})
+	}
+	if actual := ids(tr); !slices.Equal(actual, []string{"A", "B"}) {
+		t.Errorf("unexpected trail: %v", actual)
+	}
+	for _, ident := range []string{"C", "D", "E"} {
+		tr.add(&Position{Ident: ident})
+	}
+	if actual := ids(tr); !slices.Equal(actual, []string{"C", "D", "E"}) {
+		t.Errorf("unexpected trail: %v", actual)
+	}
+
+	tr = newTrail(0)
+	tr.add(&Position{Ident: "A"})
+	tr.add(&Position{Ident: "B"})
+	if actual := ids(tr); !slices.Equal(actual, []string{"B"}) {
+		t.Errorf("unexpected trail: %v", actual)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTTSRate is the default speaking rate in words per minute.
+const DefaultTTSRate = 200
+
+// SpeechQueueSize is how many announcements may be waiting to be spoken before
+// further ones are dropped.
+const SpeechQueueSize = 16
+
+// A speechJob is an announcement waiting to be spoken.
+type speechJob struct {
+	text   string
+	queued time.Time
+}
+
+var (
+	errNoTTSCommand = errors.New("text-to-speech command not found")
+	errTTSTimeout   = errors.New("text-to-speech command timed out")
+)
+
+// ttsVoicesTimeout bounds how long listing the available voices may take.
+const ttsVoicesTimeout = 5 * time.Second
+
+// defaultTTSCommand picks the text-to-speech command for the platform we are
+// running on: say ships with macOS, and espeak is widely available on Linux.
+func defaultTTSCommand() string {
+	if runtime.GOOS == "darwin" {
+		return "say"
+	}
+	return "espeak"
+}
+
+// ttsArgs builds the arguments with which to run the text-to-speech command to
+// speak the text at the rate in words per minute, in the voice if one is
+// given. Commands we don't recognize are just given the text.
+func ttsArgs(command, text string, rate int, voice string) []string {
+	var rateFlag, voiceFlag string
+	switch filepath.Base(command) {
+	case "say":
+		rateFlag, voiceFlag = "-r", "-v"
+	case "espeak", "espeak-ng":
+		rateFlag, voiceFlag = "-s", "-v"
+	default:
+		return []string{text}
+	}
+	args := []string{rateFlag, strconv.Itoa(rate)}
+	if voice != "" {
+		args = append(args, voiceFlag, voice)
+	}
+	return append(args, text)
+}
+
+// checkTTSVoice makes sure the configured voice is available to the speech
+// command, falling back to the default voice with a warning if it isn't so
+// that a typo doesn't silence every announcement. If the voices can't be
+// listed, the voice is used as given.
+func (a *App) checkTTSVoice() {
+	if a.TTSVoice == "" {
+		return
+	}
+	command, err := exec.LookPath(a.TTSCommand)
+	if err != nil {
+		return
+	}
+	var args []string
+	var parse func(string) []string
+	switch filepath.Base(command) {
+	case "say":
+		args, parse = []string{"-v", "?"}, parseSayVoices
+	case "espeak", "espeak-ng":
+		args, parse = []string{"--voices"}, parseEspeakVoices
+	default:
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ttsVoicesTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, command, args...).Output()
+	if err != nil {
+		slog.Warn("could not list speech voices", "command", command, "error", err)
+		return
+	}
+	if !slices.ContainsFunc(parse(string(out)), func(v string) bool { return strings.EqualFold(v, a.TTSVoice) }) {
+		slog.Warn("speech voice is not available; using the default voice", "voice", a.TTSVoice, "command", command)
+		a.TTSVoice = ""
+	}
+}
+
+// parseSayVoices reads the names of the voices listed by say -v '?', which
+// look like:
+//
+//	Alex                en_US    # Most people recognize me by my voice.
+//	Bad News            en_US    # The light you see at the end of the tunnel...
+func parseSayVoices(out string) []string {
+	var voices []string
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		// The last field is the locale, and names may contain spaces.
+		voices = append(voices, strings.Join(fields[:len(fields)-1], " "))
+	}
+	return voices
+}
+
+// parseEspeakVoices reads the voices listed by espeak --voices, which may be
+// given by language, name, or file:
+//
+//	Pty Language       Age/Gender VoiceName          File                 Other Languages
+//	 5  en-us           --/M      English_(America)  gmw/en-US            (en 3)
+func parseEspeakVoices(out string) []string {
+	var voices []string
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for n := 0; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if n == 0 || len(fields) < 5 {
+			continue
+		}
+		voices = append(voices, fields[1], fields[3], fields[4])
+	}
+	return voices
+}
+
+// startSpeech starts the worker which speaks queued announcements one at a
+// time, so that alerts arriving together don't talk over each other.
+func (a *App) startSpeech() {
+	a.speechMu.Lock()
+	defer a.speechMu.Unlock()
+	a.speech = make(chan speechJob, SpeechQueueSize)
+	a.speechDone = make(chan struct{})
+	go a.runSpeechQueue(a.speech, a.speechDone)
+}
+
+// runSpeechQueue speaks announcements from the queue until it is closed,
+// skipping any which waited longer than TTSMaxAge.
+func (a *App) runSpeechQueue(queue <-chan speechJob, done chan<- struct{}) {
+	defer close(done)
+	for job := range queue {
+		if age := a.wallClock().Sub(job.queued); a.TTSMaxAge > 0 && age > a.TTSMaxAge {
+			slog.Info("dropped stale announcement", "age", age, "text", job.text)
+			continue
+		}
+		a.speakNow(job.text)
+	}
+}
+
+// queueSpeech queues text to be spoken without blocking, returning false if
+// there is no queue to speak it from. Text is dropped if the queue is full or
+// has been stopped.
+func (a *App) queueSpeech(text string) bool {
+	a.speechMu.Lock()
+	defer a.speechMu.Unlock()
+	if a.speechDone == nil {
+		return false
+	}
+	if a.speech == nil {
+		slog.Debug("dropped announcement after shutdown", "text", text)
+		return true
+	}
+	select {
+	case a.speech <- speechJob{text: text, queued: a.wallClock()}:
+	default:
+		slog.Warn("speech queue is full; dropped announcement", "text", text)
+	}
+	return true
+}
+
+// stopSpeech stops accepting announcements and waits for those already queued
+// to be spoken, but no longer than the timeout.
+func (a *App) stopSpeech(timeout time.Duration) {
+	a.speechMu.Lock()
+	if a.speech == nil {
+		a.speechMu.Unlock()
+		return
+	}
+	close(a.speech)
+	a.speech = nil
+	done := a.speechDone
+	a.speechMu.Unlock()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		slog.Warn("gave up waiting for announcements to finish", "timeout", timeout)
+	}
+}
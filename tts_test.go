@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTTSArgs(t *testing.T) {
+	tests := []struct {
+		command string
+		voice   string
+		exp     string
+	}{
+		{"say", "", "-r 200 hello"},
+		{"/usr/bin/say", "", "-r 200 hello"},
+		{"say", "Samantha", "-r 200 -v Samantha hello"},
+		{"espeak", "", "-s 200 hello"},
+		{"espeak-ng", "en-us", "-s 200 -v en-us hello"},
+		{"festival-say", "kal", "hello"},
+	}
+	for _, test := range tests {
+		t.Run(test.command+" "+test.voice, func(t *testing.T) {
+			actual := strings.Join(ttsArgs(test.command, "hello", DefaultTTSRate, test.voice), " ")
+			if actual != test.exp {
+				t.Errorf("unexpected arguments: %s", actual)
+			}
+		})
+	}
+}
+
+func TestParseVoices(t *testing.T) {
+	say := `Alex                en_US    # Most people recognize me by my voice.
+Bad News            en_US    # The light you see at the end of the tunnel is the headlamp of a fast approaching train.
+Samantha            en_US    # Hello, my name is Samantha. I am an American-English voice.
+`
+	if actual := strings.Join(parseSayVoices(say), ","); actual != "Alex,Bad News,Samantha" {
+		t.Errorf("unexpected say voices: %s", actual)
+	}
+
+	espeak := `Pty Language       Age/Gender VoiceName          File                 Other Languages
+ 5  af              --/M      Afrikaans          gmw/af
+ 2  en-us           --/M      English_(America)  gmw/en-US            (en 3)
+`
+	if actual := strings.Join(parseEspeakVoices(espeak), ","); actual != "af,Afrikaans,gmw/af,en-us,English_(America),gmw/en-US" {
+		t.Errorf("unexpected espeak voices: %s", actual)
+	}
+}
+
+// fakeTTS writes a script which logs the start and end of each announcement,
+// returning its path and that of the log.
+func fakeTTS(t *testing.T) (command, log string) {
+	dir := t.TempDir()
+	command, log = filepath.Join(dir, "tts"), filepath.Join(dir, "log")
+	script := "#!/bin/sh\necho \"start $1\" >> " + log + "\nsleep 0.05\necho \"end $1\" >> " + log + "\n"
+	if err := os.WriteFile(command, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return command, log
+}
+
+func readLog(t *testing.T, log string) string {
+	data, err := os.ReadFile(log)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return strings.Join(strings.Fields(string(data)), " ")
+}
+
+func TestSpeechQueue(t *testing.T) {
+	command, log := fakeTTS(t)
+	app := &App{TTSCommand: command, TTSTimeout: time.Second}
+	app.startSpeech()
+	app.speak("one")
+	app.speak("two")
+	app.stopSpeech(5 * time.Second)
+	if actual := readLog(t, log); actual != "start one end one start two end two" {
+		t.Errorf("expected announcements one at a time, got %q", actual)
+	}
+
+	app.speak("three")
+	if actual := readLog(t, log); strings.Contains(actual, "three") {
+		t.Errorf("expected no announcements after stopping, got %q", actual)
+	}
+}
+
+func TestSpeechQueueDropsStale(t *testing.T) {
+	command, log := fakeTTS(t)
+	now := time.Date(2024, 7, 4, 12, 0, 0, 0, time.UTC)
+	app := &App{
+		TTSCommand: command,
+		TTSTimeout: time.Second,
+		TTSMaxAge:  15 * time.Second,
+		clock:      func() time.Time { return now },
+	}
+	queue := make(chan speechJob, 2)
+	queue <- speechJob{text: "stale", queued: now.Add(-30 * time.Second)}
+	queue <- speechJob{text: "fresh", queued: now.Add(-5 * time.Second)}
+	close(queue)
+	app.runSpeechQueue(queue, make(chan struct{}))
+	if actual := readLog(t, log); actual != "start fresh end fresh" {
+		t.Errorf("expected only the fresh announcement, got %q", actual)
+	}
+}
+
+func TestRunSpeechTimeout(t *testing.T) {
+	command := filepath.Join(t.TempDir(), "tts")
+	if err := os.WriteFile(command, []byte("#!/bin/sh\nexec sleep 10\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	app := &App{TTSCommand: command, TTSTimeout: 50 * time.Millisecond}
+	start := time.Now()
+	err := app.runSpeech("hello")
+	if !errors.Is(err, errTTSTimeout) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the command to be killed, but it ran for %s", elapsed)
+	}
+}
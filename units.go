@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"overhead/internal/precision"
+	"overhead/internal/unit"
+)
+
+// Systems of units in which distances, altitudes, and speeds can be shown.
+const (
+	ImperialUnits = unit.Imperial
+	MetricUnits   = unit.Metric
+)
+
+// formatDistance renders a distance given in nautical miles to a precision of
+// decimal places or precision.Adaptive.
+func formatDistance(nm float64, units, places string) string {
+	decimals := precision.Decimals(nm, places)
+	if unit.IsMetric(units) {
+		return fmt.Sprintf("%.*fkm", decimals, nm*unit.KMPerNM)
+	}
+	return fmt.Sprintf("%.*fnm", decimals, nm)
+}
+
+// formatVerticalRate renders a vertical rate given in feet per minute.
+func formatVerticalRate(fpm float64, units string) string {
+	if unit.IsMetric(units) {
+		return fmt.Sprintf("%.1fm/s", fpm*unit.MetersPerFoot/60)
+	}
+	return fmt.Sprintf("%.0ffpm", fpm)
+}
+
+// formatAltitude renders an altitude given in feet.
+func formatAltitude(ft float64, units string) string {
+	if unit.IsMetric(units) {
+		return fmt.Sprintf("%.0fm", ft*unit.MetersPerFoot)
+	}
+	return fmt.Sprintf("%.0fft", ft)
+}
+
+// formatSpeed renders a speed given in knots.
+func formatSpeed(kts float64, units string) string {
+	if unit.IsMetric(units) {
+		return fmt.Sprintf("%.0fkm/h", kts*unit.KPHPerKnot)
+	}
+	return fmt.Sprintf("%.0fkts", kts)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"overhead/internal/precision"
+)
+
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		units    string
+		distance string
+		altitude string
+		speed    string
+		rate     string
+	}{
+		{ImperialUnits, "1.4nm", "1050ft", "141kts", "1200fpm"},
+		{MetricUnits, "2.6km", "320m", "261km/h", "6.1m/s"},
+	}
+	for _, test := range tests {
+		t.Run(test.units, func(t *testing.T) {
+			if actual := formatDistance(1.4, test.units, ""); actual != test.distance {
+				t.Errorf("unexpected distance: %s", actual)
+			}
+			if actual := formatAltitude(1050, test.units); actual != test.altitude {
+				t.Errorf("unexpected altitude: %s", actual)
+			}
+			if actual := formatSpeed(141, test.units); actual != test.speed {
+				t.Errorf("unexpected speed: %s", actual)
+			}
+			if actual := formatVerticalRate(1200, test.units); actual != test.rate {
+				t.Errorf("unexpected vertical rate: %s", actual)
+			}
+		})
+	}
+}
+
+func TestFormatDistancePrecision(t *testing.T) {
+	tests := []struct {
+		nm        float64
+		units     string
+		precision string
+		exp       string
+	}{
+		{0.456, ImperialUnits, "1", "0.5nm"},
+		{0.456, ImperialUnits, "2", "0.46nm"},
+		{12.34, ImperialUnits, "0", "12nm"},
+		{0.456, ImperialUnits, precision.Adaptive, "0.46nm"},
+		{4.56, ImperialUnits, precision.Adaptive, "4.6nm"},
+		{10, ImperialUnits, precision.Adaptive, "10.0nm"},
+		{12.34, ImperialUnits, precision.Adaptive, "12nm"},
+		{12.34, MetricUnits, precision.Adaptive, "23km"},
+	}
+	for _, test := range tests {
+		t.Run(test.precision+" "+test.exp, func(t *testing.T) {
+			if actual := formatDistance(test.nm, test.units, test.precision); actual != test.exp {
+				t.Errorf("unexpected distance: %s", actual)
+			}
+		})
+	}
+}
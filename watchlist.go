@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"overhead/internal/airport"
+)
+
+// Ways the watchlist can combine with the usual interesting-flight checks.
+const (
+	// WatchlistOnly makes the watchlist the sole test of whether a flight is
+	// interesting, regardless of its distance or altitude. Flights are still
+	// only seen within the subscription box, though.
+	WatchlistOnly = "only"
+	// WatchlistAlso requires flights to be on the watchlist in addition to
+	// being within the interesting radius and altitudes.
+	WatchlistAlso = "also"
+)
+
+func validateWatchlistMode(mode string) error {
+	switch mode {
+	case WatchlistOnly, WatchlistAlso:
+		return nil
+	}
+	return fmt.Errorf("unknown watchlist-mode %q", mode)
+}
+
+// onWatchlist reports whether the position's ident or registration matches an
+// entry in the watchlist.
+func (a *App) onWatchlist(pos *Position) bool {
+	return matchesIdent(a.Watchlist, pos)
+}
+
+// onBlocklist reports whether the position's ident or registration matches an
+// entry in the blocklist.
+func (a *App) onBlocklist(pos *Position) bool {
+	return matchesIdent(a.Blocklist, pos)
+}
+
+// matchesIdent reports whether the position's ident or registration matches
+// one of the entries. Matching is case-insensitive, and an entry ending in *
+// matches any ident beginning with the rest of it, e.g. UAL* for all United
+// flights.
+func matchesIdent(entries []string, pos *Position) bool {
+	for _, entry := range entries {
+		if prefix, ok := strings.CutSuffix(entry, "*"); ok {
+			if len(pos.Ident) >= len(prefix) && strings.EqualFold(pos.Ident[:len(prefix)], prefix) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(pos.Ident, entry) || (pos.Reg != "" && strings.EqualFold(pos.Reg, entry)) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRouteWatchlist checks that each route is an origin and destination
+// separated by a dash.
+func validateRouteWatchlist(routes []string) error {
+	for _, route := range routes {
+		orig, dest, ok := strings.Cut(route, "-")
+		if !ok || strings.TrimSpace(orig) == "" || strings.TrimSpace(dest) == "" {
+			return fmt.Errorf("route-watchlist entry %q must be of the form ORIG-DEST", route)
+		}
+	}
+	return nil
+}
+
+// onRouteWatchlist reports whether the position's origin and destination
+// match an entry in the route watchlist, e.g. KBOS-KJFK. Either side may be *
+// to match any airport, or end in * to match codes beginning with the rest of
+// it.
+func (a *App) onRouteWatchlist(pos *Position) bool {
+	orig, dest := airport.Normalize(pos.Origin), airport.Normalize(pos.Destination)
+	for _, route := range a.RouteWatchlist {
+		origPattern, destPattern, _ := strings.Cut(route, "-")
+		if matchAirport(origPattern, orig) && matchAirport(destPattern, dest) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAirport reports whether a normalized airport code matches a route
+// watchlist pattern.
+func matchAirport(pattern, code string) bool {
+	pattern = strings.ToUpper(strings.TrimSpace(pattern))
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return prefix == "" || (code != "" && strings.HasPrefix(code, prefix))
+	}
+	return code != "" && code == pattern
+}
@@ -0,0 +1,149 @@
+package main
+
+import "testing"
+
+func TestIsInterestingWatchlist(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name  string
+		mode  string
+		ident string
+		reg   string
+		dist  float64
+		exp   bool
+	}{
+		{"exact ident", WatchlistAlso, "N12345", "N12345", 5, true},
+		{"exact ident any case", WatchlistAlso, "n12345", "", 5, true},
+		{"registration", WatchlistAlso, "SKW4321", "N54321", 5, true},
+		{"wildcard", WatchlistAlso, "UAL123", "N33333", 5, true},
+		{"wildcard any case", WatchlistAlso, "ual9", "", 5, true},
+		{"wildcard prefix only", WatchlistAlso, "XUAL1", "", 5, false},
+		{"non-match", WatchlistAlso, "DAL456", "N99999", 5, false},
+		{"also outside radius", WatchlistAlso, "UAL123", "", 50, false},
+		{"only outside radius", WatchlistOnly, "UAL123", "", 50, true},
+		{"only non-match", WatchlistOnly, "DAL456", "", 5, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &App{
+				InterestingRadiusNM:  10,
+				InterestingCeilingFt: 15000,
+				Watchlist:            []string{"N12345", "n54321", "ual*"},
+				WatchlistMode:        test.mode,
+			}
+			pos := &Position{
+				Ident:    test.ident,
+				Reg:      test.reg,
+				Distance: test.dist,
+				Altitude: f(5000),
+			}
+			if actual := app.isInteresting(pos); actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestIsInterestingRouteWatchlist(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name string
+		orig string
+		dest string
+		dist float64
+		exp  bool
+	}{
+		{"origin wildcard", "KBOS", "KORD", 50, true},
+		{"destination wildcard", "KSFO", "KJFK", 50, true},
+		{"lower case codes", "kbos", "kdca", 50, true},
+		{"exact route", "KLGA", "KDCA", 50, true},
+		{"reversed route", "KDCA", "KLGA", 50, false},
+		{"no route", "", "", 50, false},
+		{"no route in radius", "", "", 5, true},
+		{"other route", "KSFO", "KLAX", 50, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &App{
+				InterestingRadiusNM:  10,
+				InterestingCeilingFt: 15000,
+				RouteWatchlist:       []string{"KBOS-*", "*-KJFK", "klga-kdca"},
+			}
+			pos := &Position{
+				Origin:      test.orig,
+				Destination: test.dest,
+				Distance:    test.dist,
+				Altitude:    f(35000),
+			}
+			if test.dist < app.InterestingRadiusNM {
+				pos.Altitude = f(5000)
+			}
+			if actual := app.isInteresting(pos); actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+		})
+	}
+}
+
+func TestValidateRouteWatchlist(t *testing.T) {
+	tests := []struct {
+		route string
+		valid bool
+	}{
+		{"KBOS-KJFK", true},
+		{"KBOS-*", true},
+		{"KBOS", false},
+		{"-KJFK", false},
+		{"KBOS- ", false},
+	}
+	for _, test := range tests {
+		t.Run(test.route, func(t *testing.T) {
+			err := validateRouteWatchlist([]string{test.route})
+			if (err == nil) != test.valid {
+				t.Errorf("unexpected validation result: %v", err)
+			}
+		})
+	}
+}
+
+func TestIsInterestingBlocklist(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name      string
+		watchlist []string
+		routes    []string
+		ident     string
+		reg       string
+		exp       bool
+	}{
+		{"blocked ident", nil, nil, "N54321", "", false},
+		{"blocked registration", nil, nil, "SCH12", "N12345", false},
+		{"blocked any case", nil, nil, "n54329", "", false},
+		{"not blocked", nil, nil, "UAL123", "N99999", true},
+		{"blocked despite watchlist", []string{"N5*"}, nil, "N54321", "", false},
+		{"watched and not blocked", []string{"N5*"}, nil, "N55555", "", true},
+		{"blocked despite route watchlist", nil, []string{"KBED-*"}, "N54321", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &App{
+				InterestingRadiusNM:  10,
+				InterestingCeilingFt: 15000,
+				Watchlist:            test.watchlist,
+				WatchlistMode:        WatchlistAlso,
+				RouteWatchlist:       test.routes,
+				Blocklist:            []string{"N5432*", "n12345"},
+			}
+			pos := &Position{
+				Ident:    test.ident,
+				Reg:      test.reg,
+				Origin:   "KBED",
+				Distance: 5,
+				Altitude: f(1500),
+			}
+			if actual := app.isInteresting(pos); actual != test.exp {
+				t.Errorf("expected %t, got %t", test.exp, actual)
+			}
+		})
+	}
+}
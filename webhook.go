@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+// WebhookQueueSize is how many webhooks may be waiting to be sent before
+// further ones are dropped.
+const WebhookQueueSize = 64
+
+// WebhookCompressMinSize is the smallest webhook body worth compressing with
+// WebhookCompress, below which gzip's overhead outweighs the savings.
+const WebhookCompressMinSize = 256
+
+// A webhookJob is a webhook body waiting to be sent to a URL.
+type webhookJob struct {
+	flightID    string
+	url         string
+	contentType string
+	body        []byte
+	// compress is set if the body may be gzipped, which only the generic
+	// webhook supports.
+	compress bool
+}
+
+// A webhookKey identifies the webhooks for a flight sent to one URL, which are
+// sent at most once per WebhookMinInterval.
+type webhookKey struct {
+	url      string
+	flightID string
+}
+
+// sendsWebhooks reports whether any alerts are posted to webhooks, and so
+// whether the worker needs to be started.
+func (a *App) sendsWebhooks() bool {
+	return a.WebhookURL != "" || a.DiscordWebhookURL != "" || a.SlackWebhookURL != ""
+}
+
+// startWebhooks starts the worker which sends queued webhooks one at a time,
+// so that a slow endpoint holds up later webhooks rather than accumulating
+// concurrent requests.
+func (a *App) startWebhooks() {
+	a.webhookQueueMu.Lock()
+	defer a.webhookQueueMu.Unlock()
+	a.webhooks = make(chan webhookJob, WebhookQueueSize)
+	a.webhooksDone = make(chan struct{})
+	go a.runWebhooks(a.webhooks, a.webhooksDone)
+}
+
+// runWebhooks sends webhooks from the queue until it is closed.
+func (a *App) runWebhooks(queue <-chan webhookJob, done chan<- struct{}) {
+	defer close(done)
+	for job := range queue {
+		a.sendWebhook(job)
+	}
+}
+
+// stopWebhooks stops accepting webhooks and waits for those already queued to
+// be sent, but no longer than the timeout.
+func (a *App) stopWebhooks(timeout time.Duration) {
+	a.webhookQueueMu.Lock()
+	if a.webhooks == nil {
+		a.webhookQueueMu.Unlock()
+		return
+	}
+	close(a.webhooks)
+	a.webhooks = nil
+	done := a.webhooksDone
+	a.webhookQueueMu.Unlock()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		slog.Warn("gave up waiting for webhooks to be sent", "timeout", timeout)
+	}
+}
+
+// queueWebhook queues a body to be sent to the webhook URL.
+func (a *App) queueWebhook(flightID string, body []byte) {
+	a.queueJob(webhookJob{
+		flightID:    flightID,
+		url:         a.WebhookURL,
+		contentType: a.webhookContentType(body),
+		body:        body,
+		compress:    a.WebhookCompress,
+	})
+}
+
+// queueMessage queues a message to be posted as JSON to a chat service's
+// incoming webhook, such as Discord's or Slack's.
+func (a *App) queueMessage(service, url, flightID string, msg any) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("could not marshal message", "service", service, "flight_id", flightID, "error", err)
+		return
+	}
+	a.queueJob(webhookJob{
+		flightID:    flightID,
+		url:         url,
+		contentType: "application/json",
+		body:        body,
+	})
+}
+
+// queueJob queues a job without blocking. If the flight already had a job for
+// the same URL queued within WebhookMinInterval, or the queue is full or has
+// been stopped, the job is dropped. An empty flightID is never coalesced.
+func (a *App) queueJob(job webhookJob) {
+	a.webhookQueueMu.Lock()
+	defer a.webhookQueueMu.Unlock()
+	if a.webhooks == nil {
+		return
+	}
+	key := webhookKey{url: job.url, flightID: job.flightID}
+	now := time.Now()
+	reserved := job.flightID != "" && a.WebhookMinInterval > 0
+	if reserved && !a.reserveWebhook(key, now) {
+		slog.Debug("coalesced webhook", "flight_id", job.flightID)
+		return
+	}
+	select {
+	case a.webhooks <- job:
+	default:
+		slog.Warn("webhook queue is full; dropped webhook", "flight_id", job.flightID)
+		webhooksDropped.Inc()
+		// The flight's next webhook shouldn't be coalesced with one which
+		// was never sent.
+		if reserved {
+			a.releaseWebhook(key, now)
+		}
+	}
+}
+
+// reserveWebhook records that a webhook is being sent for the key at now,
+// unless one was already sent within WebhookMinInterval.
+func (a *App) reserveWebhook(key webhookKey, now time.Time) bool {
+	a.webhookMu.Lock()
+	defer a.webhookMu.Unlock()
+	if a.lastWebhook == nil {
+		a.lastWebhook = make(map[webhookKey]time.Time)
+	}
+	if last, ok := a.lastWebhook[key]; ok && now.Sub(last) < a.WebhookMinInterval {
+		return false
+	}
+	for k, last := range a.lastWebhook {
+		if now.Sub(last) >= a.WebhookMinInterval {
+			delete(a.lastWebhook, k)
+		}
+	}
+	a.lastWebhook[key] = now
+	return true
+}
+
+// releaseWebhook undoes the reservation made by reserveWebhook at the given
+// time, if it hasn't since been replaced.
+func (a *App) releaseWebhook(key webhookKey, at time.Time) {
+	a.webhookMu.Lock()
+	defer a.webhookMu.Unlock()
+	if last, ok := a.lastWebhook[key]; ok && last.Equal(at) {
+		delete(a.lastWebhook, key)
+	}
+}
+
+// encodeWebhook compresses the job's body if it may be compressed and is
+// large enough to be worth it, returning the content encoding used, if any.
+func encodeWebhook(job webhookJob) ([]byte, string, error) {
+	if !job.compress || len(job.body) < WebhookCompressMinSize {
+		return job.body, "", nil
+	}
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(job.body); err != nil {
+		return nil, "", err
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return b.Bytes(), "gzip", nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueueWebhook(t *testing.T) {
+	// There is no worker, so there is nothing for stopWebhooks to wait for.
+	done := make(chan struct{})
+	close(done)
+	app := &App{
+		WebhookMinInterval: time.Minute,
+		webhooks:           make(chan webhookJob, 2),
+		webhooksDone:       done,
+	}
+	app.queueWebhook("A", []byte("1"))
+	app.queueWebhook("A", []byte("2"))
+	app.queueWebhook("", []byte("summary"))
+	app.queueWebhook("B", []byte("3"))
+
+	if len(app.webhooks) != 2 {
+		t.Fatalf("expected 2 queued webhooks, got %d", len(app.webhooks))
+	}
+	for _, exp := range []string{"1", "summary"} {
+		if job := <-app.webhooks; string(job.body) != exp {
+			t.Errorf("expected %q, got %q", exp, job.body)
+		}
+	}
+
+	now := time.Now()
+	key := webhookKey{url: "https://example.com/hook", flightID: "C"}
+	if !app.reserveWebhook(key, now) {
+		t.Error("expected first webhook for a flight to be sent")
+	}
+	if app.reserveWebhook(key, now.Add(30*time.Second)) {
+		t.Error("expected webhook within the interval to be coalesced")
+	}
+	if !app.reserveWebhook(webhookKey{url: "https://example.com/other", flightID: "C"}, now.Add(30*time.Second)) {
+		t.Error("expected webhook for the flight to another URL to be sent")
+	}
+	if !app.reserveWebhook(key, now.Add(time.Minute)) {
+		t.Error("expected webhook after the interval to be sent")
+	}
+
+	// Once stopped, webhooks are dropped rather than sent on a closed queue.
+	app.stopWebhooks(time.Second)
+	app.queueWebhook("D", []byte("4"))
+	if app.webhooks != nil {
+		t.Error("expected the queue to be gone once stopped")
+	}
+}
+
+func TestQueueWebhookFull(t *testing.T) {
+	app := &App{
+		WebhookMinInterval: time.Minute,
+		webhooks:           make(chan webhookJob, 1),
+	}
+	app.queueWebhook("", []byte("summary"))
+	app.queueWebhook("A", []byte("1"))
+	if len(app.webhooks) != 1 {
+		t.Fatalf("expected 1 queued webhook, got %d", len(app.webhooks))
+	}
+	<-app.webhooks
+
+	// The dropped webhook doesn't count against the flight's interval.
+	app.queueWebhook("A", []byte("2"))
+	if len(app.webhooks) != 1 {
+		t.Fatalf("expected the next webhook for A to be queued, got %d", len(app.webhooks))
+	}
+	if job := <-app.webhooks; string(job.body) != "2" {
+		t.Errorf("expected %q, got %q", "2", job.body)
+	}
+}
+
+func TestSendWebhookRetries(t *testing.T) {
+	defer func(d time.Duration) { webhookBackoff = d }(webhookBackoff)
+	webhookBackoff = time.Millisecond
+
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		attempts int
+	}{
+		{"success", []int{200}, 3, 1},
+		{"retried 5xx", []int{503, 502, 200}, 3, 3},
+		{"4xx not retried", []int{400, 200}, 3, 1},
+		{"retries exhausted", []int{500, 500, 500, 500, 500}, 3, 4},
+		{"no retries", []int{500, 200}, 0, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.statuses[attempts])
+				attempts++
+			}))
+			defer srv.Close()
+
+			app := &App{WebhookURL: srv.URL, WebhookRetries: test.retries}
+			app.sendWebhook(webhookJob{url: srv.URL, body: []byte("{}")})
+			if attempts != test.attempts {
+				t.Errorf("expected %d attempts, got %d", test.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestWebhookCompress(t *testing.T) {
+	defer func(d time.Duration) { webhookBackoff = d }(webhookBackoff)
+	webhookBackoff = time.Millisecond
+
+	large := `{"Ident":"` + strings.Repeat("A", WebhookCompressMinSize) + `"}`
+	tests := []struct {
+		name     string
+		compress bool
+		body     string
+		encoding string
+	}{
+		{"off", false, large, ""},
+		{"large", true, large, "gzip"},
+		{"small", true, "{}", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var received []string
+			var attempts int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if r.Header.Get("content-type") != "application/json" {
+					t.Errorf("unexpected content type %q", r.Header.Get("content-type"))
+				}
+				var body io.Reader = r.Body
+				if r.Header.Get("content-encoding") != test.encoding {
+					t.Errorf("unexpected content encoding %q", r.Header.Get("content-encoding"))
+				} else if test.encoding == "gzip" {
+					gz, err := gzip.NewReader(r.Body)
+					if err != nil {
+						t.Fatal(err)
+					}
+					body = gz
+				}
+				b, _ := io.ReadAll(body)
+				received = append(received, string(b))
+				// Fail the first attempt, so that the retry is checked too.
+				if attempts == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer srv.Close()
+
+			app := &App{WebhookURL: srv.URL, WebhookRetries: 1, WebhookCompress: test.compress}
+			app.startWebhooks()
+			app.queueWebhook("", []byte(test.body))
+			app.stopWebhooks(ShutdownTimeout)
+			if len(received) != 2 || received[0] != test.body || received[1] != test.body {
+				t.Errorf("unexpected bodies received: %v", received)
+			}
+		})
+	}
+}
+
+func TestNotifyOnAppear(t *testing.T) {
+	app := &App{
+		Latitude:             42.0,
+		Longitude:            -71.0,
+		InterestingRadiusNM:  10,
+		InterestingCeilingFt: 15000,
+		AlertRadiusNM:        3,
+		NotifyOnAppear:       true,
+		WebhookURL:           "http://localhost/hook",
+		webhooks:             make(chan webhookJob, 4),
+	}
+	home := app.myLocation()
+	app.handlePosition(testPosition("A", moveNM(home, 0, 8), 1000))
+	app.handlePosition(testPosition("A", moveNM(home, 0, 6), 1010))
+	app.handlePosition(testPosition("B", moveNM(home, 90, 8), 1020))
+
+	if len(app.webhooks) != 2 {
+		t.Fatalf("expected a webhook for each new flight, got %d", len(app.webhooks))
+	}
+	for _, id := range []string{"A", "B"} {
+		job := <-app.webhooks
+		if !strings.Contains(string(job.body), `"Event":"appeared"`) || !strings.Contains(string(job.body), `"FlightID":"`+id+`"`) {
+			t.Errorf("unexpected appearance payload: %s", job.body)
+		}
+	}
+
+	body, err := app.webhookBody(&Position{FlightID: "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"Event":"alert"`) {
+		t.Errorf("expected an alert event: %s", body)
+	}
+}
+
+func TestRunSendsQueuedWebhooks(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel bool
+	}{
+		// The replay ends by itself, so Run returns without being canceled.
+		{"replay ended", false},
+		// We are interrupted right as the alert is raised.
+		{"canceled", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var received []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				defer mu.Unlock()
+				received = append(received, string(b))
+			}))
+			defer srv.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			app := &App{
+				Latitude:             42.0,
+				Longitude:            -71.0,
+				InterestingRadiusNM:  10,
+				InterestingCeilingFt: 15000,
+				AlertRadiusNM:        3,
+				WebhookURL:           srv.URL,
+				SummaryOutput:        io.Discard,
+			}
+			if test.cancel {
+				app.OnAlert = func(pos Position) { cancel() }
+			}
+			home := app.myLocation()
+			var lines []string
+			for _, msg := range []any{
+				testPosition("A", moveNM(home, 0, 2), 1000),
+				testPosition("A", moveNM(home, 0, 1.5), 1010),
+			} {
+				b, err := json.Marshal(msg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				lines = append(lines, string(b))
+			}
+			app.ReplayFile = writeReplayFile(t, lines...)
+
+			start := time.Now()
+			if err := app.Run(ctx); err != nil {
+				t.Errorf("expected a clean shutdown, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed >= ShutdownTimeout {
+				t.Errorf("took %s to shut down", elapsed)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(received) != 1 || !strings.Contains(received[0], `"FlightID":"A"`) {
+				t.Errorf("expected the alert's webhook to be sent before returning, got %v", received)
+			}
+		})
+	}
+}